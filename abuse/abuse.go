@@ -0,0 +1,163 @@
+package abuse
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/errdetails"
+	"github.com/LewisJAllan/greeter/ipfilter"
+)
+
+// errorDomain identifies the errors of this package in their ErrorInfo details.
+const errorDomain = "abuse.greeter"
+
+var (
+	blocks = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "greeter",
+		Subsystem: "abuse",
+		Name:      "blocks_total",
+		Help:      "Peers blocked for abusive behaviour.",
+	})
+
+	rejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "greeter",
+		Subsystem: "abuse",
+		Name:      "rejected_total",
+		Help:      "Calls rejected from blocked peers.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(blocks, rejected)
+}
+
+// clientFaults are the codes of the failures a client causes, failures of the server never count against it.
+var clientFaults = []codes.Code{
+	codes.InvalidArgument,
+	codes.NotFound,
+	codes.FailedPrecondition,
+	codes.OutOfRange,
+	codes.PermissionDenied,
+	codes.Unauthenticated,
+}
+
+type options struct {
+	window      time.Duration
+	thresholds  Thresholds
+	baseBlock   time.Duration
+	maxBlock    time.Duration
+	forgetAfter time.Duration
+	methods     []string
+}
+
+type Option func(o *options)
+
+// WithWindow sets how far back the behaviour of a peer is looked at.
+func WithWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.window = d
+	}
+}
+
+func WithThresholds(t Thresholds) Option {
+	return func(o *options) {
+		o.thresholds = t
+	}
+}
+
+// WithBlockDurations sets the first block of a peer, and the longest it escalates to as the peer offends again.
+func WithBlockDurations(base, max time.Duration) Option {
+	return func(o *options) {
+		o.baseBlock, o.maxBlock = base, max
+	}
+}
+
+// WithMethods restricts the interceptor to the given full method names, calls to other methods are passed through.
+func WithMethods(methods ...string) Option {
+	return func(o *options) {
+		o.methods = append(o.methods, methods...)
+	}
+}
+
+// Detector provides an interceptor tracking the calls of every peer and refusing those of blocked peers with
+// PermissionDenied, and Guard doing the same for the listeners without interceptors.  Peers are identified by the client address found by the ip filter when it is installed before,
+// otherwise by their address.
+type Detector struct {
+	detector *detector
+	opts     options
+	now      func() time.Time
+}
+
+func NewDetector(opts ...Option) *Detector {
+	o := options{
+		window: time.Minute,
+		thresholds: Thresholds{
+			Requests:             300,
+			Repeats:              30,
+			ErrorRate:            0.5,
+			MinCallsForErrorRate: 20,
+		},
+		baseBlock:   time.Minute,
+		maxBlock:    time.Hour * 24,
+		forgetAfter: time.Hour * 24,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Detector{detector: newDetector(o), opts: o, now: time.Now}
+}
+
+// nameRequest is implemented by the requests of SayHello and any other request with a name.
+type nameRequest interface {
+	GetName() string
+}
+
+func (d *Detector) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if len(d.opts.methods) > 0 && !slices.Contains(d.opts.methods, info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	p := ipfilter.PeerIP(ctx)
+	if b, ok := d.detector.blocked(p, d.now()); ok {
+		rejected.Inc()
+		return nil, blockedError(b, d.now())
+	}
+
+	res, err := handler(ctx, req)
+
+	var name string
+	if r, ok := req.(nameRequest); ok {
+		name = r.GetName()
+	}
+	failed := slices.Contains(clientFaults, status.Code(err))
+
+	if b, ok := d.detector.record(p, name, failed, d.now()); ok {
+		d.logBlock(ctx, b)
+	}
+	return res, err
+}
+
+func (d *Detector) logBlock(ctx context.Context, b Block) {
+	blocks.Inc()
+	zaphelper.Warn(ctx, "peer blocked for abuse",
+		zap.String("peer", b.Peer),
+		zap.String("reason", b.Reason),
+		zap.Int("offences", b.Offences),
+		zap.Time("until", b.Until))
+}
+
+func blockedError(b Block, now time.Time) error {
+	return errdetails.Error(codes.PermissionDenied, fmt.Sprintf("temporarily blocked: %s", b.Reason),
+		errdetails.ErrorInfo("ABUSE_BLOCKED", errorDomain, map[string]string{"until": b.Until.UTC().Format(time.RFC3339)}),
+		errdetails.RetryInfo(b.Until.Sub(now)),
+	)
+}
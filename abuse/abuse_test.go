@@ -0,0 +1,242 @@
+package abuse
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func testOptions() options {
+	return options{
+		window:      time.Minute,
+		thresholds:  Thresholds{Requests: 10, Repeats: 4, ErrorRate: 0.5, MinCallsForErrorRate: 4},
+		baseBlock:   time.Minute,
+		maxBlock:    time.Hour,
+		forgetAfter: time.Hour * 24,
+	}
+}
+
+func TestDetectorBlockDuration(t *testing.T) {
+	d := newDetector(testOptions())
+
+	tests := []struct {
+		offences int
+		want     time.Duration
+	}{
+		{offences: 1, want: time.Minute},
+		{offences: 2, want: time.Minute * 4},
+		{offences: 3, want: time.Minute * 16},
+		{offences: 4, want: time.Hour},
+		{offences: 40, want: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.offences), func(t *testing.T) {
+			if got := d.blockDuration(tt.offences); got != tt.want {
+				t.Errorf("blockDuration(%d) = %v, want %v", tt.offences, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectorRecord(t *testing.T) {
+	type call struct {
+		name   string
+		failed bool
+	}
+	repeat := func(n int, c call) []call {
+		out := make([]call, n)
+		for i := range out {
+			out[i] = c
+		}
+		return out
+	}
+	distinct := func(n int) []call {
+		out := make([]call, n)
+		for i := range out {
+			out[i] = call{name: fmt.Sprint("name", i)}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		// calls are a second apart
+		calls []call
+		// wantBlockedAt is the index of the call earning a block, -1 when none does
+		wantBlockedAt int
+	}{
+		{name: "few calls", calls: distinct(10), wantBlockedAt: -1},
+		{name: "too many calls", calls: distinct(11), wantBlockedAt: 10},
+		{name: "repeated name", calls: repeat(5, call{name: "spam"}), wantBlockedAt: 4},
+		{name: "repeated name ignoring case and spaces", calls: []call{{name: "Spam"}, {name: "spam "}, {name: " SPAM"}, {name: "spam"}, {name: "sPaM"}}, wantBlockedAt: 4},
+		{name: "failing", calls: []call{{name: "a", failed: true}, {name: "b", failed: true}, {name: "c"}, {name: "d", failed: true}}, wantBlockedAt: 3},
+		{name: "failing half", calls: []call{{name: "a", failed: true}, {name: "b", failed: true}, {name: "c"}, {name: "d"}}, wantBlockedAt: -1},
+		{name: "failing below minimum calls", calls: repeat(3, call{failed: true}), wantBlockedAt: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDetector(testOptions())
+			now := time.Unix(1_700_000_000, 0)
+
+			blockedAt := -1
+			for i, c := range tt.calls {
+				if _, ok := d.record("192.0.2.1", c.name, c.failed, now.Add(time.Second*time.Duration(i))); ok && blockedAt < 0 {
+					blockedAt = i
+				}
+			}
+			if blockedAt != tt.wantBlockedAt {
+				t.Errorf("record() blocked at call %d, want %d", blockedAt, tt.wantBlockedAt)
+			}
+		})
+	}
+}
+
+func TestDetectorWindow(t *testing.T) {
+	d := newDetector(testOptions())
+	now := time.Unix(1_700_000_000, 0)
+
+	// calls falling out of the window no longer count
+	for i := range 8 {
+		if _, ok := d.record("192.0.2.1", "spam", false, now.Add(time.Second*20*time.Duration(i))); ok {
+			t.Fatalf("record() blocked call %d spread over more than the window", i)
+		}
+	}
+}
+
+func TestDetectorEscalation(t *testing.T) {
+	o := testOptions()
+	o.thresholds = Thresholds{Requests: 1}
+	const p = "192.0.2.1"
+
+	offend := func(d *detector, at time.Time) Block {
+		t.Helper()
+		d.record(p, "a", false, at)
+		b, ok := d.record(p, "b", false, at)
+		if !ok {
+			t.Fatalf("record() did not block at %v", at)
+		}
+		return b
+	}
+
+	t.Run("escalates", func(t *testing.T) {
+		d := newDetector(o)
+		now := time.Unix(1_700_000_000, 0)
+
+		var got []time.Duration
+		for range 4 {
+			b := offend(d, now)
+			got = append(got, b.Until.Sub(b.Since))
+			now = b.Until
+		}
+		want := []time.Duration{time.Minute, time.Minute * 4, time.Minute * 16, time.Hour}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("blocks = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("blocked calls do not count", func(t *testing.T) {
+		d := newDetector(o)
+		now := time.Unix(1_700_000_000, 0)
+		b := offend(d, now)
+
+		for range 5 {
+			if _, ok := d.record(p, "c", false, now.Add(time.Second)); ok {
+				t.Fatal("record() blocked a peer already blocked")
+			}
+		}
+		if got, ok := d.blocked(p, now.Add(time.Second)); !ok || got.Offences != 1 {
+			t.Errorf("blocked() = %+v, %v, want the first block", got, ok)
+		}
+		if _, ok := d.blocked(p, b.Until); ok {
+			t.Error("blocked() after the block ended = true")
+		}
+	})
+
+	t.Run("offences forgotten", func(t *testing.T) {
+		d := newDetector(o)
+		now := time.Unix(1_700_000_000, 0)
+		offend(d, now)
+
+		b := offend(d, now.Add(o.forgetAfter+time.Hour))
+		if b.Offences != 1 {
+			t.Errorf("offences after forgetting = %d, want 1", b.Offences)
+		}
+	})
+
+	t.Run("unblocked", func(t *testing.T) {
+		d := newDetector(o)
+		now := time.Unix(1_700_000_000, 0)
+		offend(d, now)
+
+		if _, ok := d.unblock(p, false, now); !ok {
+			t.Fatal("unblock() = false, want true")
+		}
+		if _, ok := d.blocked(p, now); ok {
+			t.Error("blocked() after unblock = true")
+		}
+		if _, ok := d.unblock(p, false, now); ok {
+			t.Error("unblock() of a peer not blocked = true")
+		}
+		if b := offend(d, now); b.Offences != 2 {
+			t.Errorf("offences after unblock = %d, want 2", b.Offences)
+		}
+	})
+
+	t.Run("forgiven", func(t *testing.T) {
+		d := newDetector(o)
+		now := time.Unix(1_700_000_000, 0)
+		offend(d, now)
+
+		if _, ok := d.unblock(p, true, now); !ok {
+			t.Fatal("unblock() = false, want true")
+		}
+		if b := offend(d, now); b.Offences != 1 {
+			t.Errorf("offences after forgiving = %d, want 1", b.Offences)
+		}
+	})
+}
+
+type respondFunc func(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+
+func (f respondFunc) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	return f(ctx, request)
+}
+
+func TestDetectorGuard(t *testing.T) {
+	d := NewDetector(WithThresholds(Thresholds{Repeats: 2}))
+	now := time.Unix(1_700_000_000, 0)
+	d.now = func() time.Time { return now }
+
+	calls := 0
+	g := d.Guard(respondFunc(func(context.Context, service.RespondRequest) (service.RespondResponse, error) {
+		calls++
+		return service.RespondResponse{ResponseMessage: "Hello"}, nil
+	}))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 4242}})
+	other := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 4242}})
+	request := service.RespondRequest{OriginalMessage: "spam"}
+
+	for i := range 3 {
+		if _, err := g.Respond(ctx, request); err != nil {
+			t.Fatalf("Respond() call %d error = %v", i, err)
+		}
+	}
+	if _, err := g.Respond(ctx, request); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Respond() when blocked code = %v, want %v", status.Code(err), codes.PermissionDenied)
+	}
+	if calls != 3 {
+		t.Errorf("Respond() called the service %d times, want 3", calls)
+	}
+	if _, err := g.Respond(other, request); err != nil {
+		t.Errorf("Respond() from another peer error = %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package abuse
+
+import (
+	"context"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/admin"
+)
+
+const AdminServiceName = "greeter.abuse.v1.AbuseAdmin"
+
+const (
+	ListBlocksFullMethodName = "/" + AdminServiceName + "/ListBlocks"
+	UnblockFullMethodName    = "/" + AdminServiceName + "/Unblock"
+)
+
+type ListBlocksResponse struct {
+	Blocks []Block `json:"blocks"`
+}
+
+type UnblockRequest struct {
+	Peer string `json:"peer"`
+	// Forgive also forgets the offences of the peer, so that it is not blocked for longer when it offends again.
+	Forgive bool `json:"forgive,omitempty"`
+}
+
+type Empty struct{}
+
+// Admin is a grpc Registerer for the AbuseAdmin service, which lists and lifts the blocks of a Detector.
+type Admin struct {
+	detector *Detector
+}
+
+func NewAdmin(detector *Detector) *Admin {
+	return &Admin{detector: detector}
+}
+
+func (a *Admin) Register(s *grpc.Server) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: AdminServiceName,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			admin.UnaryMethod(AdminServiceName, "ListBlocks", a.listBlocks),
+			admin.UnaryMethod(AdminServiceName, "Unblock", a.unblock),
+		},
+	}, a)
+}
+
+func (a *Admin) listBlocks(context.Context, *Empty) (*ListBlocksResponse, error) {
+	return &ListBlocksResponse{Blocks: a.detector.detector.blocks(a.detector.now())}, nil
+}
+
+func (a *Admin) unblock(ctx context.Context, req *UnblockRequest) (*Block, error) {
+	b, ok := a.detector.detector.unblock(req.Peer, req.Forgive, a.detector.now())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "peer %q is not blocked", req.Peer)
+	}
+	zaphelper.Info(ctx, "peer unblocked",
+		zap.String("peer", b.Peer),
+		zap.Bool("forgiven", req.Forgive),
+		zap.Time("was_until", b.Until))
+	return &b, nil
+}
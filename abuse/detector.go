@@ -0,0 +1,219 @@
+// Package abuse detects peers abusing the greeter, such as scripts sending junk names, and blocks them temporarily,
+// for longer each time they offend again.
+package abuse
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Thresholds are how much of each behaviour a peer may show within the window before it is blocked.
+type Thresholds struct {
+	// Requests is the number of calls allowed.
+	Requests int
+	// Repeats is the number of calls allowed with the same name.
+	Repeats int
+	// ErrorRate is the share of calls allowed to fail through the fault of the client, once there are at least
+	// MinCallsForErrorRate calls.
+	ErrorRate            float64
+	MinCallsForErrorRate int
+}
+
+// Block is a peer currently blocked.
+type Block struct {
+	Peer   string `json:"peer"`
+	Reason string `json:"reason"`
+	// Offences counts the blocks of the peer, the longer the more it offended.
+	Offences int       `json:"offences"`
+	Since    time.Time `json:"since"`
+	Until    time.Time `json:"until"`
+}
+
+type event struct {
+	at     time.Time
+	name   string
+	failed bool
+}
+
+type peerState struct {
+	events []event
+	block  *Block
+	// offences and lastOffence drive the escalation, they outlive the block itself
+	offences    int
+	lastOffence time.Time
+}
+
+// detector tracks the calls of every peer in a sliding window.
+type detector struct {
+	window      time.Duration
+	thresholds  Thresholds
+	baseBlock   time.Duration
+	maxBlock    time.Duration
+	forgetAfter time.Duration
+
+	mu        sync.Mutex
+	peers     map[string]*peerState
+	lastSweep time.Time
+}
+
+func newDetector(o options) *detector {
+	return &detector{
+		window:      o.window,
+		thresholds:  o.thresholds,
+		baseBlock:   o.baseBlock,
+		maxBlock:    o.maxBlock,
+		forgetAfter: o.forgetAfter,
+		peers:       make(map[string]*peerState),
+	}
+}
+
+// blocked returns the block of peer at now.
+func (d *detector) blocked(peer string, now time.Time) (Block, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.peers[peer]
+	if !ok || p.block == nil || !now.Before(p.block.Until) {
+		return Block{}, false
+	}
+	return *p.block, true
+}
+
+// record adds a call of peer to its window, returning the block it earned when the call crossed a threshold.
+func (d *detector) record(peer, name string, failed bool, now time.Time) (Block, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.Sub(d.lastSweep) >= d.window {
+		d.sweep(now)
+	}
+
+	p, ok := d.peers[peer]
+	if !ok {
+		p = &peerState{}
+		d.peers[peer] = p
+	}
+	if p.block != nil && now.Before(p.block.Until) {
+		// calls admitted just before the block was imposed do not count towards the next one
+		return Block{}, false
+	}
+
+	p.events = append(p.events, event{at: now, name: strings.ToLower(strings.TrimSpace(name)), failed: failed})
+	cutoff := now.Add(-d.window)
+	p.events = slices.DeleteFunc(p.events, func(e event) bool { return !e.at.After(cutoff) })
+
+	reason := d.offence(p.events)
+	if reason == "" {
+		return Block{}, false
+	}
+
+	if !p.lastOffence.IsZero() && now.Sub(p.lastOffence) > d.forgetAfter {
+		p.offences = 0
+	}
+	p.offences++
+	p.lastOffence = now
+	p.events = nil
+	p.block = &Block{
+		Peer:     peer,
+		Reason:   reason,
+		Offences: p.offences,
+		Since:    now,
+		Until:    now.Add(d.blockDuration(p.offences)),
+	}
+	return *p.block, true
+}
+
+// offence returns why the events of a peer are abusive, or nothing when they are not.
+func (d *detector) offence(events []event) string {
+	t := d.thresholds
+	if t.Requests > 0 && len(events) > t.Requests {
+		return fmt.Sprintf("more than %d calls in %s", t.Requests, d.window)
+	}
+
+	if t.Repeats > 0 {
+		counts := make(map[string]int)
+		for _, e := range events {
+			counts[e.name]++
+			if counts[e.name] > t.Repeats {
+				return fmt.Sprintf("more than %d calls with the same name in %s", t.Repeats, d.window)
+			}
+		}
+	}
+
+	if t.ErrorRate > 0 && len(events) >= t.MinCallsForErrorRate {
+		failed := 0
+		for _, e := range events {
+			if e.failed {
+				failed++
+			}
+		}
+		if rate := float64(failed) / float64(len(events)); rate > t.ErrorRate {
+			return fmt.Sprintf("%.0f%% of calls failed in %s", rate*100, d.window)
+		}
+	}
+	return ""
+}
+
+// blockDuration quadruples the block with every offence, up to the longest block.
+func (d *detector) blockDuration(offences int) time.Duration {
+	b := d.baseBlock
+	for i := 1; i < offences && b < d.maxBlock; i++ {
+		b *= 4
+	}
+	return min(b, d.maxBlock)
+}
+
+// blocks returns the blocks in force at now, by peer.
+func (d *detector) blocks(now time.Time) []Block {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var out []Block
+	for _, p := range d.peers {
+		if p.block != nil && now.Before(p.block.Until) {
+			out = append(out, *p.block)
+		}
+	}
+	slices.SortFunc(out, func(a, b Block) int { return strings.Compare(a.Peer, b.Peer) })
+	return out
+}
+
+// unblock lifts the block of peer, forgive also forgetting its offences so that its next block is the shortest.
+func (d *detector) unblock(peer string, forgive bool, now time.Time) (Block, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.peers[peer]
+	if !ok || p.block == nil || !now.Before(p.block.Until) {
+		return Block{}, false
+	}
+
+	b := *p.block
+	p.block = nil
+	p.events = nil
+	if forgive {
+		p.offences = 0
+	}
+	return b, true
+}
+
+// sweep drops the peers with nothing left to remember.
+func (d *detector) sweep(now time.Time) {
+	cutoff := now.Add(-d.window)
+	for peer, p := range d.peers {
+		if p.block != nil && now.Before(p.block.Until) {
+			continue
+		}
+		if len(p.events) > 0 && p.events[len(p.events)-1].at.After(cutoff) {
+			continue
+		}
+		if p.offences > 0 && now.Sub(p.lastOffence) <= d.forgetAfter {
+			continue
+		}
+		delete(d.peers, peer)
+	}
+	d.lastSweep = now
+}
@@ -0,0 +1,44 @@
+package abuse
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/ipfilter"
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// Service responds to greetings, as the listeners call it.
+type Service interface {
+	Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+}
+
+type guard struct {
+	d    *Detector
+	next Service
+}
+
+// Guard returns a Service tracking and refusing the greetings of peers like the interceptor does, for the listeners
+// calling the service without going through the gRPC interceptors.  Their peer address must be in the context as a
+// grpc peer.Peer, or as the client address found by the ip filter.
+func (d *Detector) Guard(next Service) Service {
+	return &guard{d: d, next: next}
+}
+
+func (g *guard) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	p := ipfilter.PeerIP(ctx)
+	if b, ok := g.d.detector.blocked(p, g.d.now()); ok {
+		rejected.Inc()
+		return service.RespondResponse{}, blockedError(b, g.d.now())
+	}
+
+	resp, err := g.next.Respond(ctx, request)
+
+	failed := slices.Contains(clientFaults, status.Code(err))
+	if b, ok := g.d.detector.record(p, request.OriginalMessage, failed, g.d.now()); ok {
+		g.d.logBlock(ctx, b)
+	}
+	return resp, err
+}
@@ -0,0 +1,75 @@
+// Package admin supports the operational gRPC services of the greeter.  They are written by hand rather than
+// generated, so their messages are plain Go structs carried with the "json" codec registered here: clients call them
+// with grpc.CallContentSubtype(ContentSubtype), as Invoke does.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentSubtype selects the codec, the requests are sent as application/grpc+json.
+const ContentSubtype = "json"
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// codec encodes Go values with encoding/json, and protobuf messages with protojson so that any service can be called
+// with JSON.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return protojson.Marshal(m)
+	}
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return protojson.Unmarshal(data, m)
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return ContentSubtype
+}
+
+// UnaryMethod describes the unary method name of service.  Server interceptors apply as they do to generated services.
+func UnaryMethod[Req, Res any](service, name string, fn func(ctx context.Context, req *Req) (*Res, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return fn(ctx, req)
+			}
+
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fmt.Sprintf("/%s/%s", service, name)}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return fn(ctx, req.(*Req))
+			})
+		},
+	}
+}
+
+// Invoke calls a unary method of a hand written service.
+func Invoke[Res any](ctx context.Context, conn grpc.ClientConnInterface, method string, req any, opts ...grpc.CallOption) (*Res, error) {
+	res := new(Res)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(ContentSubtype)}, opts...)
+	if err := conn.Invoke(ctx, method, req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,103 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/admin"
+	"github.com/LewisJAllan/greeter/errdetails"
+)
+
+const AdminServiceName = "greeter.apikey.v1.KeyAdmin"
+
+const (
+	CreateKeyFullMethodName = "/" + AdminServiceName + "/CreateKey"
+	RevokeKeyFullMethodName = "/" + AdminServiceName + "/RevokeKey"
+	ListKeysFullMethodName  = "/" + AdminServiceName + "/ListKeys"
+)
+
+type CreateKeyRequest struct {
+	Name      string `json:"name"`
+	PerMinute int    `json:"perMinute,omitempty"`
+	PerDay    int    `json:"perDay,omitempty"`
+}
+
+// CreateKeyResponse holds the only copy of the secret key, the store keeps its hash.
+type CreateKeyResponse struct {
+	Key    Key    `json:"key"`
+	Secret string `json:"secret"`
+}
+
+type RevokeKeyRequest struct {
+	ID string `json:"id"`
+}
+
+type ListKeysResponse struct {
+	Keys []Key `json:"keys"`
+}
+
+type Empty struct{}
+
+// Admin is a grpc Registerer for the KeyAdmin service, which manages the keys of a Store.  It must be protected, for
+// example with an rbac policy, as anyone calling it can issue keys.
+type Admin struct {
+	store *Store
+}
+
+func NewAdmin(store *Store) *Admin {
+	return &Admin{store: store}
+}
+
+func (a *Admin) Register(s *grpc.Server) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: AdminServiceName,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			admin.UnaryMethod(AdminServiceName, "CreateKey", a.createKey),
+			admin.UnaryMethod(AdminServiceName, "RevokeKey", a.revokeKey),
+			admin.UnaryMethod(AdminServiceName, "ListKeys", a.listKeys),
+		},
+	}, a)
+}
+
+func (a *Admin) createKey(_ context.Context, req *CreateKeyRequest) (*CreateKeyResponse, error) {
+	var violations []errdetails.FieldViolation
+	if req.Name == "" {
+		violations = append(violations, errdetails.FieldViolation{Field: "name", Description: "must not be empty"})
+	}
+	if req.PerMinute < 0 {
+		violations = append(violations, errdetails.FieldViolation{Field: "perMinute", Description: "must not be negative"})
+	}
+	if req.PerDay < 0 {
+		violations = append(violations, errdetails.FieldViolation{Field: "perDay", Description: "must not be negative"})
+	}
+	if len(violations) > 0 {
+		return nil, errdetails.Error(codes.InvalidArgument, "invalid key", errdetails.BadRequest(violations...))
+	}
+
+	k, secret, err := a.store.Create(req.Name, req.PerMinute, req.PerDay, time.Now())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &CreateKeyResponse{Key: k, Secret: secret}, nil
+}
+
+func (a *Admin) revokeKey(_ context.Context, req *RevokeKeyRequest) (*Key, error) {
+	k, err := a.store.Revoke(req.ID, time.Now())
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "no key %q", req.ID)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &k, nil
+}
+
+func (a *Admin) listKeys(context.Context, *Empty) (*ListKeysResponse, error) {
+	return &ListKeysResponse{Keys: a.store.List()}, nil
+}
@@ -0,0 +1,276 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func TestStoreAuthenticate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	k, secret, err := s.Create("partner", 10, 100, now)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	revoked, revokedSecret, err := s.Create("former partner", 0, 0, now)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := s.Revoke(revoked.ID, now); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	// the keys must survive a restart
+	s, err = OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		wantID  string
+		wantErr error
+	}{
+		{name: "valid", key: secret, wantID: k.ID},
+		{name: "revoked", key: revokedSecret, wantErr: ErrRevoked},
+		{name: "other secret", key: secret[:len(secret)-2] + "AA", wantErr: ErrInvalidKey},
+		{name: "other id", key: keyPrefix + revoked.ID + secret[len(keyPrefix)+len(k.ID):], wantErr: ErrInvalidKey},
+		{name: "unknown id", key: keyPrefix + "0000000000000000" + secret[len(keyPrefix)+len(k.ID):], wantErr: ErrInvalidKey},
+		{name: "no prefix", key: strings.TrimPrefix(secret, keyPrefix), wantErr: ErrInvalidKey},
+		{name: "no secret", key: keyPrefix + k.ID, wantErr: ErrInvalidKey},
+		{name: "invalid encoding", key: keyPrefix + k.ID + ".!", wantErr: ErrInvalidKey},
+		{name: "empty", key: "", wantErr: ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Authenticate(tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("Authenticate() id = %q, want %q", got.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestStoreRevoke(t *testing.T) {
+	s, err := OpenStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	k, _, err := s.Create("partner", 0, 0, now)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := s.Revoke(k.ID, now); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	again, err := s.Revoke(k.ID, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if !again.RevokedAt.Equal(now) {
+		t.Errorf("Revoke() twice revoked at = %v, want %v", again.RevokedAt, now)
+	}
+	if _, err := s.Revoke("unknown", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revoke() unknown key error = %v, want %v", err, ErrNotFound)
+	}
+	if keys := s.List(); len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Errorf("List() = %+v, want the revoked key", keys)
+	}
+}
+
+func TestQuotasTake(t *testing.T) {
+	start := time.Date(2024, 5, 1, 23, 58, 30, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		key  Key
+		// calls are made at start plus each offset
+		calls     []time.Duration
+		wantTaken []bool
+		wantRetry time.Duration
+	}{
+		{
+			name:      "no quota",
+			key:       Key{ID: "a"},
+			calls:     []time.Duration{0, 0, 0},
+			wantTaken: []bool{true, true, true},
+		},
+		{
+			name:      "per minute",
+			key:       Key{ID: "a", PerMinute: 2},
+			calls:     []time.Duration{0, time.Second, time.Second * 2},
+			wantTaken: []bool{true, true, false},
+			wantRetry: time.Second * 28,
+		},
+		{
+			name:      "next minute",
+			key:       Key{ID: "a", PerMinute: 2},
+			calls:     []time.Duration{0, time.Second, time.Second * 30},
+			wantTaken: []bool{true, true, true},
+		},
+		{
+			name:      "per day",
+			key:       Key{ID: "a", PerDay: 2},
+			calls:     []time.Duration{0, time.Minute, time.Minute + time.Second*10},
+			wantTaken: []bool{true, true, false},
+			wantRetry: time.Second * 20,
+		},
+		{
+			name:      "next day",
+			key:       Key{ID: "a", PerDay: 2},
+			calls:     []time.Duration{0, time.Minute, time.Minute * 2},
+			wantTaken: []bool{true, true, true},
+		},
+		{
+			name:      "refused calls are not counted",
+			key:       Key{ID: "a", PerMinute: 1, PerDay: 2},
+			calls:     []time.Duration{0, time.Second, time.Second * 30},
+			wantTaken: []bool{true, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQuotas()
+			for i, offset := range tt.calls {
+				ex, ok := q.take(tt.key, start.Add(offset))
+				if ok != tt.wantTaken[i] {
+					t.Fatalf("take() call %d = %v, want %v", i, ok, tt.wantTaken[i])
+				}
+				if !ok && i == len(tt.calls)-1 && ex.retryAfter != tt.wantRetry {
+					t.Errorf("take() retry after = %v, want %v", ex.retryAfter, tt.wantRetry)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticatorAuthenticate(t *testing.T) {
+	s, err := OpenStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	k, secret, err := s.Create("partner", 1, 0, now)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	const method = "/playground.Greeter/SayHello"
+	incoming := func(values ...string) context.Context {
+		md := metadata.MD{}
+		for _, v := range values {
+			md.Append(MetadataKey, v)
+		}
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		ctx      context.Context
+		method   string
+		wantCode codes.Code
+		wantKey  bool
+	}{
+		{name: "valid", ctx: incoming(secret), method: method, wantKey: true},
+		{name: "missing", ctx: incoming(), method: method, wantCode: codes.Unauthenticated},
+		{name: "missing but optional", opts: []Option{WithOptional()}, ctx: incoming(), method: method},
+		{name: "invalid but optional", opts: []Option{WithOptional()}, ctx: incoming("gk_nope"), method: method, wantCode: codes.Unauthenticated},
+		{name: "sent twice", ctx: incoming(secret, secret), method: method, wantCode: codes.Unauthenticated},
+		{name: "other method", opts: []Option{WithMethods(method)}, ctx: incoming(), method: "/other.Service/Method"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthenticator(s, tt.opts...)
+			a.now = func() time.Time { return now }
+
+			ctx, err := a.authenticate(tt.ctx, tt.method)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("authenticate() code = %v, want %v (error %v)", code, tt.wantCode, err)
+			}
+			if got, ok := KeyFromContext(ctx); ok != tt.wantKey || (ok && got.ID != k.ID) {
+				t.Errorf("authenticate() key = %+v, %v, want key %v", got, ok, tt.wantKey)
+			}
+		})
+	}
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		a := NewAuthenticator(s)
+		a.now = func() time.Time { return now }
+
+		if _, err := a.authenticate(incoming(secret), method); err != nil {
+			t.Fatalf("authenticate() error = %v", err)
+		}
+		_, err := a.authenticate(incoming(secret), method)
+		if code := status.Code(err); code != codes.ResourceExhausted {
+			t.Errorf("authenticate() code = %v, want %v", code, codes.ResourceExhausted)
+		}
+	})
+}
+
+func TestAuthenticatorGuard(t *testing.T) {
+	s, err := OpenStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	k, secret, err := s.Create("partner", 2, 0, now)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	a := NewAuthenticator(s)
+	a.now = func() time.Time { return now }
+	var gotKey Key
+	g := a.Guard(respondFunc(func(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+		gotKey, _ = KeyFromContext(ctx)
+		return service.RespondResponse{ResponseMessage: "Hello " + request.OriginalMessage}, nil
+	}))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, secret))
+
+	if _, err := g.Respond(context.Background(), service.RespondRequest{OriginalMessage: "Ann"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Respond() without a key error = %v, want %v", err, codes.Unauthenticated)
+	}
+	resp, err := g.Respond(ctx, service.RespondRequest{OriginalMessage: "Ann"})
+	if err != nil || resp.ResponseMessage != "Hello Ann" {
+		t.Fatalf("Respond() = %+v, %v, want Hello Ann", resp, err)
+	}
+	if gotKey.ID != k.ID {
+		t.Errorf("key = %q, want %q", gotKey.ID, k.ID)
+	}
+
+	// the guard and the interceptors count against the same quota
+	if _, err := a.authenticate(ctx, "/playground.Greeter/SayHello"); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+	if _, err := g.Respond(ctx, service.RespondRequest{OriginalMessage: "Ann"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Respond() over quota error = %v, want %v", err, codes.ResourceExhausted)
+	}
+}
+
+type respondFunc func(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+
+func (f respondFunc) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	return f(ctx, request)
+}
@@ -0,0 +1,32 @@
+package apikey
+
+import (
+	"context"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// Service responds to greetings, as the listeners call it.
+type Service interface {
+	Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+}
+
+type guard struct {
+	a    *Authenticator
+	next Service
+}
+
+// Guard returns a Service requiring an API key on every greeting and counting it against the same quotas as the
+// interceptors, for the listeners calling the service without going through them.  The key must be in the incoming
+// grpc metadata of the context, as the HTTP and Connect listeners put their request headers.
+func (a *Authenticator) Guard(next Service) Service {
+	return &guard{a: a, next: next}
+}
+
+func (g *guard) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	ctx, err := g.a.check(ctx)
+	if err != nil {
+		return service.RespondResponse{}, err
+	}
+	return g.next.Respond(ctx, request)
+}
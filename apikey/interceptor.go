@@ -0,0 +1,129 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/errdetails"
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+// MetadataKey is the metadata clients send their key in.
+const MetadataKey = "x-api-key"
+
+type keyKey struct{}
+
+// KeyFromContext returns the key the request was authenticated with, reporting false when the request carried none.
+func KeyFromContext(ctx context.Context) (Key, bool) {
+	k, ok := ctx.Value(keyKey{}).(Key)
+	return k, ok
+}
+
+// WithKey returns a copy of ctx carrying k.
+func WithKey(ctx context.Context, k Key) context.Context {
+	return context.WithValue(ctx, keyKey{}, k)
+}
+
+type options struct {
+	optional bool
+	methods  []string
+}
+
+type Option func(o *options)
+
+// WithOptional lets requests without a key through unauthenticated, for other interceptors to decide on.  Requests
+// with an invalid or revoked key are still refused.
+func WithOptional() Option {
+	return func(o *options) {
+		o.optional = true
+	}
+}
+
+// WithMethods restricts the interceptors to the given full method names, calls to other methods are passed through.
+func WithMethods(methods ...string) Option {
+	return func(o *options) {
+		o.methods = append(o.methods, methods...)
+	}
+}
+
+// Authenticator provides interceptors requiring an API key on every call and counting the call against its quotas.
+type Authenticator struct {
+	store  *Store
+	quotas *quotas
+	opts   options
+	now    func() time.Time
+}
+
+func NewAuthenticator(store *Store, opts ...Option) *Authenticator {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Authenticator{store: store, quotas: newQuotas(), opts: o, now: time.Now}
+}
+
+func (a *Authenticator) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, grpcutil.WithContext(ss, ctx))
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if len(a.opts.methods) > 0 && !slices.Contains(a.opts.methods, method) {
+		return ctx, nil
+	}
+	return a.check(ctx)
+}
+
+// check requires the key in the incoming metadata of ctx and takes a call from its quotas.
+func (a *Authenticator) check(ctx context.Context) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, MetadataKey)
+	switch {
+	case len(values) == 0 && a.opts.optional:
+		return ctx, nil
+	case len(values) == 0:
+		return ctx, status.Error(codes.Unauthenticated, "an API key is required in "+MetadataKey)
+	case len(values) > 1:
+		return ctx, status.Error(codes.Unauthenticated, "only one "+MetadataKey+" may be sent")
+	}
+
+	k, err := a.store.Authenticate(values[0])
+	if err != nil {
+		zaphelper.Info(ctx, "api key refused", zap.Error(err))
+		if errors.Is(err, ErrRevoked) {
+			return ctx, status.Error(codes.Unauthenticated, "API key was revoked")
+		}
+		return ctx, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	ctx = zaphelper.With(ctx, zaphelper.FromContext(ctx).With(zap.String("api_key", k.ID)))
+
+	if ex, ok := a.quotas.take(k, a.now()); !ok {
+		zaphelper.Info(ctx, "api key quota exceeded",
+			zap.String("quota", ex.description),
+			zap.Duration("retry_after", ex.retryAfter))
+		return ctx, errdetails.Error(codes.ResourceExhausted, ex.description,
+			errdetails.RetryInfo(ex.retryAfter),
+			errdetails.QuotaFailure(errdetails.QuotaViolation{Subject: "apikey:" + k.ID, Description: ex.description}),
+		)
+	}
+	return WithKey(ctx, k), nil
+}
@@ -0,0 +1,70 @@
+package apikey
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// usage counts the calls of a key in the current minute and day.
+type usage struct {
+	minute      time.Time
+	minuteCalls int
+	day         time.Time
+	dayCalls    int
+}
+
+// quotas counts the calls of every key in memory, so the counts start over when the process restarts.
+type quotas struct {
+	mu    sync.Mutex
+	usage map[string]*usage
+}
+
+func newQuotas() *quotas {
+	return &quotas{usage: make(map[string]*usage)}
+}
+
+// exceeded is a quota a call went over.
+type exceeded struct {
+	description string
+	retryAfter  time.Duration
+}
+
+// take counts a call of k at now, unless it exceeds one of the quotas of k.
+func (q *quotas) take(k Key, now time.Time) (exceeded, bool) {
+	now = now.UTC()
+	minute := now.Truncate(time.Minute)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	u, ok := q.usage[k.ID]
+	if !ok {
+		u = &usage{}
+		q.usage[k.ID] = u
+	}
+	if !u.minute.Equal(minute) {
+		u.minute, u.minuteCalls = minute, 0
+	}
+	if !u.day.Equal(day) {
+		u.day, u.dayCalls = day, 0
+	}
+
+	if k.PerMinute > 0 && u.minuteCalls >= k.PerMinute {
+		return exceeded{
+			description: fmt.Sprintf("quota of %d calls per minute exceeded", k.PerMinute),
+			retryAfter:  minute.Add(time.Minute).Sub(now),
+		}, false
+	}
+	if k.PerDay > 0 && u.dayCalls >= k.PerDay {
+		return exceeded{
+			description: fmt.Sprintf("quota of %d calls per day exceeded", k.PerDay),
+			retryAfter:  day.AddDate(0, 0, 1).Sub(now),
+		}, false
+	}
+
+	u.minuteCalls++
+	u.dayCalls++
+	return exceeded{}, true
+}
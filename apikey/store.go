@@ -0,0 +1,215 @@
+// Package apikey authenticates partner integrations with API keys sent in metadata, enforcing per-key quotas.  Only
+// hashes of the keys are stored, the secret is shown once when the key is created.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyPrefix starts every key so that leaked keys are easy to recognise.
+const keyPrefix = "gk_"
+
+var (
+	ErrNotFound   = errors.New("apikey: no such key")
+	ErrInvalidKey = errors.New("apikey: invalid key")
+	ErrRevoked    = errors.New("apikey: key was revoked")
+)
+
+// Key describes an API key, without its secret.
+type Key struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// PerMinute and PerDay are the number of calls allowed in each calendar minute and UTC day, 0 allows any number.
+	PerMinute int        `json:"perMinute,omitempty"`
+	PerDay    int        `json:"perDay,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// record is a key as stored, with the hash of its secret.
+type record struct {
+	Key
+	Hash string `json:"hash"`
+}
+
+// Store holds the keys in a JSON file, rewritten on every change.
+type Store struct {
+	path string
+
+	mu   sync.RWMutex
+	keys map[string]record
+}
+
+// OpenStore reads the keys stored at path, the file is created with the first key when it does not exist.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path, keys: make(map[string]record)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("apikey: unable to read store: %w", err)
+	}
+
+	var doc struct {
+		Keys []record `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("apikey: invalid store %s: %w", path, err)
+	}
+	for _, r := range doc.Keys {
+		s.keys[r.ID] = r
+	}
+	return s, nil
+}
+
+// Create adds a key, returning it with the secret to hand to the partner.
+func (s *Store) Create(name string, perMinute, perDay int, now time.Time) (Key, string, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return Key{}, "", fmt.Errorf("apikey: unable to generate key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, "", fmt.Errorf("apikey: unable to generate key: %w", err)
+	}
+
+	r := record{
+		Key: Key{
+			ID:        hex.EncodeToString(id),
+			Name:      name,
+			PerMinute: perMinute,
+			PerDay:    perDay,
+			CreatedAt: now.UTC(),
+		},
+		Hash: hash(secret),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[r.ID] = r
+	if err := s.save(); err != nil {
+		delete(s.keys, r.ID)
+		return Key{}, "", err
+	}
+	return r.Key, keyPrefix + r.ID + "." + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// Revoke revokes the key id, revoking a key twice keeps the time of the first revocation.
+func (s *Store) Revoke(id string, now time.Time) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	if r.RevokedAt != nil {
+		return r.Key, nil
+	}
+
+	revoked := r
+	t := now.UTC()
+	revoked.RevokedAt = &t
+	s.keys[id] = revoked
+	if err := s.save(); err != nil {
+		s.keys[id] = r
+		return Key{}, err
+	}
+	return revoked.Key, nil
+}
+
+// List returns every key, revoked ones included, by creation time.
+func (s *Store) List() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Key, 0, len(s.keys))
+	for _, r := range s.keys {
+		out = append(out, r.Key)
+	}
+	slices.SortFunc(out, func(a, b Key) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return out
+}
+
+// Authenticate returns the key a client sent.
+func (s *Store) Authenticate(key string) (Key, error) {
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(key, keyPrefix), ".")
+	if !ok || !strings.HasPrefix(key, keyPrefix) {
+		return Key{}, ErrInvalidKey
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Key{}, ErrInvalidKey
+	}
+
+	s.mu.RLock()
+	r, ok := s.keys[id]
+	s.mu.RUnlock()
+
+	if !ok || subtle.ConstantTimeCompare([]byte(hash(secret)), []byte(r.Hash)) != 1 {
+		return Key{}, ErrInvalidKey
+	}
+	if r.RevokedAt != nil {
+		return Key{}, ErrRevoked
+	}
+	return r.Key, nil
+}
+
+// save writes the keys to a temporary file renamed over the store, so that a crash never leaves it half written.
+func (s *Store) save() error {
+	doc := struct {
+		Keys []record `json:"keys"`
+	}{Keys: make([]record, 0, len(s.keys))}
+	for _, r := range s.keys {
+		doc.Keys = append(doc.Keys, r)
+	}
+	slices.SortFunc(doc.Keys, func(a, b record) int { return strings.Compare(a.ID, b.ID) })
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("apikey: unable to encode store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("apikey: unable to save store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("apikey: unable to save store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("apikey: unable to save store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("apikey: unable to save store: %w", err)
+	}
+	return nil
+}
+
+func hash(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
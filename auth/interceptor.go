@@ -0,0 +1,116 @@
+// Package auth authenticates gRPC callers with JWT bearer tokens verified against a local JWKS.
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the claims of the token the request was authenticated with, reporting false when the
+// request carried no token.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(Claims)
+	return c, ok
+}
+
+// WithClaims returns a copy of ctx carrying c.
+func WithClaims(ctx context.Context, c Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+type options struct {
+	optional bool
+}
+
+type Option func(o *options)
+
+// WithOptional lets requests without a token through unauthenticated, for other interceptors to decide on.  Requests
+// with an invalid token are still refused.
+func WithOptional() Option {
+	return func(o *options) {
+		o.optional = true
+	}
+}
+
+// Authenticator provides interceptors requiring an authorization: Bearer token on every call.
+type Authenticator struct {
+	verifier *Verifier
+	opts     options
+}
+
+func NewAuthenticator(verifier *Verifier, opts ...Option) *Authenticator {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Authenticator{verifier: verifier, opts: o}
+}
+
+func (a *Authenticator) UnaryServerInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) StreamServerInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, grpcutil.WithContext(ss, ctx))
+}
+
+func (a *Authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	token, found, err := bearerToken(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	if !found {
+		if a.opts.optional {
+			return ctx, nil
+		}
+		return ctx, status.Error(codes.Unauthenticated, "a bearer token is required")
+	}
+
+	claims, err := a.verifier.Verify(token)
+	if err != nil {
+		zaphelper.Info(ctx, "bearer token refused", zap.Error(err))
+		return ctx, status.Errorf(codes.Unauthenticated, "invalid bearer token: %v", err)
+	}
+
+	ctx = zaphelper.With(ctx, zaphelper.FromContext(ctx).With(
+		zap.String("subject", claims.Subject),
+		zap.String("issuer", claims.Issuer),
+	))
+	return WithClaims(ctx, claims), nil
+}
+
+// bearerToken returns the token of the authorization metadata.
+func bearerToken(ctx context.Context) (string, bool, error) {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return "", false, nil
+	}
+	if len(values) > 1 {
+		return "", false, status.Error(codes.Unauthenticated, "only one authorization header may be sent")
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || strings.TrimSpace(token) == "" {
+		return "", false, status.Error(codes.Unauthenticated, "authorization must use the Bearer scheme")
+	}
+	return strings.TrimSpace(token), true, nil
+}
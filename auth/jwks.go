@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// key is a verification key of a JWKS, restricted to the algorithm it is used with.
+type key struct {
+	id        string
+	algorithm string
+	public    crypto.PublicKey
+}
+
+// KeySet holds the keys tokens are verified with.
+type KeySet struct {
+	keys []key
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC and OKP
+	X string `json:"x"`
+	Y string `json:"y"`
+}
+
+// LoadKeySet reads a JWKS file.  RSA keys are used with RS256, P-256 keys with ES256 and Ed25519 keys with EdDSA, keys
+// of any other kind or meant for encryption are skipped.
+func LoadKeySet(path string) (*KeySet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: unable to read JWKS: %w", err)
+	}
+	return ParseKeySet(b)
+}
+
+func ParseKeySet(b []byte) (*KeySet, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("auth: invalid JWKS: %w", err)
+	}
+
+	ks := &KeySet{}
+	var errs []error
+	for i, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		parsed, err := parseKey(k)
+		if errors.Is(err, errUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("key %d (%q): %w", i, k.Kid, err))
+			continue
+		}
+		ks.keys = append(ks.keys, parsed)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("auth: invalid JWKS: %w", err)
+	}
+	if len(ks.keys) == 0 {
+		return nil, errors.New("auth: JWKS holds no RS256, ES256 or EdDSA signing keys")
+	}
+	return ks, nil
+}
+
+var errUnsupportedKey = errors.New("unsupported key")
+
+func parseKey(k jwk) (key, error) {
+	switch {
+	case k.Kty == "RSA" && (k.Alg == "" || k.Alg == algRS256):
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return key{}, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return key{}, errors.New("invalid e")
+		}
+		if n.BitLen() < 2048 {
+			return key{}, fmt.Errorf("RSA keys must have at least 2048 bits, not %d", n.BitLen())
+		}
+		return key{id: k.Kid, algorithm: algRS256, public: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+
+	case k.Kty == "EC" && k.Crv == "P-256" && (k.Alg == "" || k.Alg == algES256):
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return key{}, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return key{}, fmt.Errorf("invalid y: %w", err)
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !pub.Curve.IsOnCurve(x, y) {
+			return key{}, errors.New("point is not on P-256")
+		}
+		return key{id: k.Kid, algorithm: algES256, public: pub}, nil
+
+	case k.Kty == "OKP" && k.Crv == "Ed25519" && (k.Alg == "" || k.Alg == algEdDSA):
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return key{}, errors.New("invalid x")
+		}
+		return key{id: k.Kid, algorithm: algEdDSA, public: ed25519.PublicKey(x)}, nil
+
+	default:
+		return key{}, errUnsupportedKey
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// find returns the keys that may have signed a token with the given header.  Tokens naming a key id must be verified
+// with that key, tokens without one are tried against every key of their algorithm.
+func (ks *KeySet) find(kid, algorithm string) []key {
+	var out []key
+	for _, k := range ks.keys {
+		if k.algorithm != algorithm {
+			continue
+		}
+		if kid != "" && k.id != kid {
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
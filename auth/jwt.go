@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	algRS256 = "RS256"
+	algES256 = "ES256"
+	algEdDSA = "EdDSA"
+)
+
+// Claims are the verified claims of a token.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	// Raw holds every claim of the token, including the registered ones above.
+	Raw map[string]any
+}
+
+// Strings returns the claim name as a list of strings, accepting a single string or an array of them as identity
+// providers differ on how they encode roles and groups.
+func (c Claims) Strings(name string) []string {
+	switch v := c.Raw[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// Verifier checks the signature and registered claims of tokens.
+type Verifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	skew     time.Duration
+	now      func() time.Time
+}
+
+// NewVerifier verifies tokens signed by keys.  When issuer or audience are set tokens must carry them, skew is the
+// clock difference tolerated when checking the times of a token.
+func NewVerifier(keys *KeySet, issuer, audience string, skew time.Duration) *Verifier {
+	return &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		skew:     skew,
+		now:      time.Now,
+	}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Verify returns the claims of a compact serialised JWS token once its signature and claims are valid.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, errors.New("token is not a compact JWS")
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, fmt.Errorf("invalid header: %w", err)
+	}
+	if h.Typ != "" && !strings.EqualFold(h.Typ, "JWT") && !strings.EqualFold(h.Typ, "at+jwt") {
+		return Claims{}, fmt.Errorf("unsupported token type %q", h.Typ)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	// the algorithm comes from the key set, a token only picks among the keys allowed for it
+	keys := v.keys.find(h.Kid, h.Alg)
+	if len(keys) == 0 {
+		return Claims{}, fmt.Errorf("no key for algorithm %q and key id %q", h.Alg, h.Kid)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	if !slices.ContainsFunc(keys, func(k key) bool { return verifySignature(k, signed, signature) }) {
+		return Claims{}, errors.New("invalid signature")
+	}
+
+	var raw map[string]any
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return Claims{}, fmt.Errorf("invalid claims: %w", err)
+	}
+	claims, err := newClaims(raw)
+	if err != nil {
+		return Claims{}, err
+	}
+	return claims, v.validate(claims)
+}
+
+func verifySignature(k key, signed, signature []byte) bool {
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		// JWS encodes the signature as the fixed size concatenation of r and s
+		if len(signature) != 64 {
+			return false
+		}
+		digest := sha256.Sum256(signed)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, signed, signature)
+	default:
+		return false
+	}
+}
+
+func (v *Verifier) validate(c Claims) error {
+	now := v.now()
+
+	if c.ExpiresAt.IsZero() {
+		return errors.New("token has no expiry")
+	}
+	if now.After(c.ExpiresAt.Add(v.skew)) {
+		return fmt.Errorf("token expired at %s", c.ExpiresAt.Format(time.RFC3339))
+	}
+	if !c.NotBefore.IsZero() && now.Add(v.skew).Before(c.NotBefore) {
+		return fmt.Errorf("token is not valid before %s", c.NotBefore.Format(time.RFC3339))
+	}
+	if !c.IssuedAt.IsZero() && now.Add(v.skew).Before(c.IssuedAt) {
+		return errors.New("token was issued in the future")
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return fmt.Errorf("unexpected issuer %q", c.Issuer)
+	}
+	if v.audience != "" && !slices.Contains(c.Audience, v.audience) {
+		return fmt.Errorf("token is not meant for audience %q", v.audience)
+	}
+	return nil
+}
+
+func newClaims(raw map[string]any) (Claims, error) {
+	c := Claims{Raw: raw}
+
+	var ok bool
+	if c.Issuer, ok = stringClaim(raw, "iss"); !ok {
+		return Claims{}, errors.New("claim iss must be a string")
+	}
+	if c.Subject, ok = stringClaim(raw, "sub"); !ok {
+		return Claims{}, errors.New("claim sub must be a string")
+	}
+
+	switch aud := raw["aud"].(type) {
+	case nil:
+	case string:
+		c.Audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			s, ok := a.(string)
+			if !ok {
+				return Claims{}, errors.New("claim aud must be a string or an array of strings")
+			}
+			c.Audience = append(c.Audience, s)
+		}
+	default:
+		return Claims{}, errors.New("claim aud must be a string or an array of strings")
+	}
+
+	for name, t := range map[string]*time.Time{"exp": &c.ExpiresAt, "nbf": &c.NotBefore, "iat": &c.IssuedAt} {
+		switch n := raw[name].(type) {
+		case nil:
+		case float64:
+			*t = time.Unix(0, int64(n*float64(time.Second)))
+		default:
+			return Claims{}, fmt.Errorf("claim %s must be a number", name)
+		}
+	}
+	return c, nil
+}
+
+func stringClaim(raw map[string]any, name string) (string, bool) {
+	switch v := raw[name].(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	default:
+		return "", false
+	}
+}
+
+func decodeSegment(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return priv
+}
+
+func sign(t *testing.T, priv ed25519.PrivateKey, h header, claims map[string]any) string {
+	t.Helper()
+	hb, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(priv, []byte(signed)))
+}
+
+func TestVerifierVerify(t *testing.T) {
+	priv := newTestKey(t)
+	other := newTestKey(t)
+	now := time.Unix(1_700_000_000, 0)
+
+	v := NewVerifier(&KeySet{keys: []key{{id: "k1", algorithm: algEdDSA, public: priv.Public()}}}, "https://issuer", "greeter", time.Minute)
+	v.now = func() time.Time { return now }
+
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss": "https://issuer",
+			"sub": "ann",
+			"aud": "greeter",
+			"exp": now.Add(time.Hour).Unix(),
+			"iat": now.Unix(),
+		}
+	}
+	with := func(name string, value any) map[string]any {
+		c := valid()
+		if value == nil {
+			delete(c, name)
+		} else {
+			c[name] = value
+		}
+		return c
+	}
+	h := header{Alg: algEdDSA, Kid: "k1", Typ: "JWT"}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: sign(t, priv, h, valid())},
+		{name: "without key id", token: sign(t, priv, header{Alg: algEdDSA}, valid())},
+		{name: "access token type", token: sign(t, priv, header{Alg: algEdDSA, Typ: "at+jwt"}, valid())},
+		{name: "audience list", token: sign(t, priv, h, with("aud", []string{"other", "greeter"}))},
+		{name: "expired within skew", token: sign(t, priv, h, with("exp", now.Add(-time.Second*30).Unix()))},
+		{name: "not before within skew", token: sign(t, priv, h, with("nbf", now.Add(time.Second*30).Unix()))},
+
+		{name: "expired", token: sign(t, priv, h, with("exp", now.Add(-time.Minute*2).Unix())), wantErr: true},
+		{name: "no expiry", token: sign(t, priv, h, with("exp", nil)), wantErr: true},
+		{name: "not yet valid", token: sign(t, priv, h, with("nbf", now.Add(time.Minute*2).Unix())), wantErr: true},
+		{name: "issued in the future", token: sign(t, priv, h, with("iat", now.Add(time.Minute*2).Unix())), wantErr: true},
+		{name: "other issuer", token: sign(t, priv, h, with("iss", "https://other")), wantErr: true},
+		{name: "other audience", token: sign(t, priv, h, with("aud", "other")), wantErr: true},
+		{name: "no audience", token: sign(t, priv, h, with("aud", nil)), wantErr: true},
+		{name: "subject not a string", token: sign(t, priv, h, with("sub", 42)), wantErr: true},
+		{name: "expiry not a number", token: sign(t, priv, h, with("exp", "tomorrow")), wantErr: true},
+		{name: "signed by another key", token: sign(t, other, h, valid()), wantErr: true},
+		{name: "unknown key id", token: sign(t, priv, header{Alg: algEdDSA, Kid: "k2"}, valid()), wantErr: true},
+		{name: "algorithm of no key", token: sign(t, priv, header{Alg: algRS256, Kid: "k1"}, valid()), wantErr: true},
+		{name: "algorithm none", token: sign(t, priv, header{Alg: "none"}, valid()), wantErr: true},
+		{name: "unsupported type", token: sign(t, priv, header{Alg: algEdDSA, Typ: "dpop+jwt"}, valid()), wantErr: true},
+		{name: "not a JWS", token: "abc.def", wantErr: true},
+		{name: "invalid signature encoding", token: sign(t, priv, h, valid()) + "!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Subject != "ann" {
+				t.Errorf("Verify() subject = %q, want %q", got.Subject, "ann")
+			}
+		})
+	}
+}
+
+func TestVerifierWithoutIssuerOrAudience(t *testing.T) {
+	priv := newTestKey(t)
+	v := NewVerifier(&KeySet{keys: []key{{algorithm: algEdDSA, public: priv.Public()}}}, "", "", 0)
+
+	token := sign(t, priv, header{Alg: algEdDSA}, map[string]any{"sub": "ann", "exp": time.Now().Add(time.Hour).Unix()})
+	if _, err := v.Verify(token); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestClaimsStrings(t *testing.T) {
+	c := Claims{Raw: map[string]any{
+		"scope":  "read write",
+		"roles":  []any{"admin", 42, "ops"},
+		"number": 42.0,
+	}}
+
+	tests := []struct {
+		claim string
+		want  []string
+	}{
+		{claim: "scope", want: []string{"read", "write"}},
+		{claim: "roles", want: []string{"admin", "ops"}},
+		{claim: "number", want: nil},
+		{claim: "missing", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.claim, func(t *testing.T) {
+			if got := c.Strings(tt.claim); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Strings(%q) = %q, want %q", tt.claim, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	priv := newTestKey(t)
+	x := base64.RawURLEncoding.EncodeToString(priv.Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name     string
+		jwks     string
+		wantKeys int
+		wantErr  bool
+	}{
+		{name: "ed25519", jwks: `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"` + x + `"}]}`, wantKeys: 1},
+		{name: "encryption keys skipped", jwks: `{"keys":[{"kty":"OKP","crv":"Ed25519","use":"sig","x":"` + x + `"},{"kty":"OKP","crv":"Ed25519","use":"enc","x":"` + x + `"}]}`, wantKeys: 1},
+		{name: "unsupported keys skipped", jwks: `{"keys":[{"kty":"oct","k":"c2VjcmV0"},{"kty":"OKP","crv":"Ed25519","x":"` + x + `"}]}`, wantKeys: 1},
+		{name: "no signing key", jwks: `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`, wantErr: true},
+		{name: "invalid key", jwks: `{"keys":[{"kty":"OKP","crv":"Ed25519","x":"c2hvcnQ"}]}`, wantErr: true},
+		{name: "point off the curve", jwks: `{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`, wantErr: true},
+		{name: "short RSA key", jwks: `{"keys":[{"kty":"RSA","n":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 128)) + `","e":"AQAB"}]}`, wantErr: true},
+		{name: "invalid json", jwks: `{"keys":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := ParseKeySet([]byte(tt.jwks))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeySet() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(ks.keys) != tt.wantKeys {
+				t.Errorf("ParseKeySet() keys = %d, want %d", len(ks.keys), tt.wantKeys)
+			}
+		})
+	}
+}
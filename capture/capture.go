@@ -0,0 +1,262 @@
+// Package capture records gRPC calls in the grpc binary log format and reads them back for replay.
+//
+// A capture file is a sequence of grpc.binarylog.v1.GrpcLogEntry messages, each prefixed with its length as a 4 byte
+// big endian integer, the same framing grpc uses for its own binary log sink.
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	pb "google.golang.org/grpc/binarylog/grpc_binarylog_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+// maxEntryBytes bounds the size of a single entry read back from a capture.
+const maxEntryBytes = 16 << 20
+
+// Recorder is a unary server interceptor writing each call it sees to a capture.
+type Recorder struct {
+	methods []string
+	callID  atomic.Uint64
+
+	mu     sync.Mutex
+	w      *bufio.Writer
+	c      io.Closer
+	closed bool
+}
+
+// NewRecorder records the calls to methods, given as full method names, to w.  All unary calls are recorded when no
+// methods are given.
+func NewRecorder(w io.WriteCloser, methods ...string) *Recorder {
+	return &Recorder{
+		methods: methods,
+		w:       bufio.NewWriter(w),
+		c:       w,
+	}
+}
+
+// Create records to a new file at path, truncating it when it already exists since the call ids of a capture restart
+// with each recorder.  The file is only readable by its owner, as it holds the names and metadata of the callers.
+func Create(path string, methods ...string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("capture: unable to open %s: %w", path, err)
+	}
+	return NewRecorder(f, methods...), nil
+}
+
+func (r *Recorder) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if len(r.methods) > 0 && !slices.Contains(r.methods, info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	if werr := r.record(ctx, info.FullMethod, start, req, resp, err); werr != nil {
+		zaphelper.Error(ctx, "unable to capture call", zap.String("method", info.FullMethod), zap.Error(werr))
+	}
+	return resp, err
+}
+
+func (r *Recorder) record(ctx context.Context, method string, start time.Time, req, resp any, callErr error) error {
+	id := r.callID.Add(1)
+	end := time.Now()
+
+	entries := []*pb.GrpcLogEntry{{
+		Timestamp: timestamppb.New(start),
+		Type:      pb.GrpcLogEntry_EVENT_TYPE_CLIENT_HEADER,
+		Payload: &pb.GrpcLogEntry_ClientHeader{ClientHeader: &pb.ClientHeader{
+			Metadata:   incomingMetadata(ctx),
+			MethodName: method,
+			Authority:  authority(ctx),
+			Timeout:    timeout(ctx, start),
+		}},
+		Peer: address(ctx),
+	}}
+
+	if msg, ok := req.(proto.Message); ok {
+		e, err := messageEntry(pb.GrpcLogEntry_EVENT_TYPE_CLIENT_MESSAGE, start, msg)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, e)
+	}
+
+	entries = append(entries, &pb.GrpcLogEntry{
+		Timestamp: timestamppb.New(end),
+		Type:      pb.GrpcLogEntry_EVENT_TYPE_SERVER_HEADER,
+		Payload:   &pb.GrpcLogEntry_ServerHeader{ServerHeader: &pb.ServerHeader{}},
+	})
+
+	if msg, ok := resp.(proto.Message); ok && callErr == nil {
+		e, err := messageEntry(pb.GrpcLogEntry_EVENT_TYPE_SERVER_MESSAGE, end, msg)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, e)
+	}
+
+	trailer := &pb.Trailer{}
+	if callErr != nil {
+		st := status.Convert(callErr)
+		trailer.StatusCode = uint32(st.Code())
+		trailer.StatusMessage = st.Message()
+		if len(st.Proto().GetDetails()) > 0 {
+			b, err := proto.Marshal(st.Proto())
+			if err != nil {
+				return fmt.Errorf("capture: unable to encode status: %w", err)
+			}
+			trailer.StatusDetails = b
+		}
+	}
+	entries = append(entries, &pb.GrpcLogEntry{
+		Timestamp: timestamppb.New(end),
+		Type:      pb.GrpcLogEntry_EVENT_TYPE_SERVER_TRAILER,
+		Payload:   &pb.GrpcLogEntry_Trailer{Trailer: trailer},
+	})
+
+	for i, e := range entries {
+		e.CallId = id
+		e.SequenceIdWithinCall = uint64(i + 1)
+		e.Logger = pb.GrpcLogEntry_LOGGER_SERVER
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errors.New("capture: recorder is closed")
+	}
+	for _, e := range entries {
+		if err := writeEntry(r.w, e); err != nil {
+			return err
+		}
+	}
+	// flush per call so a capture is complete up to the last call when the process is killed
+	return r.w.Flush()
+}
+
+// Close flushes the capture and closes the underlying writer.  Calls made afterwards are not recorded.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return errors.Join(r.w.Flush(), r.c.Close())
+}
+
+func messageEntry(typ pb.GrpcLogEntry_EventType, at time.Time, msg proto.Message) (*pb.GrpcLogEntry, error) {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("capture: unable to encode message: %w", err)
+	}
+	return &pb.GrpcLogEntry{
+		Timestamp: timestamppb.New(at),
+		Type:      typ,
+		Payload: &pb.GrpcLogEntry_Message{Message: &pb.Message{
+			Length: uint32(len(b)),
+			Data:   b,
+		}},
+	}, nil
+}
+
+func writeEntry(w io.Writer, e *pb.GrpcLogEntry) error {
+	b, err := proto.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("capture: unable to encode entry: %w", err)
+	}
+
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(b)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return fmt.Errorf("capture: unable to write entry: %w", err)
+	}
+	if _, err := w.Write(b); err != nil {
+		return fmt.Errorf("capture: unable to write entry: %w", err)
+	}
+	return nil
+}
+
+// incomingMetadata returns the request metadata without the pseudo headers, which grpc also leaves out of its logs,
+// and with the values of credentials redacted.
+func incomingMetadata(ctx context.Context) *pb.Metadata {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	out := &pb.Metadata{}
+	for k, values := range md {
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+		for _, v := range values {
+			if grpcutil.IsCredential(k) {
+				v = grpcutil.Redacted
+			}
+			out.Entry = append(out.Entry, &pb.MetadataEntry{Key: k, Value: []byte(v)})
+		}
+	}
+	slices.SortStableFunc(out.Entry, func(a, b *pb.MetadataEntry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return out
+}
+
+func authority(ctx context.Context) string {
+	if v := metadata.ValueFromIncomingContext(ctx, ":authority"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func timeout(ctx context.Context, start time.Time) *durationpb.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	return durationpb.New(deadline.Sub(start))
+}
+
+func address(ctx context.Context) *pb.Address {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+
+	switch addr := p.Addr.(type) {
+	case *net.TCPAddr:
+		typ := pb.Address_TYPE_IPV6
+		if addr.IP.To4() != nil {
+			typ = pb.Address_TYPE_IPV4
+		}
+		return &pb.Address{Type: typ, Address: addr.IP.String(), IpPort: uint32(addr.Port)}
+	case *net.UnixAddr:
+		return &pb.Address{Type: pb.Address_TYPE_UNIX, Address: addr.Name}
+	default:
+		return &pb.Address{Type: pb.Address_TYPE_UNKNOWN, Address: addr.String()}
+	}
+}
@@ -0,0 +1,209 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// sayHello answers name through r, failing for "nobody" with a detail, as the Greeter would.
+func sayHello(t *testing.T, r *Recorder, method, name string) {
+	t.Helper()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		":authority", "greeter:50051",
+		"x-request-id", "42",
+		"authorization", "Bearer secret",
+	))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}})
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	handler := func(_ context.Context, req any) (any, error) {
+		if req.(*schemas.HelloRequest).GetName() == "nobody" {
+			st, err := status.New(codes.InvalidArgument, "no one to greet").WithDetails(durationpb.New(time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return nil, st.Err()
+		}
+		return &schemas.HelloReply{Message: "Hello " + name}, nil
+	}
+	_, _ = r.UnaryServerInterceptor(ctx, &schemas.HelloRequest{Name: name}, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+}
+
+func TestRecordAndRead(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(nopCloser{&buf}, schemas.Greeter_SayHello_FullMethodName)
+
+	sayHello(t, r, schemas.Greeter_SayHello_FullMethodName, "Ann")
+	sayHello(t, r, "/playground.Greeter/Other", "skipped")
+	sayHello(t, r, schemas.Greeter_SayHello_FullMethodName, "nobody")
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	// calls after Close are not recorded
+	sayHello(t, r, schemas.Greeter_SayHello_FullMethodName, "late")
+
+	calls, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("Read() = %d calls, want 2", len(calls))
+	}
+
+	ok := calls[0]
+	if ok.ID != 1 || ok.Method != schemas.Greeter_SayHello_FullMethodName || ok.Code != codes.OK {
+		t.Errorf("first call = #%d %s %s, want #1 SayHello OK", ok.ID, ok.Method, ok.Code)
+	}
+	if ok.Timeout <= 0 || ok.Timeout > time.Minute {
+		t.Errorf("Timeout = %v, want up to a minute", ok.Timeout)
+	}
+	if got := ok.Metadata.Get("x-request-id"); len(got) != 1 || got[0] != "42" {
+		t.Errorf("x-request-id = %v, want 42", got)
+	}
+	if got := ok.Metadata.Get("authorization"); len(got) != 1 || got[0] != grpcutil.Redacted {
+		t.Errorf("authorization = %v, want it redacted", got)
+	}
+	if got := ok.Metadata.Get(":authority"); len(got) != 0 {
+		t.Errorf(":authority = %v, want pseudo headers left out", got)
+	}
+
+	var req schemas.HelloRequest
+	var resp schemas.HelloReply
+	if err := proto.Unmarshal(ok.Request, &req); err != nil || req.GetName() != "Ann" {
+		t.Errorf("Request = %v (%v), want Ann", &req, err)
+	}
+	if err := proto.Unmarshal(ok.Response, &resp); err != nil || resp.GetMessage() != "Hello Ann" {
+		t.Errorf("Response = %v (%v), want Hello Ann", &resp, err)
+	}
+
+	failed := calls[1]
+	if failed.ID != 2 || failed.Code != codes.InvalidArgument || failed.StatusMessage != "no one to greet" || failed.Response != nil {
+		t.Errorf("second call = #%d %s %q, want #2 InvalidArgument without a response", failed.ID, failed.Code, failed.StatusMessage)
+	}
+	var st spb.Status
+	if err := proto.Unmarshal(failed.StatusDetails, &st); err != nil || len(st.GetDetails()) != 1 {
+		t.Errorf("StatusDetails = %v (%v), want the retry delay", &st, err)
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(nopCloser{&buf})
+	sayHello(t, r, schemas.Greeter_SayHello_FullMethodName, "Ann")
+	sayHello(t, r, schemas.Greeter_SayHello_FullMethodName, "Bob")
+
+	// a process killed mid write leaves part of the last entry, and its call without a trailer
+	b := buf.Bytes()[:buf.Len()-3]
+	calls, err := Read(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(calls) != 1 || calls[0].ID != 1 {
+		t.Errorf("Read() = %d calls, want only the first, complete, one", len(calls))
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{name: "too large", in: []byte{0xff, 0xff, 0xff, 0xff}},
+		{name: "not an entry", in: []byte{0, 0, 0, 2, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(bytes.NewReader(tt.in)); err == nil {
+				t.Errorf("Read() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestReadRuns(t *testing.T) {
+	// two runs appended to one file, each numbering its calls from 1
+	var buf bytes.Buffer
+	first := NewRecorder(nopCloser{&buf})
+	sayHello(t, first, schemas.Greeter_SayHello_FullMethodName, "Ann")
+	sayHello(t, first, schemas.Greeter_SayHello_FullMethodName, "Bob")
+	second := NewRecorder(nopCloser{&buf})
+	sayHello(t, second, schemas.Greeter_SayHello_FullMethodName, "Cy")
+
+	calls, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	var names []string
+	for _, c := range calls {
+		var req schemas.HelloRequest
+		if err := proto.Unmarshal(c.Request, &req); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, req.GetName())
+	}
+	if len(names) != 3 || names[0] != "Ann" || names[1] != "Bob" || names[2] != "Cy" {
+		t.Errorf("Read() names = %v, want [Ann Bob Cy]", names)
+	}
+}
+
+func TestCreateTruncates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.bin")
+
+	for _, name := range []string{"Ann", "Bob"} {
+		r, err := Create(path)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		sayHello(t, r, schemas.Greeter_SayHello_FullMethodName, name)
+		if err := r.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+
+	calls, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var req schemas.HelloRequest
+	if len(calls) != 1 || proto.Unmarshal(calls[0].Request, &req) != nil || req.GetName() != "Bob" {
+		t.Errorf("ReadFile() = %d calls, want only the call of the second run", len(calls))
+	}
+}
+
+func TestRecorderWriteError(t *testing.T) {
+	r := NewRecorder(failingWriter{})
+	err := r.record(context.Background(), schemas.Greeter_SayHello_FullMethodName, time.Now(), &schemas.HelloRequest{Name: "Ann"}, &schemas.HelloReply{}, nil)
+	if err == nil {
+		t.Errorf("record() error = nil, want the write error")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+func (failingWriter) Close() error              { return nil }
@@ -0,0 +1,139 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	pb "google.golang.org/grpc/binarylog/grpc_binarylog_v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Call is a unary call assembled from the entries of a capture.
+type Call struct {
+	ID       uint64
+	Method   string
+	Metadata metadata.MD
+	// Timeout is the time the client allowed for the call, zero when it set no deadline.
+	Timeout time.Duration
+	Start   time.Time
+
+	Request  []byte
+	Response []byte
+
+	Code          codes.Code
+	StatusMessage string
+	// StatusDetails is the encoded google.rpc.Status when the error carried details.
+	StatusDetails []byte
+
+	// complete is set once the trailer has been read.
+	complete bool
+}
+
+// ReadFile reads the calls captured in the file at path.
+func ReadFile(path string) ([]Call, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("capture: unable to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Read reads the calls captured in r, in the order they started.  Calls missing their trailer, such as those cut
+// short by the process stopping, are left out.  A client header for a call id already seen starts a new call, so
+// captures concatenated from several runs, whose ids each start from 1, are not merged.
+func Read(r io.Reader) ([]Call, error) {
+	br := bufio.NewReader(r)
+
+	var (
+		calls   []*Call
+		current = map[uint64]*Call{}
+	)
+	for {
+		e, err := readEntry(br)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c, ok := current[e.GetCallId()]
+		if !ok || (e.GetType() == pb.GrpcLogEntry_EVENT_TYPE_CLIENT_HEADER && c.Method != "") {
+			c = &Call{ID: e.GetCallId()}
+			current[c.ID] = c
+			calls = append(calls, c)
+		}
+		apply(c, e)
+	}
+
+	out := make([]Call, 0, len(calls))
+	for _, c := range calls {
+		if c.complete && c.Method != "" {
+			out = append(out, *c)
+		}
+	}
+	return out, nil
+}
+
+func readEntry(r *bufio.Reader) (*pb.GrpcLogEntry, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// a truncated length is what a write interrupted mid entry leaves behind
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > maxEntryBytes {
+		return nil, fmt.Errorf("capture: entry of %d bytes exceeds the limit of %d", n, maxEntryBytes)
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	e := &pb.GrpcLogEntry{}
+	if err := proto.Unmarshal(b, e); err != nil {
+		return nil, fmt.Errorf("capture: invalid entry: %w", err)
+	}
+	return e, nil
+}
+
+func apply(c *Call, e *pb.GrpcLogEntry) {
+	switch e.GetType() {
+	case pb.GrpcLogEntry_EVENT_TYPE_CLIENT_HEADER:
+		h := e.GetClientHeader()
+		c.Method = h.GetMethodName()
+		c.Start = e.GetTimestamp().AsTime()
+		c.Timeout = h.GetTimeout().AsDuration()
+		c.Metadata = metadata.MD{}
+		for _, entry := range h.GetMetadata().GetEntry() {
+			c.Metadata.Append(entry.GetKey(), string(entry.GetValue()))
+		}
+	case pb.GrpcLogEntry_EVENT_TYPE_CLIENT_MESSAGE:
+		c.Request = e.GetMessage().GetData()
+	case pb.GrpcLogEntry_EVENT_TYPE_SERVER_MESSAGE:
+		c.Response = e.GetMessage().GetData()
+	case pb.GrpcLogEntry_EVENT_TYPE_SERVER_TRAILER:
+		t := e.GetTrailer()
+		c.Code = codes.Code(t.GetStatusCode())
+		c.StatusMessage = t.GetStatusMessage()
+		c.StatusDetails = t.GetStatusDetails()
+		c.complete = true
+	}
+}
@@ -0,0 +1,108 @@
+// Package errdetails builds the google.rpc error detail messages attached to gRPC statuses.  The generated types are
+// not vendored, so the messages are encoded directly from their well known field numbers and carried as Any.
+package errdetails
+
+import (
+	"sort"
+	"time"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const typeURLPrefix = "type.googleapis.com/"
+
+// Error returns a status error carrying details.
+func Error(code codes.Code, message string, details ...*anypb.Any) error {
+	return status.FromProto(&spb.Status{
+		Code:    int32(code),
+		Message: message,
+		Details: details,
+	}).Err()
+}
+
+// ErrorInfo encodes a google.rpc.ErrorInfo.
+func ErrorInfo(reason, domain string, metadata map[string]string) *anypb.Any {
+	var b []byte
+	b = appendString(b, 1, reason)
+	b = appendString(b, 2, domain)
+
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = appendString(entry, 1, k)
+		entry = appendString(entry, 2, metadata[k])
+		b = appendMessage(b, 3, entry)
+	}
+
+	return newAny("google.rpc.ErrorInfo", b)
+}
+
+// RetryInfo encodes a google.rpc.RetryInfo telling the client how long to wait before retrying.
+func RetryInfo(delay time.Duration) *anypb.Any {
+	d, _ := proto.Marshal(durationpb.New(delay))
+	return newAny("google.rpc.RetryInfo", appendMessage(nil, 1, d))
+}
+
+// FieldViolation is a google.rpc.BadRequest.FieldViolation.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// BadRequest encodes a google.rpc.BadRequest.
+func BadRequest(violations ...FieldViolation) *anypb.Any {
+	var b []byte
+	for _, v := range violations {
+		var fv []byte
+		fv = appendString(fv, 1, v.Field)
+		fv = appendString(fv, 2, v.Description)
+		b = appendMessage(b, 1, fv)
+	}
+	return newAny("google.rpc.BadRequest", b)
+}
+
+// QuotaViolation is a google.rpc.QuotaFailure.Violation.
+type QuotaViolation struct {
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+// QuotaFailure encodes a google.rpc.QuotaFailure.
+func QuotaFailure(violations ...QuotaViolation) *anypb.Any {
+	var b []byte
+	for _, v := range violations {
+		var qv []byte
+		qv = appendString(qv, 1, v.Subject)
+		qv = appendString(qv, 2, v.Description)
+		b = appendMessage(b, 1, qv)
+	}
+	return newAny("google.rpc.QuotaFailure", b)
+}
+
+func newAny(name string, value []byte) *anypb.Any {
+	return &anypb.Any{TypeUrl: typeURLPrefix + name, Value: value}
+}
+
+// appendString appends a string field, leaving it out when empty as proto3 does.
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
@@ -0,0 +1,144 @@
+package errdetails
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// field is a length delimited field of an encoded message, the only wire type the details use.
+type field struct {
+	num   protowire.Number
+	value []byte
+}
+
+func decode(t *testing.T, b []byte) []field {
+	t.Helper()
+	var fields []field
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || typ != protowire.BytesType {
+			t.Fatalf("invalid tag in %x", b)
+		}
+		b = b[n:]
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			t.Fatalf("invalid field %d in %x", num, b)
+		}
+		fields = append(fields, field{num: num, value: v})
+		b = b[n:]
+	}
+	return fields
+}
+
+// format writes fields as num:value, decoding the nested messages of the given field numbers.
+func format(t *testing.T, fields []field, nested ...protowire.Number) string {
+	t.Helper()
+	var s string
+	for _, f := range fields {
+		v := fmt.Sprintf("%q", f.value)
+		for _, num := range nested {
+			if f.num == num {
+				v = "{" + format(t, decode(t, f.value)) + "}"
+			}
+		}
+		if s != "" {
+			s += " "
+		}
+		s += fmt.Sprintf("%d:%s", f.num, v)
+	}
+	return s
+}
+
+func TestDetails(t *testing.T) {
+	tests := []struct {
+		name     string
+		detail   *anypb.Any
+		wantType string
+		nested   []protowire.Number
+		want     string
+	}{
+		{
+			name:     "error info",
+			detail:   ErrorInfo("RATE_LIMITED", "greeter", map[string]string{"limit": "10", "key": "k1"}),
+			wantType: "type.googleapis.com/google.rpc.ErrorInfo",
+			nested:   []protowire.Number{3},
+			want:     `1:"RATE_LIMITED" 2:"greeter" 3:{1:"key" 2:"k1"} 3:{1:"limit" 2:"10"}`,
+		},
+		{
+			name:     "error info without a domain",
+			detail:   ErrorInfo("BLOCKED", "", nil),
+			wantType: "type.googleapis.com/google.rpc.ErrorInfo",
+			want:     `1:"BLOCKED"`,
+		},
+		{
+			name:     "bad request",
+			detail:   BadRequest(FieldViolation{Field: "name", Description: "required"}, FieldViolation{Field: "locale"}),
+			wantType: "type.googleapis.com/google.rpc.BadRequest",
+			nested:   []protowire.Number{1},
+			want:     `1:{1:"name" 2:"required"} 1:{1:"locale"}`,
+		},
+		{
+			name:     "quota failure",
+			detail:   QuotaFailure(QuotaViolation{Subject: "key:k1", Description: "daily quota"}),
+			wantType: "type.googleapis.com/google.rpc.QuotaFailure",
+			nested:   []protowire.Number{1},
+			want:     `1:{1:"key:k1" 2:"daily quota"}`,
+		},
+		{
+			name:     "empty bad request",
+			detail:   BadRequest(),
+			wantType: "type.googleapis.com/google.rpc.BadRequest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.detail.GetTypeUrl() != tt.wantType {
+				t.Errorf("type URL = %s, want %s", tt.detail.GetTypeUrl(), tt.wantType)
+			}
+			if got := format(t, decode(t, tt.detail.GetValue()), tt.nested...); got != tt.want {
+				t.Errorf("value = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryInfo(t *testing.T) {
+	a := RetryInfo(time.Millisecond * 1500)
+	if a.GetTypeUrl() != "type.googleapis.com/google.rpc.RetryInfo" {
+		t.Errorf("type URL = %s, want google.rpc.RetryInfo", a.GetTypeUrl())
+	}
+
+	fields := decode(t, a.GetValue())
+	if len(fields) != 1 || fields[0].num != 1 {
+		t.Fatalf("fields = %+v, want the retry delay alone", fields)
+	}
+	var d durationpb.Duration
+	if err := proto.Unmarshal(fields[0].value, &d); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.AsDuration(); got != time.Millisecond*1500 {
+		t.Errorf("retry delay = %v, want 1.5s", got)
+	}
+}
+
+func TestError(t *testing.T) {
+	retry := RetryInfo(time.Second)
+	err := Error(codes.Unavailable, "try again", retry)
+
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Unavailable || st.Message() != "try again" {
+		t.Fatalf("Error() = %v, want Unavailable try again", err)
+	}
+	details := st.Proto().GetDetails()
+	if len(details) != 1 || !proto.Equal(details[0], retry) {
+		t.Errorf("details = %v, want the retry info", details)
+	}
+}
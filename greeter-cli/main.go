@@ -0,0 +1,246 @@
+// Command greeter-cli calls Greeter.SayHello and prints the reply.
+//
+//	greeter-cli -addr localhost:50051 -H "x-request-id: 1" -locale en-GB -o json Ann
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/LewisJAllan/greeter/internal/clientconn"
+	greetergrpc "github.com/LewisJAllan/greeter/listeners/grpc"
+)
+
+const (
+	outputText      = "text"
+	outputJSON      = "json"
+	outputProtoJSON = "protojson"
+)
+
+type config struct {
+	conn    clientconn.Options
+	timeout time.Duration
+	locale  string
+	output  string
+	verbose bool
+	name    string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	cfg, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	conn, err := cfg.conn.Dial()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	md := cfg.conn.Metadata()
+	if cfg.locale != "" {
+		md.Set(greetergrpc.LocaleMetadataKey, cfg.locale)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	var header, trailer metadata.MD
+	reply, err := schemas.NewGreeterClient(conn).SayHello(ctx, &schemas.HelloRequest{Name: cfg.name},
+		grpc.Header(&header), grpc.Trailer(&trailer))
+
+	if err := report(cfg, stdout, stderr, reply, err, header, trailer); err != nil {
+		fmt.Fprintf(stderr, "unable to write output: %v\n", err)
+		return 1
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func parseFlags(args []string, stderr io.Writer) (config, error) {
+	var cfg config
+
+	fs := flag.NewFlagSet("greeter-cli", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: greeter-cli [flags] <name>")
+		fs.PrintDefaults()
+	}
+
+	cfg.conn.RegisterFlags(fs, "localhost:50051")
+	fs.DurationVar(&cfg.timeout, "timeout", time.Second*10, "deadline of the call, 0 for none")
+	fs.StringVar(&cfg.locale, "locale", "", "locale to greet in")
+	fs.StringVar(&cfg.output, "o", outputText, "output format: text, json or protojson")
+	fs.BoolVar(&cfg.verbose, "v", false, "print response headers and trailers")
+
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	switch cfg.output {
+	case outputText, outputJSON, outputProtoJSON:
+	default:
+		return config{}, fmt.Errorf("unknown output format %q", cfg.output)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return config{}, errors.New("exactly one name is required")
+	}
+	if err := cfg.conn.Check(); err != nil {
+		return config{}, err
+	}
+	cfg.name = fs.Arg(0)
+
+	return cfg, nil
+}
+
+type statusDetail struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Raw   string          `json:"raw,omitempty"`
+}
+
+type statusOutput struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details []statusDetail `json:"details,omitempty"`
+}
+
+type jsonOutput struct {
+	Message  string              `json:"message,omitempty"`
+	Error    *statusOutput       `json:"error,omitempty"`
+	Headers  map[string][]string `json:"headers,omitempty"`
+	Trailers map[string][]string `json:"trailers,omitempty"`
+}
+
+func report(cfg config, stdout, stderr io.Writer, reply *schemas.HelloReply, callErr error, header, trailer metadata.MD) error {
+	var st *statusOutput
+	if callErr != nil {
+		st = newStatusOutput(callErr)
+	}
+
+	switch cfg.output {
+	case outputJSON:
+		out := jsonOutput{Error: st}
+		if reply != nil {
+			out.Message = reply.GetMessage()
+		}
+		if cfg.verbose || callErr != nil {
+			out.Headers, out.Trailers = header, trailer
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case outputProtoJSON:
+		if callErr == nil {
+			if cfg.verbose {
+				printMetadata(stderr, header, trailer)
+			}
+			b, err := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(reply)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(stdout, string(b))
+			return err
+		}
+	default:
+		if callErr == nil {
+			if cfg.verbose {
+				printMetadata(stderr, header, trailer)
+			}
+			_, err := fmt.Fprintln(stdout, reply.GetMessage())
+			return err
+		}
+	}
+
+	// errors are reported on stderr for the text and protojson formats
+	printMetadata(stderr, header, trailer)
+	fmt.Fprintf(stderr, "error: code = %s desc = %s\n", st.Code, st.Message)
+	for _, d := range st.Details {
+		if d.Value != nil {
+			fmt.Fprintf(stderr, "  detail %s: %s\n", d.Type, d.Value)
+		} else {
+			fmt.Fprintf(stderr, "  detail %s: %s\n", d.Type, d.Raw)
+		}
+	}
+	return nil
+}
+
+func newStatusOutput(err error) *statusOutput {
+	st := status.Convert(err)
+	out := &statusOutput{Code: st.Code().String(), Message: st.Message()}
+	for _, a := range st.Proto().GetDetails() {
+		out.Details = append(out.Details, newStatusDetail(a))
+	}
+	return out
+}
+
+// newStatusDetail decodes details of types linked into the binary, others are shown base64 encoded.
+func newStatusDetail(a *anypb.Any) statusDetail {
+	d := statusDetail{Type: strings.TrimPrefix(a.GetTypeUrl(), "type.googleapis.com/")}
+
+	msg, err := a.UnmarshalNew()
+	if err == nil {
+		if b, err := protojson.Marshal(msg); err == nil {
+			d.Value = b
+			return d
+		}
+	}
+	d.Raw = base64.StdEncoding.EncodeToString(a.GetValue())
+	return d
+}
+
+func printMetadata(w io.Writer, header, trailer metadata.MD) {
+	printMD(w, "header", header)
+	printMD(w, "trailer", trailer)
+}
+
+func printMD(w io.Writer, kind string, md metadata.MD) {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range md[k] {
+			if strings.HasSuffix(k, "-bin") {
+				v = base64.StdEncoding.EncodeToString([]byte(v))
+			}
+			fmt.Fprintf(w, "< %s %s: %s\n", kind, k, v)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	greetergrpc "github.com/LewisJAllan/greeter/listeners/grpc"
+)
+
+// greeter echoes the request metadata in its header and fails for "nobody" with a retry delay in the details.
+type greeter struct {
+	schemas.UnimplementedGreeterServer
+}
+
+func (greeter) SayHello(ctx context.Context, req *schemas.HelloRequest) (*schemas.HelloReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", strings.Join(md.Get("x-request-id"), ","), "echo-bin", "\x01"))
+	_ = grpc.SetTrailer(ctx, metadata.Pairs("x-trailer", "done"))
+
+	if req.GetName() == "nobody" {
+		st, err := status.New(codes.InvalidArgument, "no one to greet").WithDetails(durationpb.New(time.Second))
+		if err != nil {
+			return nil, err
+		}
+		return nil, st.Err()
+	}
+
+	greeting := "Hello " + req.GetName()
+	if locale := md.Get(greetergrpc.LocaleMetadataKey); len(locale) > 0 {
+		greeting += " (" + locale[0] + ")"
+	}
+	if auth := md.Get("authorization"); len(auth) > 0 {
+		greeting += " as " + auth[0]
+	}
+	return &schemas.HelloReply{Message: greeting}, nil
+}
+
+func serve(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	schemas.RegisterGreeterServer(s, greeter{})
+	go func() { _ = s.Serve(l) }()
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+func TestRun(t *testing.T) {
+	addr := serve(t)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "text",
+			args:       []string{"Ann"},
+			wantStdout: "Hello Ann\n",
+		},
+		{
+			name:       "locale and token",
+			args:       []string{"-locale", "fr", "-token", "secret", "Ann"},
+			wantStdout: "Hello Ann (fr) as Bearer secret\n",
+		},
+		{
+			name:       "verbose",
+			args:       []string{"-v", "-H", "X-Request-ID: 42", "Ann"},
+			wantStdout: "Hello Ann\n",
+			wantStderr: "< header content-type: application/grpc\n< header echo-bin: AQ==\n< header x-request-id: 42\n< trailer x-trailer: done\n",
+		},
+		{
+			name:     "error",
+			args:     []string{"nobody"},
+			wantCode: 1,
+			wantStderr: "< header content-type: application/grpc\n< header echo-bin: AQ==\n< header x-request-id: \n" +
+				"< trailer grpc-status-details-bin: CAMSD25vIG9uZSB0byBncmVldBoyCix0eXBlLmdvb2dsZWFwaXMuY29tL2dvb2dsZS5wcm90b2J1Zi5EdXJhdGlvbhICCAE=\n" +
+				"< trailer x-trailer: done\n" +
+				"error: code = InvalidArgument desc = no one to greet\n" +
+				"  detail google.protobuf.Duration: \"1s\"\n",
+		},
+		{
+			name:       "usage",
+			args:       []string{"Ann", "Bob"},
+			wantCode:   2,
+			wantStderr: "exactly one name is required\n",
+		},
+		{
+			name:       "unknown output",
+			args:       []string{"-o", "yaml", "Ann"},
+			wantCode:   2,
+			wantStderr: "unknown output format \"yaml\"\n",
+		},
+		{
+			name:       "invalid header",
+			args:       []string{"-H", "nocolon", "Ann"},
+			wantCode:   2,
+			wantStderr: "invalid value \"nocolon\" for flag -H: header \"nocolon\" must be formatted as key: value\n",
+		},
+		{
+			name:       "cert without key",
+			args:       []string{"-cert", "c.pem", "Ann"},
+			wantCode:   2,
+			wantStderr: "-cert and -key must be set together\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"-addr", addr}, tt.args...), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("run() = %d, want %d (stderr %q)", code, tt.wantCode, stderr.String())
+			}
+			if stdout.String() != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			// usage errors print the flag defaults first
+			if !strings.HasSuffix(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to end with %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	addr := serve(t)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     jsonOutput
+	}{
+		{
+			name: "reply",
+			args: []string{"Ann"},
+			want: jsonOutput{Message: "Hello Ann"},
+		},
+		{
+			name: "verbose",
+			args: []string{"-v", "-H", "x-request-id: 42", "Ann"},
+			want: jsonOutput{
+				Message:  "Hello Ann",
+				Headers:  map[string][]string{"content-type": {"application/grpc"}, "x-request-id": {"42"}, "echo-bin": {"\x01"}},
+				Trailers: map[string][]string{"x-trailer": {"done"}},
+			},
+		},
+		{
+			name:     "error",
+			args:     []string{"nobody"},
+			wantCode: 1,
+			want: jsonOutput{
+				Error: &statusOutput{
+					Code:    "InvalidArgument",
+					Message: "no one to greet",
+					Details: []statusDetail{{Type: "google.protobuf.Duration", Value: json.RawMessage(`"1s"`)}},
+				},
+				Headers:  map[string][]string{"content-type": {"application/grpc"}, "x-request-id": {""}, "echo-bin": {"\x01"}},
+				Trailers: map[string][]string{"x-trailer": {"done"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(append([]string{"-addr", addr, "-o", "json"}, tt.args...), &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("run() = %d, want %d (stderr %q)", code, tt.wantCode, stderr.String())
+			}
+
+			var got jsonOutput
+			if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+				t.Fatalf("stdout %q is not JSON: %v", stdout.String(), err)
+			}
+			// the status is checked decoded rather than as the trailer carrying it
+			delete(got.Trailers, "grpc-status-details-bin")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stdout = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunProtoJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-addr", serve(t), "-o", "protojson", "Ann"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d, want 0 (stderr %q)", code, stderr.String())
+	}
+
+	// protojson varies its whitespace from build to build
+	var got bytes.Buffer
+	if err := json.Compact(&got, stdout.Bytes()); err != nil {
+		t.Fatalf("stdout %q is not JSON: %v", stdout.String(), err)
+	}
+	if want := `{"message":"Hello Ann"}`; got.String() != want {
+		t.Errorf("stdout = %s, want %s", got.String(), want)
+	}
+}
+
+func TestNewStatusDetail(t *testing.T) {
+	known, err := anypb.New(durationpb.New(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := &anypb.Any{TypeUrl: "type.googleapis.com/example.Unknown", Value: []byte{1, 2}}
+
+	if got := newStatusDetail(known); got.Type != "google.protobuf.Duration" || string(got.Value) != `"60s"` || got.Raw != "" {
+		t.Errorf("newStatusDetail(Duration) = %+v, want the value decoded", got)
+	}
+	if got := newStatusDetail(unknown); got.Type != "example.Unknown" || got.Value != nil || got.Raw != "AQI=" {
+		t.Errorf("newStatusDetail(Unknown) = %+v, want the raw value", got)
+	}
+}
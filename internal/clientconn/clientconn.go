@@ -0,0 +1,133 @@
+// Package clientconn holds the connection flags shared by the commands calling a running greeter, and dials with
+// them.
+package clientconn
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// Headers collects repeated -H flags, as the key and value pairs metadata.Pairs takes.
+type Headers []string
+
+func (h *Headers) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *Headers) Set(v string) error {
+	key, value, ok := strings.Cut(v, ":")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("header %q must be formatted as key: value", v)
+	}
+	*h = append(*h, strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value))
+	return nil
+}
+
+type Options struct {
+	Addr       string
+	TLS        bool
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+	SkipVerify bool
+	Headers    Headers
+	// Token is sent as a bearer token in the authorization metadata.
+	Token string
+}
+
+// RegisterFlags adds the flags setting o to fs, defaulting the address to addr.
+func (o *Options) RegisterFlags(fs *flag.FlagSet, addr string) {
+	fs.StringVar(&o.Addr, "addr", addr, "address of the greeter gRPC server")
+	fs.BoolVar(&o.TLS, "tls", false, "connect with TLS")
+	fs.StringVar(&o.CAFile, "ca", "", "PEM file of the CA to verify the server with, implies -tls")
+	fs.StringVar(&o.CertFile, "cert", "", "PEM client certificate for mutual TLS, implies -tls")
+	fs.StringVar(&o.KeyFile, "key", "", "PEM client key for mutual TLS")
+	fs.StringVar(&o.ServerName, "server-name", "", "server name to verify instead of the host in -addr")
+	fs.BoolVar(&o.SkipVerify, "insecure-skip-verify", false, "do not verify the server certificate")
+	fs.Var(&o.Headers, "H", "request metadata as \"key: value\", may be repeated")
+	fs.StringVar(&o.Token, "token", "", "bearer token to authenticate with")
+}
+
+// Check reports the flags set inconsistently.
+func (o Options) Check() error {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return errors.New("-cert and -key must be set together")
+	}
+	return nil
+}
+
+// Dial creates a client connection to the server, with TLS when any of the TLS flags is set.
+func (o Options) Dial() (*grpc.ClientConn, error) {
+	if err := o.Check(); err != nil {
+		return nil, err
+	}
+	creds, err := o.transportCredentials()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.NewClient(o.Addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client: %w", err)
+	}
+	return conn, nil
+}
+
+// Metadata returns the metadata to send with every call: the headers, and the token.
+func (o Options) Metadata() metadata.MD {
+	md := metadata.Pairs(o.Headers...)
+	if o.Token != "" {
+		md.Set("authorization", "Bearer "+o.Token)
+	}
+	return md
+}
+
+// OutgoingContext returns a copy of ctx sending Metadata.
+func (o Options) OutgoingContext(ctx context.Context) context.Context {
+	return metadata.NewOutgoingContext(ctx, o.Metadata())
+}
+
+func (o Options) transportCredentials() (credentials.TransportCredentials, error) {
+	if !o.TLS && o.CAFile == "" && o.CertFile == "" && !o.SkipVerify {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.SkipVerify,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsCfg), nil
+}
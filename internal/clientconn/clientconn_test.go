@@ -0,0 +1,122 @@
+package clientconn
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHeadersSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Headers
+		wantErr bool
+	}{
+		{name: "header", value: "X-Request-Id: 42", want: Headers{"x-request-id", "42"}},
+		{name: "colon in value", value: "x-when:12:00", want: Headers{"x-when", "12:00"}},
+		{name: "empty value", value: "x-empty:", want: Headers{"x-empty", ""}},
+		{name: "no colon", value: "x-request-id", wantErr: true},
+		{name: "no key", value: " : 42", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h Headers
+			err := h.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(h, tt.want) {
+				t.Errorf("Set() = %q, want %q", h, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantMD   metadata.MD
+		wantErr  string
+		wantAddr string
+	}{
+		{name: "defaults", wantMD: metadata.MD{}, wantAddr: "localhost:50051"},
+		{
+			name:     "headers and token",
+			args:     []string{"-addr", "greeter:443", "-H", "x-api-key: gk_1", "-H", "X-Request-Id: 42", "-token", "t"},
+			wantMD:   metadata.MD{"x-api-key": {"gk_1"}, "x-request-id": {"42"}, "authorization": {"Bearer t"}},
+			wantAddr: "greeter:443",
+		},
+		{name: "cert without key", args: []string{"-cert", "c.pem"}, wantErr: "-cert and -key must be set together"},
+		{name: "key without cert", args: []string{"-key", "k.pem"}, wantErr: "-cert and -key must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o Options
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			o.RegisterFlags(fs, "localhost:50051")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := o.Check()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := o.Dial(); err == nil {
+					t.Errorf("Dial() error = nil, want %q", tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if o.Addr != tt.wantAddr {
+				t.Errorf("Addr = %q, want %q", o.Addr, tt.wantAddr)
+			}
+			if got := o.Metadata(); !reflect.DeepEqual(got, tt.wantMD) {
+				t.Errorf("Metadata() = %v, want %v", got, tt.wantMD)
+			}
+		})
+	}
+}
+
+func TestOptionsTransportCredentials(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("no certificates here"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		o            Options
+		wantSecurity string
+		wantErr      bool
+	}{
+		{name: "plain text", wantSecurity: "insecure"},
+		{name: "tls", o: Options{TLS: true}, wantSecurity: "tls"},
+		{name: "ca without certificates", o: Options{CAFile: empty}, wantErr: true},
+		{name: "skip verify implies tls", o: Options{SkipVerify: true}, wantSecurity: "tls"},
+		{name: "missing ca", o: Options{CAFile: filepath.Join(dir, "missing.pem")}, wantErr: true},
+		{name: "missing certificate", o: Options{CertFile: filepath.Join(dir, "c.pem"), KeyFile: filepath.Join(dir, "k.pem")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := tt.o.transportCredentials()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("transportCredentials() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && creds.Info().SecurityProtocol != tt.wantSecurity {
+				t.Errorf("transportCredentials() protocol = %q, want %q", creds.Info().SecurityProtocol, tt.wantSecurity)
+			}
+		})
+	}
+}
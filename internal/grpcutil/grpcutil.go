@@ -0,0 +1,48 @@
+// Package grpcutil holds the small helpers shared by the interceptors of the greeter.
+package grpcutil
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// Redacted replaces the values of credential metadata in logs and files.
+const Redacted = "REDACTED"
+
+// credentialKeys are the metadata keys carrying credentials.
+var credentialKeys = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"x-api-key":     true,
+}
+
+// IsCredential reports whether the metadata key carries credentials, whose values must never reach logs or files.
+func IsCredential(key string) bool {
+	return credentialKeys[strings.ToLower(key)]
+}
+
+// WithContext returns ss with its context replaced by ctx, for stream interceptors passing values on to the handler.
+func WithContext(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &contextStream{ServerStream: ss, ctx: ctx}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// SplitMethod splits a full method name into its service and method names, the grpc_service and grpc_method labels
+// of the go-grpc-prometheus metrics.
+func SplitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", "unknown"
+	}
+	return service, method
+}
@@ -0,0 +1,140 @@
+// Package ipfilter allows or denies peers of the listeners by IP address, both when they connect and on every
+// request, from a configuration file reloaded when it changes.
+package ipfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// Config is read from JSON:
+//
+//	{
+//	  "allow": ["10.0.0.0/8", "192.168.1.7"],
+//	  "deny": ["10.1.2.0/24"],
+//	  "trustedProxies": ["10.0.0.2"]
+//	}
+//
+// Entries are CIDR ranges or single addresses.  Denied addresses are blocked even when allowed, and when allow is not
+// empty every address it does not cover is blocked too.  Requests from trusted proxies are filtered on the client
+// address they forward in X-Forwarded-For instead of the proxy address.
+type Config struct {
+	Allow          []string `json:"allow"`
+	Deny           []string `json:"deny"`
+	TrustedProxies []string `json:"trustedProxies"`
+}
+
+// rules is a parsed Config.
+type rules struct {
+	allow   []netip.Prefix
+	deny    []netip.Prefix
+	trusted []netip.Prefix
+}
+
+func loadRules(path string) (*rules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ipfilter: unable to read config: %w", err)
+	}
+
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("ipfilter: invalid config %s: %w", path, err)
+	}
+
+	var (
+		r    rules
+		errs []error
+	)
+	r.allow, errs = parsePrefixes("allow", c.Allow, errs)
+	r.deny, errs = parsePrefixes("deny", c.Deny, errs)
+	r.trusted, errs = parsePrefixes("trustedProxies", c.TrustedProxies, errs)
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("ipfilter: invalid config %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+func parsePrefixes(field string, entries []string, errs []error) ([]netip.Prefix, []error) {
+	out := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		if !strings.Contains(e, "/") {
+			addr, err := netip.ParseAddr(e)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", field, err))
+				continue
+			}
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		p, err := netip.ParsePrefix(e)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+			continue
+		}
+		out = append(out, p.Masked())
+	}
+	return out, errs
+}
+
+// allowed reports whether addr may call, with the rule deciding when it may not.
+func (r *rules) allowed(addr netip.Addr) (bool, string) {
+	addr = addr.Unmap()
+	for _, p := range r.deny {
+		if p.Contains(addr) {
+			return false, "deny " + p.String()
+		}
+	}
+	if len(r.allow) == 0 {
+		return true, ""
+	}
+	for _, p := range r.allow {
+		if p.Contains(addr) {
+			return true, ""
+		}
+	}
+	return false, "not in allow list"
+}
+
+func (r *rules) trustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range r.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddr returns the address of the client of a request from peer, following the X-Forwarded-For values through
+// the trusted proxies.  The header is read from the right as clients can prepend anything to it.
+func (r *rules) clientAddr(peer netip.Addr, forwardedFor []string) netip.Addr {
+	client := peer.Unmap()
+	if !r.trustedProxy(client) {
+		return client
+	}
+
+	var hops []string
+	for _, v := range forwardedFor {
+		for _, hop := range strings.Split(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			// an unparseable hop cannot be trusted further, the last proxy is held responsible
+			return client
+		}
+		client = addr.Unmap()
+		if !r.trustedProxy(client) {
+			return client
+		}
+	}
+	return client
+}
@@ -0,0 +1,171 @@
+package ipfilter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+)
+
+// Filter is an app.Runner keeping the rules in step with the configuration file, which is polled.  A changed file
+// that fails to load leaves the previous rules in use until it is fixed.
+type Filter struct {
+	// ctx logs the connections blocked before any request context exists
+	ctx      context.Context
+	path     string
+	interval time.Duration
+
+	rules atomic.Pointer[rules]
+	stamp string
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewFilter loads the configuration at path, failing when it is not valid, and polls it every interval once started.
+func NewFilter(ctx context.Context, path string, interval time.Duration) (*Filter, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("ipfilter: reload interval must be positive, got %s", interval)
+	}
+	f := &Filter{
+		ctx:      ctx,
+		path:     path,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	f.stamp = f.fileStamp()
+	r, err := loadRules(path)
+	if err != nil {
+		return nil, err
+	}
+	f.rules.Store(r)
+	return f, nil
+}
+
+func (f *Filter) Start(ctx context.Context) error {
+	t := time.NewTicker(f.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return nil
+		case <-t.C:
+			f.check(ctx)
+		}
+	}
+}
+
+func (f *Filter) Stop(context.Context) error {
+	f.stopOnce.Do(func() { close(f.stop) })
+	return nil
+}
+
+func (f *Filter) Name() string {
+	return "ip-filter"
+}
+
+// check reloads the configuration when it changed since the last attempt.
+func (f *Filter) check(ctx context.Context) {
+	stamp := f.fileStamp()
+	if stamp == f.stamp {
+		return
+	}
+	f.stamp = stamp
+
+	r, err := loadRules(f.path)
+	if err != nil {
+		zaphelper.Error(ctx, "ip filter reload failed, keeping the rules in use", zap.Error(err))
+		return
+	}
+	f.rules.Store(r)
+	zaphelper.Info(ctx, "ip filter reloaded",
+		zap.Int("allow", len(r.allow)),
+		zap.Int("deny", len(r.deny)),
+		zap.Int("trusted_proxies", len(r.trusted)))
+}
+
+func (f *Filter) fileStamp() string {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return "missing"
+	}
+	return fmt.Sprintf("%d:%d", fi.Size(), fi.ModTime().UnixNano())
+}
+
+// allowConn reports whether a connection from remote may go on.  Connections from trusted proxies are let through,
+// their requests are filtered on the client address they forward.
+func (f *Filter) allowConn(listener string, remote net.Addr) bool {
+	addr, ok := addrOf(remote)
+	if !ok {
+		return true
+	}
+	r := f.rules.Load()
+	if r.trustedProxy(addr) {
+		return true
+	}
+
+	allowed, rule := r.allowed(addr)
+	if !allowed {
+		zaphelper.Warn(f.ctx, "blocked connection",
+			zap.String("listener", listener),
+			zap.String("peer", remote.String()),
+			zap.String("rule", rule))
+	}
+	return allowed
+}
+
+// allowRequest returns the client address of a request from remote, reporting whether it may go on and logging it
+// when it may not.
+func (f *Filter) allowRequest(ctx context.Context, remote net.Addr, forwardedFor []string, fields ...zap.Field) (netip.Addr, bool) {
+	peer, ok := addrOf(remote)
+	if !ok {
+		return netip.Addr{}, true
+	}
+	r := f.rules.Load()
+	client := r.clientAddr(peer, forwardedFor)
+
+	allowed, rule := r.allowed(client)
+	if !allowed {
+		zaphelper.Warn(ctx, "blocked request", append(fields,
+			zap.String("peer", remote.String()),
+			zap.String("client_ip", client.String()),
+			zap.Strings("forwarded_for", forwardedFor),
+			zap.String("rule", rule),
+		)...)
+	}
+	return client, allowed
+}
+
+func addrOf(a net.Addr) (netip.Addr, bool) {
+	if a == nil {
+		return netip.Addr{}, false
+	}
+	ap, err := netip.ParseAddrPort(a.String())
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ap.Addr(), true
+}
+
+type clientIPKey struct{}
+
+// ClientIPFromContext returns the address of the client of a request, through the trusted proxies, reporting false
+// when the request was not filtered.
+func ClientIPFromContext(ctx context.Context) (netip.Addr, bool) {
+	a, ok := ctx.Value(clientIPKey{}).(netip.Addr)
+	return a, ok
+}
+
+// WithClientIP returns a copy of ctx carrying addr.
+func WithClientIP(ctx context.Context, addr netip.Addr) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, addr)
+}
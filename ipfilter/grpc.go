@@ -0,0 +1,97 @@
+package ipfilter
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+// ForwardedForMetadataKey is the metadata trusted proxies forward the client address in.
+const ForwardedForMetadataKey = "x-forwarded-for"
+
+var errBlocked = errors.New("ipfilter: address blocked")
+
+// Credentials wraps the transport credentials of a gRPC server to close the connections of blocked peers before
+// their handshake.
+func (f *Filter) Credentials(creds credentials.TransportCredentials) credentials.TransportCredentials {
+	return &filteredCredentials{TransportCredentials: creds, filter: f}
+}
+
+type filteredCredentials struct {
+	credentials.TransportCredentials
+	filter *Filter
+}
+
+func (c *filteredCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if !c.filter.allowConn("grpc", conn.RemoteAddr()) {
+		return nil, nil, errBlocked
+	}
+	return c.TransportCredentials.ServerHandshake(conn)
+}
+
+func (c *filteredCredentials) Clone() credentials.TransportCredentials {
+	return &filteredCredentials{TransportCredentials: c.TransportCredentials.Clone(), filter: c.filter}
+}
+
+func (f *Filter) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := f.filterCall(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (f *Filter) StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := f.filterCall(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, grpcutil.WithContext(ss, ctx))
+}
+
+func (f *Filter) filterCall(ctx context.Context, method string) (context.Context, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	client, allowed := f.allowRequest(ctx, p.Addr, md.Get(ForwardedForMetadataKey),
+		zap.String("method", method),
+		zap.Strings("user_agent", md.Get("user-agent")),
+	)
+	if !allowed {
+		return ctx, status.Error(codes.PermissionDenied, "address not allowed")
+	}
+	if client.IsValid() {
+		ctx = WithClientIP(ctx, client)
+	}
+	return ctx, nil
+}
+
+// PeerIP returns the client address found by the filter, which follows trusted proxies, or the host of the peer
+// address when the request was not filtered.
+func PeerIP(ctx context.Context) string {
+	if addr, ok := ClientIPFromContext(ctx); ok {
+		return addr.String()
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
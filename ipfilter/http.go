@@ -0,0 +1,57 @@
+package ipfilter
+
+import (
+	"net"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// Middleware refuses the requests of blocked clients with 403 Forbidden.
+func (f *Filter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client, allowed := f.allowRequest(r.Context(), remote, r.Header.Values("X-Forwarded-For"),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("user_agent", r.UserAgent()),
+		)
+		if !allowed {
+			http.Error(w, "address not allowed", http.StatusForbidden)
+			return
+		}
+		if client.IsValid() {
+			r = r.WithContext(WithClientIP(r.Context(), client))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Listener closes the connections of blocked peers as they are accepted.
+func (f *Filter) Listener(name string, l net.Listener) net.Listener {
+	return &filteredListener{Listener: l, filter: f, name: name}
+}
+
+type filteredListener struct {
+	net.Listener
+	filter *Filter
+	name   string
+}
+
+func (l *filteredListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.filter.allowConn(l.name, conn.RemoteAddr()) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,160 @@
+package ipfilter
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/peer"
+)
+
+func writeRules(t *testing.T, config string) *rules {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ipfilter.json")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := loadRules(path)
+	if err != nil {
+		t.Fatalf("loadRules() error = %v", err)
+	}
+	return r
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{name: "invalid address", config: `{"allow":["10.0.0.256"]}`},
+		{name: "invalid prefix", config: `{"deny":["10.0.0.0/33"]}`},
+		{name: "invalid proxy", config: `{"trustedProxies":["proxy"]}`},
+		{name: "invalid json", config: `{"allow":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ipfilter.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadRules(path); err == nil {
+				t.Error("loadRules() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestRulesAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		addr   string
+		want   bool
+	}{
+		{name: "no rules", config: `{}`, addr: "203.0.113.1", want: true},
+		{name: "allowed range", config: `{"allow":["10.0.0.0/8"]}`, addr: "10.1.2.3", want: true},
+		{name: "outside allowed range", config: `{"allow":["10.0.0.0/8"]}`, addr: "192.168.0.1", want: false},
+		{name: "allowed address", config: `{"allow":["192.168.1.7"]}`, addr: "192.168.1.7", want: true},
+		{name: "next to allowed address", config: `{"allow":["192.168.1.7"]}`, addr: "192.168.1.8", want: false},
+		{name: "denied", config: `{"deny":["10.1.2.0/24"]}`, addr: "10.1.2.3", want: false},
+		{name: "outside denied range", config: `{"deny":["10.1.2.0/24"]}`, addr: "10.1.3.3", want: true},
+		{name: "deny before allow", config: `{"allow":["10.0.0.0/8"],"deny":["10.1.2.0/24"]}`, addr: "10.1.2.3", want: false},
+		{name: "allowed next to denied", config: `{"allow":["10.0.0.0/8"],"deny":["10.1.2.0/24"]}`, addr: "10.1.3.3", want: true},
+		{name: "unmasked prefix", config: `{"deny":["10.1.2.3/24"]}`, addr: "10.1.2.200", want: false},
+		{name: "ipv4 mapped", config: `{"deny":["10.1.2.0/24"]}`, addr: "::ffff:10.1.2.3", want: false},
+		{name: "ipv6", config: `{"allow":["2001:db8::/32"]}`, addr: "2001:db8::1", want: true},
+		{name: "ipv6 outside", config: `{"allow":["2001:db8::/32"]}`, addr: "2001:db9::1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := writeRules(t, tt.config)
+			if got, rule := r.allowed(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("allowed(%s) = %v (%s), want %v", tt.addr, got, rule, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesClientAddr(t *testing.T) {
+	r := writeRules(t, `{"trustedProxies":["10.0.0.2", "10.0.1.0/24"]}`)
+
+	tests := []struct {
+		name         string
+		peer         string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "direct", peer: "203.0.113.1", want: "203.0.113.1"},
+		{name: "untrusted peer forwarding", peer: "203.0.113.1", forwardedFor: []string{"198.51.100.7"}, want: "203.0.113.1"},
+		{name: "trusted proxy", peer: "10.0.0.2", forwardedFor: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "chain of proxies", peer: "10.0.0.2", forwardedFor: []string{"198.51.100.7, 10.0.1.5"}, want: "198.51.100.7"},
+		{name: "chain over headers", peer: "10.0.0.2", forwardedFor: []string{"198.51.100.7", "10.0.1.5"}, want: "198.51.100.7"},
+		{name: "spoofed first hop", peer: "10.0.0.2", forwardedFor: []string{"1.2.3.4, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "unparseable hop", peer: "10.0.0.2", forwardedFor: []string{"198.51.100.7, unknown"}, want: "10.0.0.2"},
+		{name: "only proxies", peer: "10.0.0.2", forwardedFor: []string{"10.0.1.5"}, want: "10.0.1.5"},
+		{name: "no header", peer: "10.0.0.2", want: "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.clientAddr(netip.MustParseAddr(tt.peer), tt.forwardedFor); got.String() != tt.want {
+				t.Errorf("clientAddr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeerIP(t *testing.T) {
+	fromPeer := func(a net.Addr) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: a})
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "client ip", ctx: WithClientIP(fromPeer(&net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1}), netip.MustParseAddr("198.51.100.7")), want: "198.51.100.7"},
+		{name: "peer", ctx: fromPeer(&net.TCPAddr{IP: net.IPv4(203, 0, 113, 1), Port: 4242}), want: "203.0.113.1"},
+		{name: "ipv6 peer", ctx: fromPeer(&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4242}), want: "2001:db8::1"},
+		{name: "peer without port", ctx: fromPeer(&net.UnixAddr{Name: "/run/greeter.sock", Net: "unix"}), want: "/run/greeter.sock"},
+		{name: "no peer", ctx: context.Background(), want: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PeerIP(tt.ctx); got != tt.want {
+				t.Errorf("PeerIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipfilter.json")
+	if err := os.WriteFile(path, []byte(`{"deny":["127.0.0.1"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		interval time.Duration
+		wantErr  bool
+	}{
+		{name: "valid", path: path, interval: time.Second},
+		{name: "no interval", path: path, wantErr: true},
+		{name: "negative interval", path: path, interval: -time.Second, wantErr: true},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.json"), interval: time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFilter(context.Background(), tt.path, tt.interval)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFilter() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
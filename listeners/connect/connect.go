@@ -0,0 +1,29 @@
+package connect
+
+import (
+	"context"
+	"net/http"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+type Service interface {
+	Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+}
+
+// Client exposes the Greeter service over the Connect protocol.  It is registered on the HTTP listener.
+type Client struct {
+	service Service
+}
+
+func NewClient(service Service) *Client {
+	return &Client{service: service}
+}
+
+func (c *Client) Register(mux *http.ServeMux) {
+	newRequest := func() *schemas.HelloRequest { return &schemas.HelloRequest{} }
+
+	mux.Handle(schemas.Greeter_SayHello_FullMethodName, unary(newRequest, c.SayHello))
+}
@@ -0,0 +1,303 @@
+package connect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+type respondFunc func(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+
+func (f respondFunc) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	return f(ctx, request)
+}
+
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	NewClient(respondFunc(func(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+		switch request.OriginalMessage {
+		case "":
+			return service.RespondResponse{}, status.Error(codes.InvalidArgument, "name is required")
+		case "deadline":
+			if _, ok := ctx.Deadline(); !ok {
+				return service.RespondResponse{}, status.Error(codes.FailedPrecondition, "no deadline")
+			}
+		}
+		return service.RespondResponse{ResponseMessage: "Hello " + request.OriginalMessage}, nil
+	})).Register(mux)
+	return mux
+}
+
+func gzipped(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUnary(t *testing.T) {
+	ann, err := proto.Marshal(&schemas.HelloRequest{Name: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := schemas.Greeter_SayHello_FullMethodName
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		header      http.Header
+		body        []byte
+		wantStatus  int
+		wantMessage string
+		// wantCode is the code of the Connect error envelope, when one is expected
+		wantCode string
+	}{
+		{
+			name:        "json",
+			method:      http.MethodPost,
+			header:      http.Header{"Content-Type": {"application/json"}},
+			body:        []byte(`{"name":"Ann"}`),
+			wantStatus:  http.StatusOK,
+			wantMessage: "Hello Ann",
+		},
+		{
+			name:        "json with charset",
+			method:      http.MethodPost,
+			header:      http.Header{"Content-Type": {"application/json; charset=utf-8"}, "Connect-Protocol-Version": {"1"}},
+			body:        []byte(`{"name":"Ann"}`),
+			wantStatus:  http.StatusOK,
+			wantMessage: "Hello Ann",
+		},
+		{
+			name:        "proto",
+			method:      http.MethodPost,
+			header:      http.Header{"Content-Type": {"application/proto"}},
+			body:        ann,
+			wantStatus:  http.StatusOK,
+			wantMessage: "Hello Ann",
+		},
+		{
+			name:        "gzip request",
+			method:      http.MethodPost,
+			header:      http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+			body:        gzipped(t, []byte(`{"name":"Ann"}`)),
+			wantStatus:  http.StatusOK,
+			wantMessage: "Hello Ann",
+		},
+		{
+			name:        "gzip response",
+			method:      http.MethodPost,
+			header:      http.Header{"Content-Type": {"application/json"}, "Accept-Encoding": {"br, gzip;q=0.5"}},
+			body:        []byte(`{"name":"Ann"}`),
+			wantStatus:  http.StatusOK,
+			wantMessage: "Hello Ann",
+		},
+		{
+			name:        "get json",
+			method:      http.MethodGet,
+			target:      "?encoding=json&message=" + url.QueryEscape(`{"name":"Ann"}`),
+			wantStatus:  http.StatusOK,
+			wantMessage: "Hello Ann",
+		},
+		{
+			name:        "get base64 proto",
+			method:      http.MethodGet,
+			target:      "?encoding=proto&base64=1&message=" + base64.URLEncoding.EncodeToString(ann),
+			wantStatus:  http.StatusOK,
+			wantMessage: "Hello Ann",
+		},
+		{
+			name:        "timeout",
+			method:      http.MethodPost,
+			header:      http.Header{"Content-Type": {"application/json"}, "Connect-Timeout-Ms": {"1000"}},
+			body:        []byte(`{"name":"deadline"}`),
+			wantStatus:  http.StatusOK,
+			wantMessage: "Hello deadline",
+		},
+		{
+			name:       "unsupported content-type",
+			method:     http.MethodPost,
+			header:     http.Header{"Content-Type": {"text/plain"}},
+			body:       []byte(`Ann`),
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "unsupported protocol version",
+			method:     http.MethodPost,
+			header:     http.Header{"Content-Type": {"application/json"}, "Connect-Protocol-Version": {"2"}},
+			body:       []byte(`{"name":"Ann"}`),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_argument",
+		},
+		{
+			name:       "unsupported method",
+			method:     http.MethodPut,
+			header:     http.Header{"Content-Type": {"application/json"}},
+			wantStatus: http.StatusNotImplemented,
+			wantCode:   "unimplemented",
+		},
+		{
+			name:       "unsupported compression",
+			method:     http.MethodPost,
+			header:     http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"br"}},
+			body:       []byte(`{"name":"Ann"}`),
+			wantStatus: http.StatusNotImplemented,
+			wantCode:   "unimplemented",
+		},
+		{
+			name:       "invalid gzip",
+			method:     http.MethodPost,
+			header:     http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+			body:       []byte(`{"name":"Ann"}`),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_argument",
+		},
+		{
+			name:       "invalid json",
+			method:     http.MethodPost,
+			header:     http.Header{"Content-Type": {"application/json"}},
+			body:       []byte(`{"name":`),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_argument",
+		},
+		{
+			name:       "too large",
+			method:     http.MethodPost,
+			header:     http.Header{"Content-Type": {"application/proto"}},
+			body:       bytes.Repeat([]byte{0}, maxBodyBytes+1),
+			wantStatus: http.StatusTooManyRequests,
+			wantCode:   "resource_exhausted",
+		},
+		{
+			name:       "get without encoding",
+			method:     http.MethodGet,
+			target:     "?message=" + url.QueryEscape(`{"name":"Ann"}`),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_argument",
+		},
+		{
+			name:       "invalid timeout",
+			method:     http.MethodPost,
+			header:     http.Header{"Content-Type": {"application/json"}, "Connect-Timeout-Ms": {"-1"}},
+			body:       []byte(`{"name":"Ann"}`),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_argument",
+		},
+		{
+			name:       "status of the service",
+			method:     http.MethodPost,
+			header:     http.Header{"Content-Type": {"application/json"}},
+			body:       []byte(`{}`),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_argument",
+		},
+	}
+	mux := newMux()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, path+tt.target, bytes.NewReader(tt.body))
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, tt.wantStatus, w.Body)
+			}
+
+			switch {
+			case tt.wantCode != "":
+				if got := w.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("error Content-Type = %q, want application/json", got)
+				}
+				var body connectError
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("invalid error envelope %q: %v", w.Body, err)
+				}
+				if body.Code != tt.wantCode {
+					t.Errorf("error code = %q, want %q", body.Code, tt.wantCode)
+				}
+			case tt.wantMessage != "":
+				if got := readReply(t, w, tt.header.Get("Content-Type"), r.URL.Query().Get("encoding")); got != tt.wantMessage {
+					t.Errorf("message = %q, want %q", got, tt.wantMessage)
+				}
+			default:
+				if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+					t.Errorf("Content-Type = %q, want a plain HTTP response", w.Header().Get("Content-Type"))
+				}
+			}
+		})
+	}
+}
+
+// readReply decodes the HelloReply of a successful response.
+func readReply(t *testing.T, w *httptest.ResponseRecorder, contentType, encoding string) string {
+	t.Helper()
+	b := w.Body.Bytes()
+	if w.Header().Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err = io.ReadAll(gz); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var reply schemas.HelloReply
+	var err error
+	if strings.HasPrefix(contentType, "application/proto") || encoding == codecProto {
+		err = proto.Unmarshal(b, &reply)
+	} else {
+		err = unmarshaler.Unmarshal(b, &reply)
+	}
+	if err != nil {
+		t.Fatalf("invalid response %q: %v", b, err)
+	}
+	return reply.GetMessage()
+}
+
+func TestCodeName(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want string
+	}{
+		{code: codes.Canceled, want: "canceled"},
+		{code: codes.InvalidArgument, want: "invalid_argument"},
+		{code: codes.DeadlineExceeded, want: "deadline_exceeded"},
+		{code: codes.ResourceExhausted, want: "resource_exhausted"},
+		{code: codes.FailedPrecondition, want: "failed_precondition"},
+		{code: codes.Unauthenticated, want: "unauthenticated"},
+		{code: codes.Unknown, want: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			if got := codeName(tt.code); got != tt.want {
+				t.Errorf("codeName(%v) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
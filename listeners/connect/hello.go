@@ -0,0 +1,28 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func (c *Client) SayHello(ctx context.Context, request *schemas.HelloRequest) (*schemas.HelloReply, error) {
+	resp, err := c.service.Respond(ctx, service.RespondRequest{
+		OriginalMessage: request.GetName(),
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			// the service chose the status, pass it on with its message and details intact
+			return nil, err
+		}
+		return nil, fmt.Errorf("error occurred: %w", err)
+	}
+
+	return &schemas.HelloReply{
+		Message: resp.ResponseMessage,
+	}, nil
+}
@@ -0,0 +1,306 @@
+package connect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	httplistener "github.com/LewisJAllan/greeter/listeners/http"
+)
+
+const (
+	// maxBodyBytes matches the default maximum receive message size of grpc.Server.
+	maxBodyBytes = 4 << 20
+
+	codecJSON  = "json"
+	codecProto = "proto"
+)
+
+var (
+	marshaler   = protojson.MarshalOptions{}
+	unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// unary serves a Connect unary RPC: POST with an application/json or application/proto body, or GET with the message
+// in the query string.
+func unary[Req, Res proto.Message](newRequest func() Req, call func(context.Context, Req) (Res, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get("Connect-Protocol-Version"); v != "" && v != "1" {
+			writeError(r.Context(), w, status.Errorf(codes.InvalidArgument, "unsupported connect protocol version %q", v))
+			return
+		}
+
+		var (
+			codec string
+			body  []byte
+			err   error
+		)
+		switch r.Method {
+		case http.MethodPost:
+			contentType := r.Header.Get("Content-Type")
+			var ok bool
+			if codec, ok = codecFromContentType(contentType); !ok {
+				// not a Connect request, so it is refused as plain HTTP rather than with an error envelope
+				w.Header().Set("Accept-Post", "application/json, application/proto")
+				http.Error(w, "unsupported content-type "+contentType, http.StatusUnsupportedMediaType)
+				return
+			}
+			body, err = readBody(w, r)
+		case http.MethodGet:
+			codec, body, err = readQuery(r.URL.Query())
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeError(r.Context(), w, status.Errorf(codes.Unimplemented, "method %s is not supported", r.Method))
+			return
+		}
+		if err != nil {
+			writeError(r.Context(), w, err)
+			return
+		}
+
+		request := newRequest()
+		if err := unmarshal(codec, body, request); err != nil {
+			writeError(r.Context(), w, err)
+			return
+		}
+
+		ctx, cancel, err := withTimeout(r)
+		if err != nil {
+			writeError(r.Context(), w, err)
+			return
+		}
+		defer cancel()
+
+		response, err := call(ctx, request)
+		if err != nil {
+			writeError(r.Context(), w, err)
+			return
+		}
+
+		b, err := marshal(codec, response)
+		if err != nil {
+			writeError(r.Context(), w, status.Errorf(codes.Internal, "unable to encode response: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/"+codec)
+		if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			_, err = gz.Write(b)
+			if err == nil {
+				err = gz.Close()
+			}
+		} else {
+			_, err = w.Write(b)
+		}
+		if err != nil {
+			zaphelper.Debug(r.Context(), "unable to write connect response", zap.Error(err))
+		}
+	})
+}
+
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	return decompress(r.Header.Get("Content-Encoding"), http.MaxBytesReader(w, r.Body, maxBodyBytes))
+}
+
+// readQuery decodes the message of a GET request, sent as the message, encoding, base64 and compression parameters.
+func readQuery(query url.Values) (string, []byte, error) {
+	codec := query.Get("encoding")
+	if codec != codecJSON && codec != codecProto {
+		return "", nil, status.Errorf(codes.InvalidArgument, "unsupported encoding %q", codec)
+	}
+
+	message := []byte(query.Get("message"))
+	if query.Get("base64") == "1" {
+		decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(string(message), "="))
+		if err != nil {
+			return "", nil, status.Errorf(codes.InvalidArgument, "message is not valid base64: %v", err)
+		}
+		message = decoded
+	}
+
+	body, err := decompress(query.Get("compression"), io.LimitReader(bytes.NewReader(message), maxBodyBytes))
+	if err != nil {
+		return "", nil, err
+	}
+	return codec, body, nil
+}
+
+func codecFromContentType(contentType string) (string, bool) {
+	switch strings.TrimSpace(strings.Split(contentType, ";")[0]) {
+	case "application/json":
+		return codecJSON, true
+	case "application/proto":
+		return codecProto, true
+	default:
+		return "", false
+	}
+}
+
+func decompress(encoding string, r io.Reader) ([]byte, error) {
+	switch encoding {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid gzip body: %v", err)
+		}
+		defer gz.Close()
+		r = io.LimitReader(gz, maxBodyBytes)
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported compression %q, accepted: gzip, identity", encoding)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, status.Errorf(codes.ResourceExhausted, "request body exceeds %d bytes", maxErr.Limit)
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "unable to read request body: %v", err)
+	}
+	return b, nil
+}
+
+func acceptsGzip(acceptEncoding string) bool {
+	for _, encoding := range strings.Split(acceptEncoding, ",") {
+		if strings.TrimSpace(strings.Split(encoding, ";")[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+func unmarshal(codec string, b []byte, m proto.Message) error {
+	if len(b) == 0 {
+		return nil
+	}
+
+	var err error
+	if codec == codecJSON {
+		err = unmarshaler.Unmarshal(b, m)
+	} else {
+		err = proto.Unmarshal(b, m)
+	}
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "unable to decode request: %v", err)
+	}
+	return nil
+}
+
+func marshal(codec string, m proto.Message) ([]byte, error) {
+	if codec == codecJSON {
+		return marshaler.Marshal(m)
+	}
+	return proto.Marshal(m)
+}
+
+// withTimeout applies the Connect-Timeout-Ms header to the request context.
+func withTimeout(r *http.Request) (context.Context, context.CancelFunc, error) {
+	v := r.Header.Get("Connect-Timeout-Ms")
+	if v == "" {
+		ctx, cancel := context.WithCancel(httplistener.IncomingMetadata(r))
+		return ctx, cancel, nil
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 || len(v) > 10 {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid Connect-Timeout-Ms %q", v)
+	}
+
+	ctx, cancel := context.WithTimeout(httplistener.IncomingMetadata(r), time.Duration(ms)*time.Millisecond)
+	return ctx, cancel, nil
+}
+
+type errorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type connectError struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message,omitempty"`
+	Details []errorDetail `json:"details,omitempty"`
+}
+
+// writeError writes err as a Connect error envelope.
+func writeError(ctx context.Context, w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	if st.Code() == codes.Internal || st.Code() == codes.Unknown {
+		zaphelper.Error(ctx, "connect request failed", zap.Error(err))
+	}
+
+	body := connectError{
+		Code:    codeName(st.Code()),
+		Message: st.Message(),
+	}
+	for _, detail := range st.Proto().GetDetails() {
+		body.Details = append(body.Details, errorDetail{
+			Type:  strings.TrimPrefix(detail.GetTypeUrl(), "type.googleapis.com/"),
+			Value: base64.RawStdEncoding.EncodeToString(detail.GetValue()),
+		})
+	}
+
+	w.Header().Del("Content-Encoding")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httplistener.HTTPStatusFromCode(st.Code()))
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		zaphelper.Debug(ctx, "unable to write connect error", zap.Error(err))
+	}
+}
+
+// codeName returns the Connect name of a gRPC status code, e.g. invalid_argument.
+func codeName(code codes.Code) string {
+	switch code {
+	case codes.Canceled:
+		return "canceled"
+	case codes.InvalidArgument:
+		return "invalid_argument"
+	case codes.DeadlineExceeded:
+		return "deadline_exceeded"
+	case codes.NotFound:
+		return "not_found"
+	case codes.AlreadyExists:
+		return "already_exists"
+	case codes.PermissionDenied:
+		return "permission_denied"
+	case codes.ResourceExhausted:
+		return "resource_exhausted"
+	case codes.FailedPrecondition:
+		return "failed_precondition"
+	case codes.Aborted:
+		return "aborted"
+	case codes.OutOfRange:
+		return "out_of_range"
+	case codes.Unimplemented:
+		return "unimplemented"
+	case codes.Internal:
+		return "internal"
+	case codes.Unavailable:
+		return "unavailable"
+	case codes.DataLoss:
+		return "data_loss"
+	case codes.Unauthenticated:
+		return "unauthenticated"
+	default:
+		return "unknown"
+	}
+}
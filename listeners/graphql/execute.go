@@ -0,0 +1,280 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// object is a value of a GraphQL object type.  field returns a scalar, an object, a slice of objects or nil, and
+// errUnknownField when the type has no such field.
+type object interface {
+	typeName() string
+	field(ctx context.Context, name string, args map[string]any) (any, error)
+}
+
+var errUnknownField = errors.New("unknown field")
+
+type gqlError struct {
+	Message   string     `json:"message"`
+	Locations []location `json:"locations,omitempty"`
+	Path      []any      `json:"path,omitempty"`
+}
+
+type result struct {
+	Data   any        `json:"data,omitempty"`
+	Errors []gqlError `json:"errors,omitempty"`
+}
+
+// orderedMap keeps the fields of a response in the order they were selected, as the spec requires.
+type orderedMap []entry
+
+type entry struct {
+	key   string
+	value any
+}
+
+func (m orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, e := range m {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(e.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+type executor struct {
+	doc       *document
+	variables map[string]any
+	errors    []gqlError
+}
+
+// newExecutor checks the variables provided for op against its definitions and applies default values.
+func newExecutor(doc *document, op *operation, provided map[string]any) (*executor, error) {
+	variables := map[string]any{}
+	for _, def := range op.variables {
+		v, ok := provided[def.name]
+		if !ok && def.defaultValue != nil {
+			v, ok = resolve(def.defaultValue, nil), true
+		}
+		if def.nonNull && (!ok || v == nil) {
+			return nil, fmt.Errorf("variable \"$%s\" of non-null type must not be null", def.name)
+		}
+		if ok {
+			variables[def.name] = v
+		}
+	}
+
+	return &executor{doc: doc, variables: variables}, nil
+}
+
+func (e *executor) execute(ctx context.Context, root object, op *operation) result {
+	data := e.selectionSet(ctx, root, op.selection, nil)
+	return result{Data: data, Errors: e.errors}
+}
+
+func (e *executor) fail(f *field, path []any, err error) {
+	e.errors = append(e.errors, gqlError{
+		Message:   err.Error(),
+		Locations: []location{f.loc},
+		Path:      append([]any(nil), path...),
+	})
+}
+
+func (e *executor) selectionSet(ctx context.Context, obj object, selections []selection, path []any) orderedMap {
+	fields := e.collectFields(obj.typeName(), selections, nil, map[string]bool{})
+
+	out := make(orderedMap, 0, len(fields))
+	for _, f := range fields {
+		key := f.responseKey()
+		fieldPath := append(append([]any(nil), path...), key)
+
+		if f.name == "__typename" {
+			out = append(out, entry{key, obj.typeName()})
+			continue
+		}
+
+		v, err := obj.field(ctx, f.name, e.arguments(f.arguments))
+		switch {
+		case errors.Is(err, errUnknownField):
+			e.fail(f, fieldPath, fmt.Errorf("Cannot query field %q on type %q.", f.name, obj.typeName()))
+			v = nil
+		case err != nil:
+			e.fail(f, fieldPath, err)
+			v = nil
+		default:
+			v = e.complete(ctx, f, v, fieldPath)
+		}
+		out = append(out, entry{key, v})
+	}
+	return out
+}
+
+// collectFields flattens fragments into the list of fields to resolve, merging fields with the same response key.
+func (e *executor) collectFields(typeName string, selections []selection, fields []*field, visited map[string]bool) []*field {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *field:
+			if !e.included(s.directives) {
+				continue
+			}
+			merged := false
+			for i, existing := range fields {
+				if existing.responseKey() == s.responseKey() {
+					combined := *existing
+					combined.selection = append(append([]selection(nil), existing.selection...), s.selection...)
+					fields[i] = &combined
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				fields = append(fields, s)
+			}
+		case *fragmentSpread:
+			if !e.included(s.directives) || visited[s.name] {
+				continue
+			}
+			visited[s.name] = true
+			frag, ok := e.doc.fragments[s.name]
+			if !ok || frag.typeCondition != typeName {
+				continue
+			}
+			fields = e.collectFields(typeName, frag.selection, fields, visited)
+		case *inlineFragment:
+			if !e.included(s.directives) || (s.typeCondition != "" && s.typeCondition != typeName) {
+				continue
+			}
+			fields = e.collectFields(typeName, s.selection, fields, visited)
+		}
+	}
+	return fields
+}
+
+// included applies the skip and include directives.
+func (e *executor) included(directives []directive) bool {
+	for _, d := range directives {
+		cond, _ := resolve(d.arguments["if"], e.variables).(bool)
+		switch d.name {
+		case "skip":
+			if cond {
+				return false
+			}
+		case "include":
+			if !cond {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (e *executor) arguments(args map[string]value) map[string]any {
+	out := make(map[string]any, len(args))
+	for name, v := range args {
+		if ref, ok := v.(variable); ok {
+			if _, provided := e.variables[string(ref)]; !provided {
+				continue
+			}
+		}
+		out[name] = resolve(v, e.variables)
+	}
+	return out
+}
+
+func (e *executor) complete(ctx context.Context, f *field, v any, path []any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case object:
+		if f.selection == nil {
+			e.fail(f, path, fmt.Errorf("Field %q of type %q must have a selection of subfields.", f.name, v.typeName()))
+			return nil
+		}
+		return e.selectionSet(ctx, v, f.selection, path)
+	case []object:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = e.complete(ctx, f, item, append(path, i))
+		}
+		return list
+	default:
+		if f.selection != nil {
+			e.fail(f, path, fmt.Errorf("Field %q must not have a selection since it is a scalar.", f.name))
+			return nil
+		}
+		return v
+	}
+}
+
+// resolve replaces variable references in v and converts enums to strings.
+func resolve(v value, variables map[string]any) any {
+	switch v := v.(type) {
+	case variable:
+		return variables[string(v)]
+	case enumValue:
+		return string(v)
+	case []value:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = resolve(item, variables)
+		}
+		return out
+	case map[string]value:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = resolve(item, variables)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func stringArg(args map[string]any, name string, required bool) (string, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		if required {
+			return "", fmt.Errorf("argument %q of type \"String!\" is required", name)
+		}
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("argument %q must be a String", name)
+	}
+	return s, nil
+}
+
+func intArg(args map[string]any, name string) (int, bool, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return 0, false, nil
+	}
+	switch n := v.(type) {
+	case int64:
+		return int(n), true, nil
+	case float64:
+		// variables decoded from JSON arrive as float64
+		if n == float64(int(n)) {
+			return int(n), true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("argument %q must be an Int", name)
+}
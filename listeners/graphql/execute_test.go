@@ -0,0 +1,276 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// testService greets anyone but "nobody" and holds three greetings in its history.
+type testService struct{}
+
+func (testService) Respond(_ context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	if request.OriginalMessage == "nobody" {
+		return service.RespondResponse{}, errors.New("no one to greet")
+	}
+	return service.RespondResponse{
+		ResponseMessage: "Hello " + request.OriginalMessage,
+		Greeting: service.Greeting{
+			ID:       7,
+			Name:     request.OriginalMessage,
+			Locale:   request.Locale,
+			Style:    request.Style,
+			Message:  "Hello " + request.OriginalMessage,
+			IssuedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}, nil
+}
+
+func (testService) Greetings(_ context.Context, request service.GreetingsRequest) (service.GreetingsResponse, error) {
+	var greetings []service.Greeting
+	for id := request.After + 1; id <= 3; id++ {
+		if request.Limit > 0 && len(greetings) == request.Limit {
+			return service.GreetingsResponse{Greetings: greetings, HasMore: true, Total: 3}, nil
+		}
+		greetings = append(greetings, service.Greeting{ID: id, Name: "name"})
+	}
+	return service.GreetingsResponse{Greetings: greetings, Total: 3}, nil
+}
+
+// run executes query as the Client does, returning the JSON encoded result or the error refusing it.
+func run(t *testing.T, query string, variables map[string]any) (string, error) {
+	t.Helper()
+	doc, err := parse(query)
+	if err != nil {
+		return "", err
+	}
+	op, err := selectOperation(doc, "")
+	if err != nil {
+		return "", err
+	}
+	exec, err := newExecutor(doc, op, variables)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(exec.execute(context.Background(), queryRoot{service: testService{}}, op))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), nil
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		want      string
+	}{
+		{
+			name:  "fields in selection order",
+			query: `{ hello(name: "Ann") { message name id __typename } }`,
+			want:  `{"data":{"hello":{"message":"Hello Ann","name":"Ann","id":"7","__typename":"Greeting"}}}`,
+		},
+		{
+			name:  "aliases",
+			query: `{ a: hello(name: "Ann") { id } b: hello(name: "Bob") { greeted: name } }`,
+			want:  `{"data":{"a":{"id":"7"},"b":{"greeted":"Bob"}}}`,
+		},
+		{
+			name:  "optional fields",
+			query: `{ hello(name: "Ann", style: "formal") { locale style issuedAt } }`,
+			want:  `{"data":{"hello":{"locale":null,"style":"formal","issuedAt":"2024-01-02T03:04:05Z"}}}`,
+		},
+		{
+			name:      "variables",
+			query:     `query ($name: String!) { hello(name: $name) { name } }`,
+			variables: map[string]any{"name": "Ann"},
+			want:      `{"data":{"hello":{"name":"Ann"}}}`,
+		},
+		{
+			name:  "default variable value",
+			query: `query ($name: String = "Ann") { hello(name: $name) { name } }`,
+			want:  `{"data":{"hello":{"name":"Ann"}}}`,
+		},
+		{
+			name:  "merged fields",
+			query: `{ hello(name: "Ann") { id } hello(name: "Ann") { name } }`,
+			want:  `{"data":{"hello":{"id":"7","name":"Ann"}}}`,
+		},
+		{
+			name:  "fragments",
+			query: `{ hello(name: "Ann") { ...Names ... on Greeting { id } ... on Query { message } } } fragment Names on Greeting { name }`,
+			want:  `{"data":{"hello":{"name":"Ann","id":"7"}}}`,
+		},
+		{
+			name:  "fragment on another type",
+			query: `{ hello(name: "Ann") { id ...Page } } fragment Page on PageInfo { hasNextPage }`,
+			want:  `{"data":{"hello":{"id":"7"}}}`,
+		},
+		{
+			name:  "fragment cycle",
+			query: `{ hello(name: "Ann") { ...A } } fragment A on Greeting { id ...B } fragment B on Greeting { name ...A }`,
+			want:  `{"data":{"hello":{"id":"7","name":"Ann"}}}`,
+		},
+		{
+			name:  "skip and include",
+			query: `{ hello(name: "Ann") { id @skip(if: true) name @include(if: false) message @skip(if: false) @include(if: true) } }`,
+			want:  `{"data":{"hello":{"message":"Hello Ann"}}}`,
+		},
+		{
+			name:      "directives from variables",
+			query:     `query ($brief: Boolean!) { hello(name: "Ann") { id ... @skip(if: $brief) { name message } } }`,
+			variables: map[string]any{"brief": true},
+			want:      `{"data":{"hello":{"id":"7"}}}`,
+		},
+		{
+			name:  "pages",
+			query: `{ greetings(first: 2) { edges { cursor node { id } } pageInfo { hasNextPage endCursor } totalCount } }`,
+			want:  `{"data":{"greetings":{"edges":[{"cursor":"Z3JlZXRpbmc6MQ==","node":{"id":"1"}},{"cursor":"Z3JlZXRpbmc6Mg==","node":{"id":"2"}}],"pageInfo":{"hasNextPage":true,"endCursor":"Z3JlZXRpbmc6Mg=="},"totalCount":3}}}`,
+		},
+		{
+			name:  "after a cursor",
+			query: `{ greetings(after: "Z3JlZXRpbmc6Mg==") { edges { node { id } } pageInfo { hasNextPage } } }`,
+			want:  `{"data":{"greetings":{"edges":[{"node":{"id":"3"}}],"pageInfo":{"hasNextPage":false}}}}`,
+		},
+		{
+			name:  "empty page",
+			query: `{ greetings(after: "Z3JlZXRpbmc6Mw==") { edges { cursor } pageInfo { endCursor } } }`,
+			want:  `{"data":{"greetings":{"edges":[],"pageInfo":{"endCursor":null}}}}`,
+		},
+		{
+			name:      "int from a JSON variable",
+			query:     `query ($n: Int) { greetings(first: $n) { totalCount edges { cursor } } }`,
+			variables: map[string]any{"n": 1.0},
+			want:      `{"data":{"greetings":{"totalCount":3,"edges":[{"cursor":"Z3JlZXRpbmc6MQ=="}]}}}`,
+		},
+		{
+			name:  "unset variable left out",
+			query: `query ($n: Int) { greetings(first: $n) { totalCount } }`,
+			want:  `{"data":{"greetings":{"totalCount":3}}}`,
+		},
+		{
+			name:      "fractional int",
+			query:     `query ($n: Int) { greetings(first: $n) { totalCount } }`,
+			variables: map[string]any{"n": 1.5},
+			want:      `{"data":{"greetings":null},"errors":[{"message":"argument \"first\" must be an Int","locations":[{"line":1,"column":19}],"path":["greetings"]}]}`,
+		},
+		{
+			name:  "string for an int",
+			query: `{ greetings(first: "two") { totalCount } }`,
+			want:  `{"data":{"greetings":null},"errors":[{"message":"argument \"first\" must be an Int","locations":[{"line":1,"column":3}],"path":["greetings"]}]}`,
+		},
+		{
+			name:  "missing required argument",
+			query: `{ hello { id } }`,
+			want:  `{"data":{"hello":null},"errors":[{"message":"argument \"name\" of type \"String!\" is required","locations":[{"line":1,"column":3}],"path":["hello"]}]}`,
+		},
+		{
+			name:  "invalid cursor",
+			query: `{ greetings(after: "nope") { totalCount } }`,
+			want:  `{"data":{"greetings":null},"errors":[{"message":"invalid cursor \"nope\"","locations":[{"line":1,"column":3}],"path":["greetings"]}]}`,
+		},
+		{
+			name:  "service error",
+			query: `{ hello(name: "nobody") { id } }`,
+			want:  `{"data":{"hello":null},"errors":[{"message":"error occurred: no one to greet","locations":[{"line":1,"column":3}],"path":["hello"]}]}`,
+		},
+		{
+			name:  "unknown field",
+			query: `{ hello(name: "Ann") { id age } }`,
+			want:  `{"data":{"hello":{"id":"7","age":null}},"errors":[{"message":"Cannot query field \"age\" on type \"Greeting\".","locations":[{"line":1,"column":27}],"path":["hello","age"]}]}`,
+		},
+		{
+			name:  "unknown field in a list",
+			query: `{ greetings(first: 1) { edges { node { age } } } }`,
+			want:  `{"data":{"greetings":{"edges":[{"node":{"age":null}}]}},"errors":[{"message":"Cannot query field \"age\" on type \"Greeting\".","locations":[{"line":1,"column":40}],"path":["greetings","edges",0,"node","age"]}]}`,
+		},
+		{
+			name:  "object without a selection",
+			query: `{ hello(name: "Ann") }`,
+			want:  `{"data":{"hello":null},"errors":[{"message":"Field \"hello\" of type \"Greeting\" must have a selection of subfields.","locations":[{"line":1,"column":3}],"path":["hello"]}]}`,
+		},
+		{
+			name:  "scalar with a selection",
+			query: `{ hello(name: "Ann") { id { value } } }`,
+			want:  `{"data":{"hello":{"id":null}},"errors":[{"message":"Field \"id\" must not have a selection since it is a scalar.","locations":[{"line":1,"column":24}],"path":["hello","id"]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.query, tt.variables)
+			if err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("run() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRefused(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		wantErr   string
+	}{
+		{
+			name:    "missing non-null variable",
+			query:   `query ($name: String!) { hello(name: $name) { id } }`,
+			wantErr: `variable "$name" of non-null type must not be null`,
+		},
+		{
+			name:      "null non-null variable",
+			query:     `query ($name: String!) { hello(name: $name) { id } }`,
+			variables: map[string]any{"name": nil},
+			wantErr:   `variable "$name" of non-null type must not be null`,
+		},
+		{
+			name:    "several operations",
+			query:   `query A { hello(name: "Ann") { id } } query B { hello(name: "Bob") { id } }`,
+			wantErr: "operationName is required when the document contains several operations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := run(t, tt.query, tt.variables); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("run() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIntArg(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    int
+		wantSet bool
+		wantErr bool
+	}{
+		{name: "missing", args: map[string]any{}},
+		{name: "null", args: map[string]any{"n": nil}},
+		{name: "literal", args: map[string]any{"n": int64(3)}, want: 3, wantSet: true},
+		{name: "whole float", args: map[string]any{"n": 3.0}, want: 3, wantSet: true},
+		{name: "fractional float", args: map[string]any{"n": 3.5}, wantErr: true},
+		{name: "string", args: map[string]any{"n": "3"}, wantErr: true},
+		{name: "bool", args: map[string]any{"n": true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, set, err := intArg(tt.args, "n")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("intArg() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want || set != tt.wantSet {
+				t.Errorf("intArg() = %d, %v, want %d, %v", got, set, tt.want, tt.wantSet)
+			}
+		})
+	}
+}
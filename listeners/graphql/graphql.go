@@ -0,0 +1,246 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+
+	httplistener "github.com/LewisJAllan/greeter/listeners/http"
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// maxBodyBytes caps the size of GraphQL request bodies.
+const maxBodyBytes = 1 << 20
+
+type Service interface {
+	Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+	Greetings(ctx context.Context, request service.GreetingsRequest) (service.GreetingsResponse, error)
+}
+
+// Client serves the greeter over GraphQL at /graphql.  Queries are answered as JSON, subscriptions are streamed as
+// Server-Sent Events.
+type Client struct {
+	service       Service
+	subscriptions *Subscriptions
+}
+
+func NewClient(service Service, subscriptions *Subscriptions) *Client {
+	return &Client{
+		service:       service,
+		subscriptions: subscriptions,
+	}
+}
+
+func (c *Client) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /graphql", c.serveGraphQL)
+	mux.HandleFunc("GET /graphql", c.serveGraphQL)
+	mux.HandleFunc("GET /graphql/schema.graphql", serveSchema)
+}
+
+// Subscriptions feeds greetings issued by the service to open GraphQL subscriptions.  It implements
+// service.GreetingObserver.
+type Subscriptions struct {
+	mu          sync.Mutex
+	subscribers map[chan service.Greeting]struct{}
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewSubscriptions() *Subscriptions {
+	return &Subscriptions{
+		subscribers: map[chan service.Greeting]struct{}{},
+		closed:      make(chan struct{}),
+	}
+}
+
+// Observe implements service.GreetingObserver.  Subscribers that are not keeping up miss greetings rather than
+// blocking the service.
+func (s *Subscriptions) Observe(greeting service.Greeting) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- greeting:
+		default:
+		}
+	}
+}
+
+// Close ends every open subscription.  Pass it to the HTTP listener's WithOnShutdown.
+func (s *Subscriptions) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+}
+
+func (s *Subscriptions) subscribe() chan service.Greeting {
+	ch := make(chan service.Greeting, 16)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch
+}
+
+func (s *Subscriptions) unsubscribe(ch chan service.Greeting) {
+	s.mu.Lock()
+	delete(s.subscribers, ch)
+	s.mu.Unlock()
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func serveSchema(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, Schema)
+}
+
+func (c *Client) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeRequest(w, r)
+	if err != nil {
+		writeResult(r.Context(), w, http.StatusBadRequest, result{Errors: []gqlError{{Message: err.Error()}}})
+		return
+	}
+
+	doc, err := parse(req.Query)
+	if err != nil {
+		writeResult(r.Context(), w, http.StatusBadRequest, result{Errors: []gqlError{{Message: err.Error()}}})
+		return
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		writeResult(r.Context(), w, http.StatusBadRequest, result{Errors: []gqlError{{Message: err.Error()}}})
+		return
+	}
+
+	exec, err := newExecutor(doc, op, req.Variables)
+	if err != nil {
+		writeResult(r.Context(), w, http.StatusBadRequest, result{Errors: []gqlError{{Message: err.Error()}}})
+		return
+	}
+
+	switch {
+	case op.kind == "query":
+		// the headers are passed on as metadata, like the HTTP and Connect listeners do, for the API key among others
+		writeResult(r.Context(), w, http.StatusOK, exec.execute(httplistener.IncomingMetadata(r), queryRoot{service: c.service}, op))
+	case op.kind == "subscription" && r.Method == http.MethodPost:
+		c.subscribe(w, r, exec, op)
+	case op.kind == "subscription":
+		writeResult(r.Context(), w, http.StatusMethodNotAllowed, result{Errors: []gqlError{{Message: "subscriptions must use POST"}}})
+	default:
+		writeResult(r.Context(), w, http.StatusBadRequest, result{Errors: []gqlError{{Message: fmt.Sprintf("%s operations are not supported", op.kind)}}})
+	}
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request) (request, error) {
+	var req request
+
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return req, fmt.Errorf("variables are not valid JSON: %w", err)
+			}
+		}
+	} else {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+			return req, fmt.Errorf("request body is not valid JSON: %w", err)
+		}
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		return req, errors.New("query is required")
+	}
+	return req, nil
+}
+
+func selectOperation(doc *document, name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("operationName is required when the document contains several operations")
+		}
+		return doc.operations[0], nil
+	}
+
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// subscribe streams a result for each greeting issued, following the distinct connections mode of the GraphQL over
+// Server-Sent Events protocol.
+func (c *Client) subscribe(w http.ResponseWriter, r *http.Request, exec *executor, op *operation) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeResult(r.Context(), w, http.StatusNotAcceptable, result{Errors: []gqlError{{Message: "subscriptions are streamed as text/event-stream"}}})
+		return
+	}
+
+	ch := c.subscriptions.subscribe()
+	defer c.subscriptions.unsubscribe(ch)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.subscriptions.closed:
+			_, _ = io.WriteString(w, "event: complete\ndata:\n\n")
+			flusher.Flush()
+			return
+		case g := <-ch:
+			// each event is executed with a fresh error list
+			exec.errors = nil
+			b, err := json.Marshal(exec.execute(r.Context(), event{greeting: g}, op))
+			if err != nil {
+				zaphelper.Error(r.Context(), "unable to encode graphql event", zap.Error(err))
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeResult(ctx context.Context, w http.ResponseWriter, code int, res result) {
+	b, err := json.Marshal(res)
+	if err != nil {
+		zaphelper.Error(ctx, "unable to encode graphql result", zap.Error(err))
+		code = http.StatusInternalServerError
+		b = []byte(`{"errors":[{"message":"internal error"}]}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(b); err != nil {
+		zaphelper.Debug(ctx, "unable to write graphql result", zap.Error(err))
+	}
+}
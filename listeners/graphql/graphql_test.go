@@ -0,0 +1,167 @@
+package graphql
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func TestClientServeGraphQL(t *testing.T) {
+	mux := http.NewServeMux()
+	NewClient(testService{}, NewSubscriptions()).Register(mux)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "post",
+			method:     http.MethodPost,
+			body:       `{"query":"query ($name: String!) { hello(name: $name) { name } }","variables":{"name":"Ann"}}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"hello":{"name":"Ann"}}}`,
+		},
+		{
+			name:       "get",
+			method:     http.MethodGet,
+			target:     "?query=" + url.QueryEscape(`query ($name: String!) { hello(name: $name) { name } }`) + "&variables=" + url.QueryEscape(`{"name":"Ann"}`),
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"hello":{"name":"Ann"}}}`,
+		},
+		{
+			name:       "operation name",
+			method:     http.MethodPost,
+			body:       `{"query":"query A { a: hello(name: \"Ann\") { name } } query B { b: hello(name: \"Bob\") { name } }","operationName":"B"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"b":{"name":"Bob"}}}`,
+		},
+		{
+			name:       "unknown operation",
+			method:     http.MethodPost,
+			body:       `{"query":"query A { hello(name: \"Ann\") { name } }","operationName":"B"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"message":"unknown operation \"B\""}]}`,
+		},
+		{
+			name:       "invalid json",
+			method:     http.MethodPost,
+			body:       `{"query":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid variables",
+			method:     http.MethodGet,
+			target:     "?query=" + url.QueryEscape(`{ hello(name: "Ann") { name } }`) + "&variables=nope",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no query",
+			method:     http.MethodPost,
+			body:       `{"query":"  "}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"message":"query is required"}]}`,
+		},
+		{
+			name:       "syntax error",
+			method:     http.MethodPost,
+			body:       `{"query":"{ hello"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"message":"Syntax Error: unexpected end of document (1:8)"}]}`,
+		},
+		{
+			name:       "mutation",
+			method:     http.MethodPost,
+			body:       `{"query":"mutation { hello(name: \"Ann\") { name } }"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"message":"mutation operations are not supported"}]}`,
+		},
+		{
+			name:       "subscription over get",
+			method:     http.MethodGet,
+			target:     "?query=" + url.QueryEscape(`subscription { greetings { id } }`),
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "subscription without event stream",
+			method:     http.MethodPost,
+			body:       `{"query":"subscription { greetings { id } }"}`,
+			wantStatus: http.StatusNotAcceptable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/graphql"+tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	subscriptions := NewSubscriptions()
+	mux := http.NewServeMux()
+	NewClient(testService{}, subscriptions).Register(mux)
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	r, err := http.NewRequest(http.MethodPost, s.URL+"/graphql", strings.NewReader(`{"query":"subscription { greetings { id name age } }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// the headers are only sent once the stream has subscribed
+	subscriptions.Observe(service.Greeting{ID: 1, Name: "Ann"})
+	subscriptions.Observe(service.Greeting{ID: 2, Name: "Bob"})
+
+	var got []string
+	br := bufio.NewReader(resp.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if line = strings.TrimSuffix(line, "\n"); line != "" {
+			got = append(got, line)
+		}
+		// closed once both greetings are read, as pending greetings are dropped on close
+		if len(got) == 4 {
+			subscriptions.Close()
+		}
+	}
+
+	// every event reports its own errors rather than accumulating those of earlier ones
+	fieldErr := `"errors":[{"message":"Cannot query field \"age\" on type \"Greeting\".","locations":[{"line":1,"column":36}],"path":["greetings","age"]}]`
+	want := []string{
+		"event: next",
+		`data: {"data":{"greetings":{"id":"1","name":"Ann","age":null}},` + fieldErr + `}`,
+		"event: next",
+		`data: {"data":{"greetings":{"id":"2","name":"Bob","age":null}},` + fieldErr + `}`,
+		"event: complete",
+		"data:",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("stream =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,539 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// the subset of the GraphQL query language needed by clients of the greeter: operations, variables, aliases,
+// arguments, fragments and the skip and include directives
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind      string // query, mutation or subscription
+	name      string
+	variables []variableDefinition
+	selection []selection
+}
+
+type variableDefinition struct {
+	name         string
+	defaultValue value
+	nonNull      bool
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	selection     []selection
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment.
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  map[string]value
+	directives []directive
+	selection  []selection
+	loc        location
+}
+
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []directive
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []directive
+	selection     []selection
+}
+
+type directive struct {
+	name      string
+	arguments map[string]value
+}
+
+// value is a literal or variable reference in a document.
+type value interface{}
+
+type variable string
+
+type enumValue string
+
+type location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	loc   location
+}
+
+type syntaxError struct {
+	message string
+	loc     location
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("Syntax Error: %s (%d:%d)", e.message, e.loc.Line, e.loc.Column)
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+}
+
+func (l *lexer) loc() location {
+	return location{Line: l.line, Column: l.pos - l.lineStart + 1}
+}
+
+func (l *lexer) next() (token, error) {
+	// skip ignored tokens: whitespace, commas, comments and the byte order mark
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.pos++
+			l.line++
+			l.lineStart = l.pos
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			goto scan
+		}
+	}
+
+scan:
+	loc := l.loc()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, loc: loc}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunct, value: "...", loc: loc}, nil
+	case strings.ContainsRune("!$&():=@[]{}|", rune(c)):
+		l.pos++
+		return token{kind: tokenPunct, value: string(c), loc: loc}, nil
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], loc: loc}, nil
+	case c == '-' || isDigit(c):
+		return l.number(loc)
+	case c == '"':
+		return l.string(loc)
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return token{}, &syntaxError{message: fmt.Sprintf("unexpected character %q", r), loc: loc}
+	}
+}
+
+func (l *lexer) number(loc location) (token, error) {
+	start := l.pos
+	kind := tokenInt
+
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() {
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	digits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		digits()
+	}
+
+	return token{kind: kind, value: l.src[start:l.pos], loc: loc}, nil
+}
+
+func (l *lexer) string(loc location) (token, error) {
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		end := strings.Index(l.src[l.pos+3:], `"""`)
+		if end < 0 {
+			return token{}, &syntaxError{message: "unterminated block string", loc: loc}
+		}
+		raw := l.src[l.pos+3 : l.pos+3+end]
+		for _, c := range raw {
+			if c == '\n' {
+				l.line++
+			}
+		}
+		l.pos += 3 + end + 3
+		return token{kind: tokenString, value: strings.TrimSpace(raw), loc: loc}, nil
+	}
+
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return token{kind: tokenString, value: b.String(), loc: loc}, nil
+		case '\n':
+			return token{}, &syntaxError{message: "unterminated string", loc: loc}
+		case '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, &syntaxError{message: "unterminated string", loc: loc}
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, &syntaxError{message: "invalid unicode escape", loc: loc}
+				}
+				r, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, &syntaxError{message: "invalid unicode escape", loc: loc}
+				}
+				b.WriteRune(rune(r))
+				l.pos += 4
+			default:
+				return token{}, &syntaxError{message: fmt.Sprintf("invalid escape \\%c", esc), loc: loc}
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, &syntaxError{message: "unterminated string", loc: loc}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func parse(src string) (doc *document, err error) {
+	p := &parser{lex: &lexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	// the recursive descent below panics with a *syntaxError to avoid threading errors through every production
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(*syntaxError)
+			if !ok {
+				panic(r)
+			}
+			err = se
+		}
+	}()
+
+	doc = &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunct, "{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", selection: p.selectionSet()})
+		case p.peek(tokenName, "query"), p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			doc.operations = append(doc.operations, p.operation())
+		case p.peek(tokenName, "fragment"):
+			f := p.fragment()
+			doc.fragments[f.name] = f
+		default:
+			p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, &syntaxError{message: "document contains no operations", loc: p.tok.loc}
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) mustAdvance() {
+	if err := p.advance(); err != nil {
+		panic(err)
+	}
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) skip(kind tokenKind, value string) bool {
+	if p.peek(kind, value) {
+		p.mustAdvance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, value string) {
+	if !p.skip(kind, value) {
+		p.unexpected()
+	}
+}
+
+func (p *parser) unexpected() {
+	if p.tok.kind == tokenEOF {
+		panic(&syntaxError{message: "unexpected end of document", loc: p.tok.loc})
+	}
+	panic(&syntaxError{message: fmt.Sprintf("unexpected %q", p.tok.value), loc: p.tok.loc})
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokenName {
+		p.unexpected()
+	}
+	name := p.tok.value
+	p.mustAdvance()
+	return name
+}
+
+func (p *parser) operation() *operation {
+	op := &operation{kind: p.name()}
+	if p.tok.kind == tokenName {
+		op.name = p.name()
+	}
+
+	if p.skip(tokenPunct, "(") {
+		for !p.skip(tokenPunct, ")") {
+			p.expect(tokenPunct, "$")
+			def := variableDefinition{name: p.name()}
+			p.expect(tokenPunct, ":")
+			def.nonNull = p.typeRef()
+			if p.skip(tokenPunct, "=") {
+				def.defaultValue = p.value(true)
+			}
+			p.directives()
+			op.variables = append(op.variables, def)
+		}
+	}
+
+	p.directives()
+	op.selection = p.selectionSet()
+	return op
+}
+
+// typeRef consumes a type reference and reports whether it is non-null.
+func (p *parser) typeRef() bool {
+	if p.skip(tokenPunct, "[") {
+		p.typeRef()
+		p.expect(tokenPunct, "]")
+	} else {
+		p.name()
+	}
+	return p.skip(tokenPunct, "!")
+}
+
+func (p *parser) fragment() *fragment {
+	p.expect(tokenName, "fragment")
+	f := &fragment{name: p.name()}
+	p.expect(tokenName, "on")
+	f.typeCondition = p.name()
+	p.directives()
+	f.selection = p.selectionSet()
+	return f
+}
+
+func (p *parser) selectionSet() []selection {
+	p.expect(tokenPunct, "{")
+	if p.peek(tokenPunct, "}") {
+		// a selection set holds at least one selection
+		p.unexpected()
+	}
+
+	var selections []selection
+	for !p.skip(tokenPunct, "}") {
+		if p.skip(tokenPunct, "...") {
+			if p.tok.kind == tokenName && p.tok.value != "on" {
+				selections = append(selections, &fragmentSpread{name: p.name(), directives: p.directives()})
+				continue
+			}
+
+			inline := &inlineFragment{}
+			if p.skip(tokenName, "on") {
+				inline.typeCondition = p.name()
+			}
+			inline.directives = p.directives()
+			inline.selection = p.selectionSet()
+			selections = append(selections, inline)
+			continue
+		}
+
+		selections = append(selections, p.field())
+	}
+	return selections
+}
+
+func (p *parser) field() *field {
+	// read before p.name advances, the order operands of a composite literal are evaluated in is unspecified
+	loc := p.tok.loc
+	f := &field{loc: loc, name: p.name()}
+	if p.skip(tokenPunct, ":") {
+		f.alias, f.name = f.name, p.name()
+	}
+
+	f.arguments = p.arguments(false)
+	f.directives = p.directives()
+	if p.peek(tokenPunct, "{") {
+		f.selection = p.selectionSet()
+	}
+	return f
+}
+
+func (p *parser) arguments(constant bool) map[string]value {
+	args := map[string]value{}
+	if !p.skip(tokenPunct, "(") {
+		return args
+	}
+
+	for !p.skip(tokenPunct, ")") {
+		name := p.name()
+		p.expect(tokenPunct, ":")
+		args[name] = p.value(constant)
+	}
+	return args
+}
+
+func (p *parser) directives() []directive {
+	var directives []directive
+	for p.skip(tokenPunct, "@") {
+		directives = append(directives, directive{name: p.name(), arguments: p.arguments(false)})
+	}
+	return directives
+}
+
+func (p *parser) value(constant bool) value {
+	tok := p.tok
+
+	switch {
+	case tok.kind == tokenPunct && tok.value == "$" && !constant:
+		p.mustAdvance()
+		return variable(p.name())
+	case tok.kind == tokenInt:
+		p.mustAdvance()
+		v, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			panic(&syntaxError{message: fmt.Sprintf("invalid int %s", tok.value), loc: tok.loc})
+		}
+		return v
+	case tok.kind == tokenFloat:
+		p.mustAdvance()
+		v, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			panic(&syntaxError{message: fmt.Sprintf("invalid float %s", tok.value), loc: tok.loc})
+		}
+		return v
+	case tok.kind == tokenString:
+		p.mustAdvance()
+		return tok.value
+	case tok.kind == tokenName:
+		p.mustAdvance()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		default:
+			return enumValue(tok.value)
+		}
+	case tok.kind == tokenPunct && tok.value == "[":
+		p.mustAdvance()
+		list := []value{}
+		for !p.skip(tokenPunct, "]") {
+			list = append(list, p.value(constant))
+		}
+		return list
+	case tok.kind == tokenPunct && tok.value == "{":
+		p.mustAdvance()
+		obj := map[string]value{}
+		for !p.skip(tokenPunct, "}") {
+			name := p.name()
+			p.expect(tokenPunct, ":")
+			obj[name] = p.value(constant)
+		}
+		return obj
+	default:
+		p.unexpected()
+		return nil
+	}
+}
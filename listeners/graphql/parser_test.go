@@ -0,0 +1,128 @@
+package graphql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{name: "empty", src: "", wantErr: "document contains no operations (1:1)"},
+		{name: "only fragments", src: "fragment F on Query { hello }", wantErr: "document contains no operations"},
+		{name: "unclosed selection", src: "{ hello", wantErr: "unexpected end of document (1:8)"},
+		{name: "empty selection", src: "query {\n}", wantErr: `unexpected "}" (2:1)`},
+		{name: "unexpected character", src: "{ hello % }", wantErr: `unexpected character '%' (1:9)`},
+		{name: "unexpected keyword", src: "schema { query: Query }", wantErr: `unexpected "schema" (1:1)`},
+		{name: "unterminated string", src: `{ hello(name: "Ann) { id } }`, wantErr: "unterminated string (1:15)"},
+		{name: "newline in string", src: "{ hello(name: \"A\nnn\") { id } }", wantErr: "unterminated string"},
+		{name: "unterminated block string", src: `{ hello(name: """Ann) { id } }`, wantErr: "unterminated block string"},
+		{name: "invalid escape", src: `{ hello(name: "\q") { id } }`, wantErr: `invalid escape \q`},
+		{name: "invalid unicode escape", src: `{ hello(name: "\u12G4") { id } }`, wantErr: "invalid unicode escape"},
+		{name: "invalid float", src: `{ greetings(first: 1e) { totalCount } }`, wantErr: "invalid float 1e"},
+		{name: "int out of range", src: `{ greetings(first: 99999999999999999999) { totalCount } }`, wantErr: "invalid int"},
+		{name: "variable in default value", src: `query ($n: Int = $m) { greetings(first: $n) { totalCount } }`, wantErr: `unexpected "$"`},
+		{name: "missing argument value", src: `{ hello(name:) { id } }`, wantErr: `unexpected ")"`},
+		{name: "fragment without type condition", src: `{ ...F } fragment F { hello }`, wantErr: `unexpected "{"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.src)
+			var se *syntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("parse() error = %v, want a syntax error", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parse() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	doc, err := parse(`# the greeting
+		query Greet($name: String! = "Ann", $ids: [ID!]) @cached {
+			hello(name: $name, locale: "en\tGBé", style: """
+				formal
+			""", n: -12, f: 1.5e2, b: true, none: null, e: FORMAL, list: [1, "a"], obj: {k: $ids})
+		}`)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	if len(doc.operations) != 1 {
+		t.Fatalf("operations = %d, want 1", len(doc.operations))
+	}
+	op := doc.operations[0]
+	if op.kind != "query" || op.name != "Greet" {
+		t.Errorf("operation = %s %s, want query Greet", op.kind, op.name)
+	}
+	wantVariables := []variableDefinition{{name: "name", defaultValue: "Ann", nonNull: true}, {name: "ids"}}
+	if !reflect.DeepEqual(op.variables, wantVariables) {
+		t.Errorf("variables = %+v, want %+v", op.variables, wantVariables)
+	}
+
+	f := op.selection[0].(*field)
+	want := map[string]value{
+		"name":   variable("name"),
+		"locale": "en\tGBé",
+		"style":  "formal",
+		"n":      int64(-12),
+		"f":      150.0,
+		"b":      true,
+		"none":   nil,
+		"e":      enumValue("FORMAL"),
+		"list":   []value{int64(1), "a"},
+		"obj":    map[string]value{"k": variable("ids")},
+	}
+	if !reflect.DeepEqual(f.arguments, want) {
+		t.Errorf("arguments = %#v, want %#v", f.arguments, want)
+	}
+	if f.loc != (location{Line: 3, Column: 4}) {
+		t.Errorf("field location = %+v, want 3:4", f.loc)
+	}
+}
+
+func TestParseSelections(t *testing.T) {
+	doc, err := parse(`
+		{ greeting: hello(name: "Ann") @skip(if: false) { ...Fields ... on Greeting { id } ... @include(if: true) { name } } }
+		fragment Fields on Greeting { message }
+		subscription Live { greetings { id } }`)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+
+	if len(doc.operations) != 2 || doc.operations[0].kind != "query" || doc.operations[1].kind != "subscription" {
+		t.Fatalf("operations = %+v, want an anonymous query and a subscription", doc.operations)
+	}
+
+	f := doc.operations[0].selection[0].(*field)
+	if f.alias != "greeting" || f.name != "hello" || f.responseKey() != "greeting" {
+		t.Errorf("field = %s: %s, want greeting: hello", f.alias, f.name)
+	}
+	if len(f.directives) != 1 || f.directives[0].name != "skip" || f.directives[0].arguments["if"] != false {
+		t.Errorf("directives = %+v, want skip if false", f.directives)
+	}
+
+	if len(f.selection) != 3 {
+		t.Fatalf("selections = %d, want 3", len(f.selection))
+	}
+	if s, ok := f.selection[0].(*fragmentSpread); !ok || s.name != "Fields" {
+		t.Errorf("first selection = %#v, want a spread of Fields", f.selection[0])
+	}
+	if s, ok := f.selection[1].(*inlineFragment); !ok || s.typeCondition != "Greeting" {
+		t.Errorf("second selection = %#v, want an inline fragment on Greeting", f.selection[1])
+	}
+	if s, ok := f.selection[2].(*inlineFragment); !ok || s.typeCondition != "" || len(s.directives) != 1 {
+		t.Errorf("third selection = %#v, want an inline fragment with a directive", f.selection[2])
+	}
+
+	frag := doc.fragments["Fields"]
+	if frag == nil || frag.typeCondition != "Greeting" || len(frag.selection) != 1 {
+		t.Errorf("fragment = %+v, want Fields on Greeting", frag)
+	}
+}
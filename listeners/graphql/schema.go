@@ -0,0 +1,233 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// Schema is the SDL of the schema served by the Client.
+const Schema = `type Query {
+  "Greets name, recording the locale and style requested."
+  hello(name: String!, locale: String, style: String): Greeting!
+  "Pages through the most recent greetings, oldest first."
+  greetings(first: Int, after: String): GreetingConnection!
+}
+
+type Subscription {
+  "Emits each greeting as it is issued."
+  greetings: Greeting!
+}
+
+type Greeting {
+  id: ID!
+  name: String!
+  locale: String
+  style: String
+  message: String!
+  "RFC 3339 timestamp."
+  issuedAt: String!
+}
+
+type GreetingConnection {
+  edges: [GreetingEdge!]!
+  pageInfo: PageInfo!
+  totalCount: Int!
+}
+
+type GreetingEdge {
+  cursor: String!
+  node: Greeting!
+}
+
+type PageInfo {
+  hasNextPage: Boolean!
+  endCursor: String
+}
+`
+
+type queryRoot struct {
+	service Service
+}
+
+func (queryRoot) typeName() string { return "Query" }
+
+func (q queryRoot) field(ctx context.Context, name string, args map[string]any) (any, error) {
+	switch name {
+	case "hello":
+		return q.hello(ctx, args)
+	case "greetings":
+		return q.greetings(ctx, args)
+	default:
+		return nil, errUnknownField
+	}
+}
+
+func (q queryRoot) hello(ctx context.Context, args map[string]any) (any, error) {
+	var request service.RespondRequest
+	var err error
+	if request.OriginalMessage, err = stringArg(args, "name", true); err != nil {
+		return nil, err
+	}
+	if request.Locale, err = stringArg(args, "locale", false); err != nil {
+		return nil, err
+	}
+	if request.Style, err = stringArg(args, "style", false); err != nil {
+		return nil, err
+	}
+
+	resp, err := q.service.Respond(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred: %w", err)
+	}
+	return greeting(resp.Greeting), nil
+}
+
+func (q queryRoot) greetings(ctx context.Context, args map[string]any) (any, error) {
+	first, _, err := intArg(args, "first")
+	if err != nil {
+		return nil, err
+	}
+	after, err := stringArg(args, "after", false)
+	if err != nil {
+		return nil, err
+	}
+
+	request := service.GreetingsRequest{Limit: first}
+	if after != "" {
+		if request.After, err = decodeCursor(after); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := q.service.Greetings(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred: %w", err)
+	}
+	return connection(resp), nil
+}
+
+// event is the root value a subscription selection set is resolved against for each greeting.
+type event struct {
+	greeting service.Greeting
+}
+
+func (event) typeName() string { return "Subscription" }
+
+func (e event) field(_ context.Context, name string, _ map[string]any) (any, error) {
+	if name == "greetings" {
+		return greeting(e.greeting), nil
+	}
+	return nil, errUnknownField
+}
+
+type greeting service.Greeting
+
+func (greeting) typeName() string { return "Greeting" }
+
+func (g greeting) field(_ context.Context, name string, _ map[string]any) (any, error) {
+	switch name {
+	case "id":
+		return strconv.FormatUint(g.ID, 10), nil
+	case "name":
+		return g.Name, nil
+	case "locale":
+		return optional(g.Locale), nil
+	case "style":
+		return optional(g.Style), nil
+	case "message":
+		return g.Message, nil
+	case "issuedAt":
+		return g.IssuedAt.Format(time.RFC3339Nano), nil
+	default:
+		return nil, errUnknownField
+	}
+}
+
+func optional(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+type connection service.GreetingsResponse
+
+func (connection) typeName() string { return "GreetingConnection" }
+
+func (c connection) field(_ context.Context, name string, _ map[string]any) (any, error) {
+	switch name {
+	case "edges":
+		edges := make([]object, len(c.Greetings))
+		for i, g := range c.Greetings {
+			edges[i] = edge(g)
+		}
+		return edges, nil
+	case "pageInfo":
+		info := pageInfo{hasNextPage: c.HasMore}
+		if len(c.Greetings) > 0 {
+			info.endCursor = encodeCursor(c.Greetings[len(c.Greetings)-1].ID)
+		}
+		return info, nil
+	case "totalCount":
+		return c.Total, nil
+	default:
+		return nil, errUnknownField
+	}
+}
+
+type edge service.Greeting
+
+func (edge) typeName() string { return "GreetingEdge" }
+
+func (e edge) field(_ context.Context, name string, _ map[string]any) (any, error) {
+	switch name {
+	case "cursor":
+		return encodeCursor(e.ID), nil
+	case "node":
+		return greeting(e), nil
+	default:
+		return nil, errUnknownField
+	}
+}
+
+type pageInfo struct {
+	hasNextPage bool
+	endCursor   string
+}
+
+func (pageInfo) typeName() string { return "PageInfo" }
+
+func (p pageInfo) field(_ context.Context, name string, _ map[string]any) (any, error) {
+	switch name {
+	case "hasNextPage":
+		return p.hasNextPage, nil
+	case "endCursor":
+		return optional(p.endCursor), nil
+	default:
+		return nil, errUnknownField
+	}
+}
+
+const cursorPrefix = "greeting:"
+
+func encodeCursor(id uint64) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatUint(id, 10)))
+}
+
+func decodeCursor(cursor string) (uint64, error) {
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), cursorPrefix) {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(string(b), cursorPrefix), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return id, nil
+}
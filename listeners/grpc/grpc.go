@@ -14,8 +14,8 @@ type Service interface {
 }
 
 type Client struct {
+	schemas.UnimplementedGreeterServer
 	service Service
-	greeter schemas.UnimplementedGreeterServer
 }
 
 func NewClient(service Service) *Client {
@@ -23,5 +23,5 @@ func NewClient(service Service) *Client {
 }
 
 func (c *Client) Register(server *grpc.Server) {
-	schemas.RegisterGreeterServer(server, c.greeter)
+	schemas.RegisterGreeterServer(server, c)
 }
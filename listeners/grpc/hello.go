@@ -5,16 +5,25 @@ import (
 	"fmt"
 
 	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
-	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/LewisJAllan/greeter/service"
 )
 
-func (c *Client) SayHello(ctx context.Context, request *schemas.HelloRequest, _ ...grpc.CallOption) (*schemas.HelloReply, error) {
+// LocaleMetadataKey is the request metadata carrying the locale to greet in, since HelloRequest has no field for it.
+const LocaleMetadataKey = "greeter-locale"
+
+func (c *Client) SayHello(ctx context.Context, request *schemas.HelloRequest) (*schemas.HelloReply, error) {
 	resp, err := c.service.Respond(ctx, service.RespondRequest{
 		OriginalMessage: request.GetName(),
+		Locale:          locale(ctx),
 	})
 	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			// the service chose the status, pass it on with its message and details intact
+			return nil, err
+		}
 		return nil, fmt.Errorf("error occurred: %w", err)
 	}
 
@@ -22,3 +31,10 @@ func (c *Client) SayHello(ctx context.Context, request *schemas.HelloRequest, _
 		Message: resp.ResponseMessage,
 	}, nil
 }
+
+func locale(ctx context.Context) string {
+	if v := metadata.ValueFromIncomingContext(ctx, LocaleMetadataKey); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package grpcweb
+
+import (
+	"net/http"
+	"strings"
+)
+
+var (
+	defaultAllowedHeaders = []string{"Content-Type", "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout", "Authorization"}
+	exposedHeaders        = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"}
+)
+
+// cors answers preflight requests and sets the headers browsers need to read gRPC-Web responses cross-origin.
+type cors struct {
+	anyOrigin      bool
+	origins        map[string]bool
+	allowedHeaders string
+}
+
+func newCORS(origins, headers []string) cors {
+	c := cors{
+		origins:        map[string]bool{},
+		allowedHeaders: strings.Join(append(defaultAllowedHeaders, headers...), ", "),
+	}
+	for _, origin := range origins {
+		if origin == "*" {
+			c.anyOrigin = true
+		}
+		c.origins[origin] = true
+	}
+	return c
+}
+
+func (c cors) allowed(origin string) bool {
+	return c.anyOrigin || c.origins[origin]
+}
+
+func (c cors) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+
+		if !c.allowed(origin) {
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.origins[origin] {
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Credentials", "true")
+		} else {
+			// origins only matched by the wildcard may call, but browsers won't send them the credentials of the user
+			h.Set("Access-Control-Allow-Origin", "*")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", c.allowedHeaders)
+			h.Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		h.Set("Access-Control-Expose-Headers", strings.Join(exposedHeaders, ", "))
+		next.ServeHTTP(w, r)
+	})
+}
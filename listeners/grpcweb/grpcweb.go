@@ -0,0 +1,165 @@
+package grpcweb
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	grpclistener "github.com/LewisJAllan/application-helper/listeners/grpc"
+	"google.golang.org/grpc"
+)
+
+type ListenConfig interface {
+	Listen(ctx context.Context, net, addr string) (net.Listener, error)
+}
+
+type options struct {
+	addr           string
+	serverOptions  []grpc.ServerOption
+	allowedOrigins []string
+	allowedHeaders []string
+	maxBodyBytes   int64
+	tlsConfig      *tls.Config
+}
+
+type Option func(o *options)
+
+func defaultOpts() options {
+	return options{
+		addr: ":8081",
+		// matches the default maximum receive message size of grpc.Server
+		maxBodyBytes: 4 << 20,
+	}
+}
+
+// WithAddr sets the address the Handler listens on.  Defaults to :8081.
+func WithAddr(addr string) Option {
+	return func(o *options) {
+		o.addr = addr
+	}
+}
+
+// WithGRPCOptions sets the options of the grpc.Server the gRPC-Web requests are bridged to.  Pass the interceptors of
+// the native gRPC listener so both authenticate, authorize and limit calls alike.
+func WithGRPCOptions(opts ...grpc.ServerOption) Option {
+	return func(o *options) {
+		o.serverOptions = append(o.serverOptions, opts...)
+	}
+}
+
+// WithAllowedOrigins sets the origins allowed to make cross-origin requests with credentials.  "*" allows any other
+// origin too, without credentials.  No origins are allowed by default.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(o *options) {
+		o.allowedOrigins = append(o.allowedOrigins, origins...)
+	}
+}
+
+// WithAllowedHeaders adds request headers, such as custom metadata, that browsers may send cross-origin.
+func WithAllowedHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.allowedHeaders = append(o.allowedHeaders, headers...)
+	}
+}
+
+// WithTLSConfig serves the Handler over TLS with cfg.  The Handler is plaintext by default.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// Handler is an app.Runner serving the services of a Registerer to gRPC-Web clients over HTTP.  Native gRPC clients are
+// refused, they are served by the gRPC listener.
+type Handler struct {
+	r    grpclistener.Registerer
+	opts options
+	cors cors
+
+	listenCfg ListenConfig
+
+	mu         sync.Mutex
+	server     *http.Server
+	grpcServer *grpc.Server
+}
+
+func New(r grpclistener.Registerer, opts ...Option) *Handler {
+	o := defaultOpts()
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Handler{
+		r:         r,
+		opts:      o,
+		cors:      newCORS(o.allowedOrigins, o.allowedHeaders),
+		listenCfg: &net.ListenConfig{},
+	}
+}
+
+func (h *Handler) Start(ctx context.Context) error {
+	l, err := h.listenCfg.Listen(ctx, "tcp", h.opts.addr)
+	if err != nil {
+		return fmt.Errorf("grpcweb: unable to create listener: %w", err)
+	}
+	if h.opts.tlsConfig != nil {
+		l = tls.NewListener(l, h.opts.tlsConfig)
+	}
+
+	gs := grpc.NewServer(h.opts.serverOptions...)
+	h.r.Register(gs)
+
+	s := &http.Server{
+		Handler:           h.cors.wrap(h.bridge(gs)),
+		ReadHeaderTimeout: time.Second * 10,
+		BaseContext: func(net.Listener) context.Context {
+			return context.WithoutCancel(ctx)
+		},
+	}
+
+	h.mu.Lock()
+	h.server = s
+	h.grpcServer = gs
+	h.mu.Unlock()
+
+	if err := s.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (h *Handler) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	s, gs := h.server, h.grpcServer
+	h.mu.Unlock()
+
+	if s == nil {
+		return nil
+	}
+
+	err := s.Shutdown(ctx)
+	gs.Stop()
+	return err
+}
+
+func (h *Handler) Name() string {
+	return "grpc-web"
+}
+
+// bridge translates gRPC-Web requests for gs.
+func (h *Handler) bridge(gs *grpc.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		if !isGRPCWeb(contentType) {
+			http.Error(w, "unsupported content-type "+contentType, http.StatusUnsupportedMediaType)
+			return
+		}
+		h.serveGRPCWeb(gs, w, r)
+	})
+}
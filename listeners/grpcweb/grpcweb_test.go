@@ -0,0 +1,220 @@
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name            string
+		origins         []string
+		method          string
+		origin          string
+		wantStatus      int
+		wantOrigin      string
+		wantCredentials bool
+	}{
+		{name: "same origin", origins: nil, method: http.MethodPost, wantStatus: http.StatusOK},
+		{name: "not allowed", origins: []string{"https://app.example"}, method: http.MethodPost, origin: "https://evil.example", wantStatus: http.StatusOK},
+		{name: "preflight not allowed", origins: []string{"https://app.example"}, method: http.MethodOptions, origin: "https://evil.example", wantStatus: http.StatusForbidden},
+		{name: "allowed", origins: []string{"https://app.example"}, method: http.MethodPost, origin: "https://app.example", wantStatus: http.StatusOK, wantOrigin: "https://app.example", wantCredentials: true},
+		{name: "preflight allowed", origins: []string{"https://app.example"}, method: http.MethodOptions, origin: "https://app.example", wantStatus: http.StatusNoContent, wantOrigin: "https://app.example", wantCredentials: true},
+		{name: "wildcard", origins: []string{"*"}, method: http.MethodPost, origin: "https://any.example", wantStatus: http.StatusOK, wantOrigin: "*"},
+		{name: "preflight wildcard", origins: []string{"*"}, method: http.MethodOptions, origin: "https://any.example", wantStatus: http.StatusNoContent, wantOrigin: "*"},
+		{name: "listed beside wildcard", origins: []string{"*", "https://app.example"}, method: http.MethodPost, origin: "https://app.example", wantStatus: http.StatusOK, wantOrigin: "https://app.example", wantCredentials: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newCORS(tt.origins, nil).wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+			r := httptest.NewRequest(tt.method, "/playground.Greeter/SayHello", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == http.MethodOptions {
+				r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("credentials allowed = %v, want %v", got, tt.wantCredentials)
+			}
+		})
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "single chunk", body: base64.StdEncoding.EncodeToString([]byte("hello")), want: "hello"},
+		{name: "padded chunks", body: base64.StdEncoding.EncodeToString([]byte("he")) + base64.StdEncoding.EncodeToString([]byte("llo")), want: "hello"},
+		{name: "whitespace", body: "aGVs\r\nbG8=\n", want: "hello"},
+		{name: "empty", body: "", want: ""},
+		{name: "truncated", body: "aGVsbG", wantErr: true},
+		{name: "invalid", body: "aGV!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeText([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeText() error = %v, want error %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("decodeText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type greeter struct {
+	schemas.UnimplementedGreeterServer
+}
+
+func (greeter) SayHello(_ context.Context, request *schemas.HelloRequest) (*schemas.HelloReply, error) {
+	if request.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	return &schemas.HelloReply{Message: "Hello " + request.GetName()}, nil
+}
+
+type registerer func(s *grpc.Server)
+
+func (r registerer) Register(s *grpc.Server) {
+	r(s)
+}
+
+// frame encodes a message as a length prefixed gRPC frame.
+func frame(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, 5, 5+len(b))
+	binary.BigEndian.PutUint32(out[1:], uint32(len(b)))
+	return append(out, b...)
+}
+
+// readFrames splits a gRPC-Web response body into its messages and trailers.
+func readFrames(t *testing.T, b []byte) (messages [][]byte, trailers map[string]string) {
+	t.Helper()
+	trailers = map[string]string{}
+	for len(b) > 0 {
+		if len(b) < 5 {
+			t.Fatalf("truncated frame header %q", b)
+		}
+		flag, n := b[0], binary.BigEndian.Uint32(b[1:5])
+		if uint32(len(b)-5) < n {
+			t.Fatalf("truncated frame of %d bytes", n)
+		}
+		payload := b[5 : 5+n]
+		b = b[5+n:]
+
+		if flag&trailerFlag == 0 {
+			messages = append(messages, payload)
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(payload)), "\r\n") {
+			k, v, _ := strings.Cut(line, ": ")
+			trailers[k] = v
+		}
+	}
+	return messages, trailers
+}
+
+func TestHandlerBridge(t *testing.T) {
+	h := New(registerer(func(s *grpc.Server) { schemas.RegisterGreeterServer(s, greeter{}) }))
+	gs := grpc.NewServer()
+	h.r.Register(gs)
+	defer gs.Stop()
+	handler := h.cors.wrap(h.bridge(gs))
+
+	tests := []struct {
+		name        string
+		contentType string
+		request     *schemas.HelloRequest
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{name: "binary", contentType: "application/grpc-web+proto", request: &schemas.HelloRequest{Name: "Ann"}, wantStatus: http.StatusOK, wantCode: "0", wantMessage: "Hello Ann"},
+		{name: "text", contentType: "application/grpc-web-text", request: &schemas.HelloRequest{Name: "Ann"}, wantStatus: http.StatusOK, wantCode: "0", wantMessage: "Hello Ann"},
+		{name: "error in trailers", contentType: "application/grpc-web", request: &schemas.HelloRequest{}, wantStatus: http.StatusOK, wantCode: "3"},
+		{name: "native grpc", contentType: "application/grpc", request: &schemas.HelloRequest{Name: "Ann"}, wantStatus: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := frame(t, tt.request)
+			text := isText(tt.contentType)
+			if text {
+				body = []byte(base64.StdEncoding.EncodeToString(body))
+			}
+			r := httptest.NewRequest(http.MethodPost, "/playground.Greeter/SayHello", bytes.NewReader(body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+
+			b := w.Body.Bytes()
+			if text {
+				var err error
+				if b, err = decodeText(b); err != nil {
+					t.Fatalf("decodeText() error = %v", err)
+				}
+			}
+			messages, trailers := readFrames(t, b)
+			if trailers["grpc-status"] != tt.wantCode {
+				t.Errorf("grpc-status = %q, want %q (trailers %v)", trailers["grpc-status"], tt.wantCode, trailers)
+			}
+			if tt.wantMessage == "" {
+				if len(messages) != 0 {
+					t.Errorf("messages = %d, want none", len(messages))
+				}
+				return
+			}
+			if len(messages) != 1 {
+				t.Fatalf("messages = %d, want 1", len(messages))
+			}
+			var reply schemas.HelloReply
+			if err := proto.Unmarshal(messages[0], &reply); err != nil {
+				t.Fatal(err)
+			}
+			if reply.GetMessage() != tt.wantMessage {
+				t.Errorf("message = %q, want %q", reply.GetMessage(), tt.wantMessage)
+			}
+		})
+	}
+}
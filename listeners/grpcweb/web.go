@@ -0,0 +1,187 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	contentTypeWeb     = "application/grpc-web"
+	contentTypeWebText = "application/grpc-web-text"
+
+	// trailerFlag marks a frame in the response body as holding trailers rather than a message.
+	trailerFlag = 0x80
+)
+
+func isGRPCWeb(contentType string) bool {
+	return strings.HasPrefix(contentType, contentTypeWeb)
+}
+
+func isText(contentType string) bool {
+	return strings.HasPrefix(contentType, contentTypeWebText)
+}
+
+// serveGRPCWeb rewrites a gRPC-Web request into the HTTP/2 form grpc.Server expects and encodes the response, with
+// its trailers, back into the body.
+func (h *Handler) serveGRPCWeb(gs *grpc.Server, w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := isText(contentType)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.opts.maxBodyBytes))
+	if err != nil {
+		http.Error(w, "unable to read request body", http.StatusBadRequest)
+		return
+	}
+	if text {
+		if body, err = decodeText(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the subtype, e.g. +proto, is kept so grpc.Server picks the matching codec
+	subtype := strings.TrimPrefix(strings.TrimPrefix(contentType, contentTypeWebText), contentTypeWeb)
+
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2"
+	req.Header.Set("Content-Type", "application/grpc"+subtype)
+	req.Header.Set("Te", "trailers")
+	req.Header.Del("Content-Length")
+	req.ContentLength = int64(len(body))
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	responseContentType := contentTypeWeb + subtype
+	if text {
+		responseContentType = contentTypeWebText + subtype
+	}
+
+	rw := &responseWriter{
+		w:           w,
+		header:      http.Header{},
+		contentType: responseContentType,
+		text:        text,
+	}
+	gs.ServeHTTP(rw, req)
+	rw.finish()
+}
+
+// decodeText decodes a grpc-web-text body.  Clients may send several base64 chunks back to back, each with its own
+// padding, so the body is decoded one four character group at a time.
+func decodeText(b []byte) ([]byte, error) {
+	b = bytes.Join(bytes.Fields(b), nil)
+	if len(b)%4 != 0 {
+		return nil, errors.New("grpc-web-text body is not valid base64")
+	}
+
+	out := make([]byte, 0, base64.StdEncoding.DecodedLen(len(b)))
+	group := make([]byte, 3)
+	for i := 0; i < len(b); i += 4 {
+		n, err := base64.StdEncoding.Decode(group, b[i:i+4])
+		if err != nil {
+			return nil, fmt.Errorf("grpc-web-text body is not valid base64: %w", err)
+		}
+		out = append(out, group[:n]...)
+	}
+	return out, nil
+}
+
+// responseWriter collects the headers set by grpc.Server, sends those set before the body as HTTP headers and encodes
+// the rest as a trailer frame at the end of the body.
+type responseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+
+	wroteHeader bool
+	sent        map[string]bool
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+
+	rw.sent = map[string]bool{}
+	h := rw.w.Header()
+	for k, vs := range rw.header {
+		rw.sent[k] = true
+		if k == "Trailer" || strings.HasPrefix(k, http.TrailerPrefix) || isDeclaredTrailer(rw.header, k) {
+			continue
+		}
+		h[k] = vs
+	}
+	h.Set("Content-Type", rw.contentType)
+	h.Del("Content-Length")
+
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+
+	if !rw.text {
+		return rw.w.Write(b)
+	}
+
+	if _, err := io.WriteString(rw.w, base64.StdEncoding.EncodeToString(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (rw *responseWriter) Flush() {
+	rw.WriteHeader(http.StatusOK)
+
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the trailer frame once grpc.Server has finished with the request.
+func (rw *responseWriter) finish() {
+	rw.WriteHeader(http.StatusOK)
+
+	var trailer bytes.Buffer
+	for k, vs := range rw.header {
+		name := strings.TrimPrefix(k, http.TrailerPrefix)
+		if k == "Trailer" || (rw.sent[k] && name == k && !isDeclaredTrailer(rw.header, k)) {
+			continue
+		}
+		for _, v := range vs {
+			fmt.Fprintf(&trailer, "%s: %s\r\n", strings.ToLower(name), v)
+		}
+	}
+
+	frame := make([]byte, 5, 5+trailer.Len())
+	frame[0] = trailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(trailer.Len()))
+	frame = append(frame, trailer.Bytes()...)
+
+	_, _ = rw.Write(frame)
+	rw.Flush()
+}
+
+func isDeclaredTrailer(h http.Header, key string) bool {
+	for _, v := range h.Values("Trailer") {
+		for _, name := range strings.Split(v, ",") {
+			if http.CanonicalHeaderKey(strings.TrimSpace(name)) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
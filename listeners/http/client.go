@@ -0,0 +1,48 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+type Service interface {
+	Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+}
+
+// Route binds an HTTP method and path to the RPC it is translated to.
+type Route struct {
+	Method  string
+	Path    string
+	RPC     protoreflect.MethodDescriptor
+	handler http.HandlerFunc
+}
+
+// Client exposes the Greeter service as JSON over HTTP.
+type Client struct {
+	service Service
+}
+
+func NewClient(service Service) *Client {
+	return &Client{service: service}
+}
+
+// Routes returns the routes served by the Client.
+func (c *Client) Routes() []Route {
+	sayHello := schemas.File_playground_helloworld_proto.Services().ByName("Greeter").Methods().ByName("SayHello")
+
+	return []Route{
+		{Method: http.MethodPost, Path: "/v1/hello", RPC: sayHello, handler: c.postHello},
+		{Method: http.MethodGet, Path: "/v1/hello", RPC: sayHello, handler: c.getHello},
+	}
+}
+
+func (c *Client) Register(mux *http.ServeMux) {
+	for _, route := range c.Routes() {
+		mux.HandleFunc(route.Method+" "+route.Path, route.handler)
+	}
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxBodyBytes caps the size of request bodies decoded by the gateway.
+const maxBodyBytes = 1 << 20
+
+var (
+	marshaler   = protojson.MarshalOptions{EmitUnpopulated: true}
+	unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// decodeBody reads a JSON body into m.  An empty body leaves m unset.
+func decodeBody(w http.ResponseWriter, r *http.Request, m proto.Message) error {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return status.Errorf(codes.ResourceExhausted, "request body exceeds %d bytes", maxErr.Limit)
+		}
+		return status.Errorf(codes.InvalidArgument, "unable to read request body: %v", err)
+	}
+
+	if len(body) == 0 {
+		return nil
+	}
+
+	if err := unmarshaler.Unmarshal(body, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// decodeQuery sets the scalar fields of m from query parameters, matching on either the proto or JSON field name.
+func decodeQuery(values url.Values, m proto.Message) error {
+	msg := m.ProtoReflect()
+	fields := msg.Descriptor().Fields()
+
+	for key, vs := range values {
+		fd := fields.ByName(protoreflect.Name(key))
+		if fd == nil {
+			fd = fields.ByJSONName(key)
+		}
+		if fd == nil || len(vs) == 0 {
+			continue
+		}
+		if fd.IsMap() || fd.Message() != nil {
+			return status.Errorf(codes.InvalidArgument, "query parameter %q cannot be set from a string", key)
+		}
+
+		if fd.IsList() {
+			list := msg.Mutable(fd).List()
+			for _, v := range vs {
+				value, err := parseScalar(fd, v)
+				if err != nil {
+					return status.Errorf(codes.InvalidArgument, "invalid query parameter %q: %v", key, err)
+				}
+				list.Append(value)
+			}
+			continue
+		}
+
+		value, err := parseScalar(fd, vs[len(vs)-1])
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid query parameter %q: %v", key, err)
+		}
+		msg.Set(fd, value)
+	}
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(s)), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(s, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), err
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", fd.Kind())
+	}
+}
+
+func writeMessage(ctx context.Context, w http.ResponseWriter, code int, m proto.Message) {
+	b, err := marshaler.Marshal(m)
+	if err != nil {
+		writeError(ctx, w, status.Errorf(codes.Internal, "unable to encode response: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(b); err != nil {
+		zaphelper.Debug(ctx, "unable to write response", zap.Error(err))
+	}
+}
+
+// writeError writes err as a JSON google.rpc.Status with the HTTP status mapped from its gRPC code.
+func writeError(ctx context.Context, w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	b, mErr := marshaler.Marshal(st.Proto())
+	if mErr != nil {
+		// details may hold types that are not registered, drop them rather than failing the response
+		b, _ = marshaler.Marshal(status.New(st.Code(), st.Message()).Proto())
+	}
+
+	if st.Code() == codes.Internal || st.Code() == codes.Unknown {
+		zaphelper.Error(ctx, "http request failed", zap.Error(err))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(HTTPStatusFromCode(st.Code()))
+	if _, err := w.Write(b); err != nil {
+		zaphelper.Debug(ctx, "unable to write error response", zap.Error(err))
+	}
+}
+
+// IncomingMetadata exposes the headers of r to the service as gRPC request metadata, so calls made over HTTP can be
+// told apart the same way as calls made over gRPC.
+func IncomingMetadata(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for k, v := range r.Header {
+		md.Append(k, v...)
+	}
+	return metadata.NewIncomingContext(r.Context(), md)
+}
+
+// HTTPStatusFromCode maps a gRPC status code to the closest HTTP status code.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// non-standard code used by nginx and grpc-gateway for client closed request
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
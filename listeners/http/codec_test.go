@@ -0,0 +1,184 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	binlogpb "google.golang.org/grpc/binarylog/grpc_binarylog_v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func TestDecodeQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		message  proto.Message
+		want     proto.Message
+		wantCode codes.Code
+	}{
+		{name: "proto name", query: "name=Ann", message: &schemas.HelloRequest{}, want: &schemas.HelloRequest{Name: "Ann"}},
+		{name: "last value wins", query: "name=Ann&name=Bob", message: &schemas.HelloRequest{}, want: &schemas.HelloRequest{Name: "Bob"}},
+		{name: "unknown parameter", query: "other=1", message: &schemas.HelloRequest{}, want: &schemas.HelloRequest{}},
+		{name: "json name", query: "callId=7&payloadTruncated=true", message: &binlogpb.GrpcLogEntry{}, want: &binlogpb.GrpcLogEntry{CallId: 7, PayloadTruncated: true}},
+		{name: "enum by name", query: "type=EVENT_TYPE_CLIENT_MESSAGE", message: &binlogpb.GrpcLogEntry{}, want: &binlogpb.GrpcLogEntry{Type: binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_MESSAGE}},
+		{name: "enum by number", query: "type=4", message: &binlogpb.GrpcLogEntry{}, want: &binlogpb.GrpcLogEntry{Type: binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_MESSAGE}},
+		{name: "signed integers", query: "seconds=-3&nanos=500", message: &durationpb.Duration{}, want: &durationpb.Duration{Seconds: -3, Nanos: 500}},
+		{name: "invalid integer", query: "seconds=three", message: &durationpb.Duration{}, wantCode: codes.InvalidArgument},
+		{name: "integer out of range", query: "nanos=4294967296", message: &durationpb.Duration{}, wantCode: codes.InvalidArgument},
+		{name: "invalid bool", query: "payload_truncated=maybe", message: &binlogpb.GrpcLogEntry{}, wantCode: codes.InvalidArgument},
+		{name: "message field", query: "peer=127.0.0.1", message: &binlogpb.GrpcLogEntry{}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = decodeQuery(values, tt.message)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("decodeQuery() code = %v, want %v (error %v)", code, tt.wantCode, err)
+			}
+			if err == nil && !proto.Equal(tt.message, tt.want) {
+				t.Errorf("decodeQuery() = %v, want %v", tt.message, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		want     string
+		wantCode codes.Code
+	}{
+		{name: "json", body: `{"name":"Ann"}`, want: "Ann"},
+		{name: "empty", body: "", want: ""},
+		{name: "unknown fields", body: `{"name":"Ann","other":1}`, want: "Ann"},
+		{name: "invalid json", body: `{"name":`, wantCode: codes.InvalidArgument},
+		{name: "wrong type", body: `{"name":1}`, wantCode: codes.InvalidArgument},
+		{name: "too large", body: `{"name":"` + strings.Repeat("a", maxBodyBytes) + `"}`, wantCode: codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/hello", strings.NewReader(tt.body))
+			var request schemas.HelloRequest
+			err := decodeBody(httptest.NewRecorder(), r, &request)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("decodeBody() code = %v, want %v (error %v)", code, tt.wantCode, err)
+			}
+			if request.GetName() != tt.want {
+				t.Errorf("decodeBody() name = %q, want %q", request.GetName(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{code: codes.OK, want: http.StatusOK},
+		{code: codes.Canceled, want: 499},
+		{code: codes.InvalidArgument, want: http.StatusBadRequest},
+		{code: codes.FailedPrecondition, want: http.StatusBadRequest},
+		{code: codes.DeadlineExceeded, want: http.StatusGatewayTimeout},
+		{code: codes.NotFound, want: http.StatusNotFound},
+		{code: codes.AlreadyExists, want: http.StatusConflict},
+		{code: codes.PermissionDenied, want: http.StatusForbidden},
+		{code: codes.Unauthenticated, want: http.StatusUnauthorized},
+		{code: codes.ResourceExhausted, want: http.StatusTooManyRequests},
+		{code: codes.Unimplemented, want: http.StatusNotImplemented},
+		{code: codes.Unavailable, want: http.StatusServiceUnavailable},
+		{code: codes.Internal, want: http.StatusInternalServerError},
+		{code: codes.DataLoss, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			if got := HTTPStatusFromCode(tt.code); got != tt.want {
+				t.Errorf("HTTPStatusFromCode(%v) = %d, want %d", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+type respondFunc func(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+
+func (f respondFunc) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	return f(ctx, request)
+}
+
+func TestClientHello(t *testing.T) {
+	svc := respondFunc(func(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+		switch request.OriginalMessage {
+		case "":
+			return service.RespondResponse{}, status.Error(codes.InvalidArgument, "name is required")
+		case "crash":
+			return service.RespondResponse{}, context.DeadlineExceeded
+		}
+		greeting := "Hello " + request.OriginalMessage
+		if v := metadata.ValueFromIncomingContext(ctx, "x-greeting"); len(v) > 0 {
+			greeting = v[0] + " " + request.OriginalMessage
+		}
+		return service.RespondResponse{ResponseMessage: greeting}, nil
+	})
+	mux := http.NewServeMux()
+	NewClient(svc).Register(mux)
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		header      http.Header
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "post", method: http.MethodPost, target: "/v1/hello", body: `{"name":"Ann"}`, wantStatus: http.StatusOK, wantMessage: "Hello Ann"},
+		{name: "get", method: http.MethodGet, target: "/v1/hello?name=Ann", wantStatus: http.StatusOK, wantMessage: "Hello Ann"},
+		{name: "headers as metadata", method: http.MethodGet, target: "/v1/hello?name=Ann", header: http.Header{"X-Greeting": {"Hi"}}, wantStatus: http.StatusOK, wantMessage: "Hi Ann"},
+		{name: "status of the service", method: http.MethodGet, target: "/v1/hello", wantStatus: http.StatusBadRequest, wantMessage: "name is required"},
+		{name: "error without status", method: http.MethodGet, target: "/v1/hello?name=crash", wantStatus: http.StatusInternalServerError},
+		{name: "invalid body", method: http.MethodPost, target: "/v1/hello", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "method not allowed", method: http.MethodDelete, target: "/v1/hello", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantMessage == "" {
+				return
+			}
+			var body struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid body %s: %v", w.Body, err)
+			}
+			if body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Message, tt.wantMessage)
+			}
+		})
+	}
+}
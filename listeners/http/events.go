@@ -0,0 +1,203 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+type greetingEvent struct {
+	ID       uint64    `json:"id"`
+	Name     string    `json:"name"`
+	Message  string    `json:"message"`
+	IssuedAt time.Time `json:"issuedAt"`
+}
+
+// Events streams the greetings issued by the service as Server-Sent Events.  The most recent greetings are kept so
+// clients reconnecting with Last-Event-ID receive the greetings they missed.
+type Events struct {
+	heartbeat time.Duration
+
+	mu          sync.Mutex
+	buffer      []greetingEvent
+	start       int
+	subscribers map[chan struct{}]struct{}
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewEvents(bufferSize int, heartbeat time.Duration) *Events {
+	return &Events{
+		heartbeat:   heartbeat,
+		buffer:      make([]greetingEvent, 0, bufferSize),
+		subscribers: map[chan struct{}]struct{}{},
+		closed:      make(chan struct{}),
+	}
+}
+
+// Observe implements service.GreetingObserver.
+func (e *Events) Observe(greeting service.Greeting) {
+	event := greetingEvent{
+		ID:       greeting.ID,
+		Name:     greeting.Name,
+		Message:  greeting.Message,
+		IssuedAt: greeting.IssuedAt,
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.buffer) < cap(e.buffer) {
+		e.buffer = append(e.buffer, event)
+	} else if cap(e.buffer) > 0 {
+		e.buffer[e.start] = event
+		e.start = (e.start + 1) % cap(e.buffer)
+	}
+
+	for notify := range e.subscribers {
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close ends every open stream.  Pass it to WithOnShutdown so streams do not hold up the listener shutting down.
+func (e *Events) Close() {
+	e.closeOnce.Do(func() {
+		close(e.closed)
+	})
+}
+
+func (e *Events) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/greetings/events", e.stream)
+}
+
+// since returns the buffered events with an ID greater than id, in order.
+func (e *Events) since(id uint64) []greetingEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []greetingEvent
+	for i := 0; i < len(e.buffer); i++ {
+		event := e.buffer[(e.start+i)%len(e.buffer)]
+		if event.ID > id {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// latest returns the ID of the most recent buffered event.
+func (e *Events) latest() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.buffer) == 0 {
+		return 0
+	}
+	return e.buffer[(e.start+len(e.buffer)-1)%len(e.buffer)].ID
+}
+
+func (e *Events) subscribe() chan struct{} {
+	notify := make(chan struct{}, 1)
+
+	e.mu.Lock()
+	e.subscribers[notify] = struct{}{}
+	e.mu.Unlock()
+
+	return notify
+}
+
+func (e *Events) unsubscribe(notify chan struct{}) {
+	e.mu.Lock()
+	delete(e.subscribers, notify)
+	e.mu.Unlock()
+}
+
+func (e *Events) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// subscribe before reading the buffer so no greeting issued in between is missed
+	notify := e.subscribe()
+	defer e.unsubscribe(notify)
+
+	cursor := e.latest()
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		cursor = id
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", (time.Second * 3).Milliseconds()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(e.heartbeat)
+	defer heartbeat.Stop()
+
+	// replay anything issued after the client's last event
+	select {
+	case notify <- struct{}{}:
+	default:
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-e.closed:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-notify:
+			for _, event := range e.since(cursor) {
+				if err := writeEvent(w, event); err != nil {
+					zaphelper.Debug(r.Context(), "unable to write greeting event", zap.Error(err))
+					return
+				}
+				cursor = event.ID
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event greetingEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: greeting\ndata: %s\n\n", event.ID, b)
+	return err
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func observe(e *Events, ids ...uint64) {
+	for _, id := range ids {
+		e.Observe(service.Greeting{ID: id, Name: fmt.Sprint("name", id), Message: "Hello", IssuedAt: time.Unix(1_700_000_000, 0)})
+	}
+}
+
+func eventIDs(events []greetingEvent) []uint64 {
+	var ids []uint64
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestEventsBuffer(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		observed   []uint64
+		since      uint64
+		want       []uint64
+		wantLatest uint64
+	}{
+		{name: "empty", size: 3, since: 0, want: nil, wantLatest: 0},
+		{name: "not full", size: 3, observed: []uint64{1, 2}, since: 0, want: []uint64{1, 2}, wantLatest: 2},
+		{name: "after an id", size: 3, observed: []uint64{1, 2, 3}, since: 1, want: []uint64{2, 3}, wantLatest: 3},
+		{name: "wrapped", size: 3, observed: []uint64{1, 2, 3, 4, 5}, since: 0, want: []uint64{3, 4, 5}, wantLatest: 5},
+		{name: "wrapped after an id", size: 3, observed: []uint64{1, 2, 3, 4, 5}, since: 3, want: []uint64{4, 5}, wantLatest: 5},
+		{name: "up to date", size: 3, observed: []uint64{1, 2, 3, 4, 5}, since: 5, want: nil, wantLatest: 5},
+		{name: "no buffer", size: 0, observed: []uint64{1, 2}, since: 0, want: nil, wantLatest: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEvents(tt.size, time.Minute)
+			observe(e, tt.observed...)
+
+			if got := eventIDs(e.since(tt.since)); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("since(%d) = %v, want %v", tt.since, got, tt.want)
+			}
+			if got := e.latest(); got != tt.wantLatest {
+				t.Errorf("latest() = %d, want %d", got, tt.wantLatest)
+			}
+		})
+	}
+}
+
+// readEvents reads n greeting events from a stream, skipping comments and the retry field.
+func readEvents(t *testing.T, r *bufio.Reader, n int) []uint64 {
+	t.Helper()
+	var ids []uint64
+	for len(ids) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read %v, want %d events: %v", ids, n, err)
+		}
+		data, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), "data: ")
+		if !ok {
+			continue
+		}
+		var event greetingEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", data, err)
+		}
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestEventsStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		query  string
+		// observed after the stream is open
+		observed []uint64
+		want     []uint64
+	}{
+		{name: "new greetings only", observed: []uint64{4}, want: []uint64{4}},
+		{name: "resume from header", header: "1", observed: []uint64{4}, want: []uint64{2, 3, 4}},
+		{name: "resume from query", query: "?lastEventId=2", observed: []uint64{4}, want: []uint64{3, 4}},
+		{name: "header before query", header: "2", query: "?lastEventId=0", observed: []uint64{4}, want: []uint64{3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEvents(10, time.Minute)
+			observe(e, 1, 2, 3)
+			mux := http.NewServeMux()
+			e.Register(mux)
+			s := httptest.NewServer(mux)
+			defer s.Close()
+			defer e.Close()
+
+			r, err := http.NewRequest(http.MethodGet, s.URL+"/v1/greetings/events"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				r.Header.Set("Last-Event-ID", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+			}
+
+			// the headers are only sent once the stream has subscribed
+			observe(e, tt.observed...)
+			if got := readEvents(t, bufio.NewReader(resp.Body), len(tt.want)); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventsStreamInvalidLastEventID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/greetings/events", nil)
+	r.Header.Set("Last-Event-ID", "latest")
+	w := httptest.NewRecorder()
+	NewEvents(10, time.Minute).stream(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
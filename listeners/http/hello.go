@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func (c *Client) postHello(w http.ResponseWriter, r *http.Request) {
+	request := &schemas.HelloRequest{}
+	if err := decodeBody(w, r, request); err != nil {
+		writeError(r.Context(), w, err)
+		return
+	}
+
+	c.sayHello(w, r, request)
+}
+
+func (c *Client) getHello(w http.ResponseWriter, r *http.Request) {
+	request := &schemas.HelloRequest{}
+	if err := decodeQuery(r.URL.Query(), request); err != nil {
+		writeError(r.Context(), w, err)
+		return
+	}
+
+	c.sayHello(w, r, request)
+}
+
+func (c *Client) sayHello(w http.ResponseWriter, r *http.Request, request *schemas.HelloRequest) {
+	reply, err := c.SayHello(IncomingMetadata(r), request)
+	if err != nil {
+		writeError(r.Context(), w, err)
+		return
+	}
+
+	writeMessage(r.Context(), w, http.StatusOK, reply)
+}
+
+func (c *Client) SayHello(ctx context.Context, request *schemas.HelloRequest) (*schemas.HelloReply, error) {
+	resp, err := c.service.Respond(ctx, service.RespondRequest{
+		OriginalMessage: request.GetName(),
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			// the service chose the status, pass it on with its message and details intact
+			return nil, err
+		}
+		return nil, fmt.Errorf("error occurred: %w", err)
+	}
+
+	return &schemas.HelloReply{
+		Message: resp.ResponseMessage,
+	}, nil
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/peer"
+)
+
+type ListenConfig interface {
+	Listen(ctx context.Context, net, addr string) (net.Listener, error)
+}
+
+// Registerer adds routes to the mux served by the Handler, in the same way the grpc Registerer adds services to a
+// grpc.Server.
+type Registerer interface {
+	Register(mux *http.ServeMux)
+}
+
+type register func(mux *http.ServeMux)
+
+func (r register) Register(mux *http.ServeMux) {
+	r(mux)
+}
+
+func MultiRegisterer(rs ...Registerer) Registerer {
+	return register(func(mux *http.ServeMux) {
+		for _, r := range rs {
+			r.Register(mux)
+		}
+	})
+}
+
+type options struct {
+	addr              string
+	readHeaderTimeout time.Duration
+	idleTimeout       time.Duration
+	onShutdown        []func()
+	middleware        []func(http.Handler) http.Handler
+	wrapListener      func(net.Listener) net.Listener
+}
+
+type Option func(o *options)
+
+func defaultOpts() options {
+	return options{
+		addr:              ":8080",
+		readHeaderTimeout: time.Second * 10,
+		idleTimeout:       time.Second * 120,
+	}
+}
+
+// WithAddr sets the address the Handler listens on.  Defaults to :8080.
+func WithAddr(addr string) Option {
+	return func(o *options) {
+		o.addr = addr
+	}
+}
+
+// WithReadHeaderTimeout sets the amount of time allowed to read request headers.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.readHeaderTimeout = d
+	}
+}
+
+// WithIdleTimeout sets the maximum amount of time to wait for the next request on a keep-alive connection.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// WithOnShutdown registers functions called when the Handler begins to stop, such as closing long-lived streams that
+// would otherwise hold up the shutdown.
+func WithOnShutdown(fns ...func()) Option {
+	return func(o *options) {
+		o.onShutdown = append(o.onShutdown, fns...)
+	}
+}
+
+// WithMiddleware wraps the mux in middleware, the first one given being the outermost.
+func WithMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// WithListenerWrapper wraps the listener the Handler accepts connections from, such as to close unwanted ones early.
+func WithListenerWrapper(wrap func(net.Listener) net.Listener) Option {
+	return func(o *options) {
+		o.wrapListener = wrap
+	}
+}
+
+// Handler is an app.Runner serving the routes of a Registerer over HTTP/1.1 and unencrypted HTTP/2.
+type Handler struct {
+	r    Registerer
+	opts options
+
+	listenCfg ListenConfig
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+func New(r Registerer, opts ...Option) *Handler {
+	o := defaultOpts()
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Handler{
+		r:         r,
+		opts:      o,
+		listenCfg: &net.ListenConfig{},
+	}
+}
+
+func (h *Handler) Start(ctx context.Context) error {
+	l, err := h.listenCfg.Listen(ctx, "tcp", h.opts.addr)
+	if err != nil {
+		return fmt.Errorf("http: unable to create listener: %w", err)
+	}
+	if h.opts.wrapListener != nil {
+		l = h.opts.wrapListener(l)
+	}
+
+	mux := http.NewServeMux()
+	h.r.Register(mux)
+
+	var handler http.Handler = mux
+	for i := len(h.opts.middleware) - 1; i >= 0; i-- {
+		handler = h.opts.middleware[i](handler)
+	}
+
+	s := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: h.opts.readHeaderTimeout,
+		IdleTimeout:       h.opts.idleTimeout,
+		// requests inherit the runner context so handlers log through the service logger
+		BaseContext: func(net.Listener) context.Context {
+			return context.WithoutCancel(ctx)
+		},
+		// the peer address is found as it is for gRPC calls, by those such as the abuse detector
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return peer.NewContext(ctx, &peer.Peer{Addr: c.RemoteAddr(), LocalAddr: c.LocalAddr()})
+		},
+	}
+	// unencrypted HTTP/2 with prior knowledge, for clients such as Connect that may use either protocol
+	s.Protocols = new(http.Protocols)
+	s.Protocols.SetHTTP1(true)
+	s.Protocols.SetUnencryptedHTTP2(true)
+	for _, fn := range h.opts.onShutdown {
+		s.RegisterOnShutdown(fn)
+	}
+
+	h.mu.Lock()
+	h.server = s
+	h.mu.Unlock()
+
+	if err := s.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (h *Handler) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	s := h.server
+	h.mu.Unlock()
+
+	if s == nil {
+		return nil
+	}
+	return s.Shutdown(ctx)
+}
+
+func (h *Handler) Name() string {
+	return "http"
+}
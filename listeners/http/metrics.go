@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+)
+
+// Metrics serves the metrics gathered by a prometheus.Gatherer at GET /metrics.
+type Metrics struct {
+	gatherer prometheus.Gatherer
+}
+
+func NewMetrics(gatherer prometheus.Gatherer) *Metrics {
+	return &Metrics{gatherer: gatherer}
+}
+
+func (m *Metrics) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /metrics", m.serve)
+}
+
+func (m *Metrics) serve(w http.ResponseWriter, r *http.Request) {
+	families, err := m.gatherer.Gather()
+	if err != nil {
+		// a partial result is still worth serving, Gather returns what it could collect
+		zaphelper.Error(r.Context(), "unable to gather metrics", zap.Error(err))
+	}
+
+	format := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(format))
+
+	enc := expfmt.NewEncoder(w, format)
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			zaphelper.Debug(r.Context(), "unable to write metrics", zap.Error(err))
+			return
+		}
+	}
+}
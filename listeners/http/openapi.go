@@ -0,0 +1,283 @@
+package http
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+//go:embed static/explorer.html
+var explorerPage []byte
+
+// OpenAPI serves an OpenAPI 3 document describing a set of gateway routes, generated from their protobuf descriptors,
+// alongside an HTML explorer for trying requests.
+type OpenAPI struct {
+	document []byte
+}
+
+func NewOpenAPI(title, version string, routes ...Route) (*OpenAPI, error) {
+	b, err := json.MarshalIndent(buildDocument(title, version, routes), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("openapi: unable to encode document: %w", err)
+	}
+
+	return &OpenAPI{document: b}, nil
+}
+
+func (o *OpenAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /openapi.json", o.serveDocument)
+	mux.HandleFunc("GET /explorer", serveExplorer)
+}
+
+func (o *OpenAPI) serveDocument(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(o.document)
+}
+
+func serveExplorer(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(explorerPage)
+}
+
+type document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       info                             `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type components struct {
+	Schemas map[string]*schema `json:"schemas"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+}
+
+// statusSchema is the name of the google.rpc.Status schema used for error responses.
+const statusSchema = "google.rpc.Status"
+
+func buildDocument(title, version string, routes []Route) document {
+	doc := document{
+		OpenAPI: "3.0.3",
+		Info:    info{Title: title, Version: version},
+		Paths:   map[string]map[string]*operation{},
+		Components: components{Schemas: map[string]*schema{
+			statusSchema: {
+				Type:        "object",
+				Description: "The error model returned by every operation.",
+				Properties: map[string]*schema{
+					"code":    {Type: "integer", Format: "int32", Description: "The gRPC status code."},
+					"message": {Type: "string"},
+					"details": {Type: "array", Items: &schema{Type: "object"}},
+				},
+			},
+		}},
+	}
+
+	for _, route := range routes {
+		method := strings.ToLower(route.Method)
+		if doc.Paths[route.Path] == nil {
+			doc.Paths[route.Path] = map[string]*operation{}
+		}
+
+		op := &operation{
+			OperationID: fmt.Sprintf("%s_%s", route.RPC.FullName(), route.Method),
+			Summary:     string(route.RPC.Name()),
+			Description: comments(route.RPC),
+			Tags:        []string{string(route.RPC.Parent().FullName())},
+			Responses: map[string]response{
+				"200": {
+					Description: "A successful response.",
+					Content:     jsonContent(messageSchema(doc.Components.Schemas, route.RPC.Output())),
+				},
+				"default": {
+					Description: "An unexpected error response.",
+					Content:     jsonContent(&schema{Ref: "#/components/schemas/" + statusSchema}),
+				},
+			},
+		}
+
+		if route.Method == http.MethodGet || route.Method == http.MethodDelete {
+			op.Parameters = queryParameters(route.RPC.Input())
+			// still record the input so its documentation is available to the explorer
+			messageSchema(doc.Components.Schemas, route.RPC.Input())
+		} else {
+			op.RequestBody = &requestBody{
+				Required: true,
+				Content:  jsonContent(messageSchema(doc.Components.Schemas, route.RPC.Input())),
+			}
+		}
+
+		doc.Paths[route.Path][method] = op
+	}
+
+	return doc
+}
+
+func jsonContent(s *schema) map[string]mediaType {
+	return map[string]mediaType{"application/json": {Schema: s}}
+}
+
+// queryParameters lists the fields of md that decodeQuery is able to set.
+func queryParameters(md protoreflect.MessageDescriptor) []parameter {
+	var params []parameter
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsMap() || fd.Message() != nil {
+			continue
+		}
+
+		s := fieldSchema(nil, fd)
+		params = append(params, parameter{
+			Name:        fd.JSONName(),
+			In:          "query",
+			Description: s.Description,
+			Schema:      s,
+		})
+	}
+	return params
+}
+
+// messageSchema adds md and any messages it references to schemas, returning a reference to md.
+func messageSchema(schemas map[string]*schema, md protoreflect.MessageDescriptor) *schema {
+	name := string(md.FullName())
+	ref := &schema{Ref: "#/components/schemas/" + name}
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+
+	s := &schema{
+		Type:        "object",
+		Description: comments(md),
+		Properties:  map[string]*schema{},
+	}
+	// register before walking the fields so recursive messages terminate
+	schemas[name] = s
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		s.Properties[fd.JSONName()] = fieldSchema(schemas, fd)
+	}
+	return ref
+}
+
+func fieldSchema(schemas map[string]*schema, fd protoreflect.FieldDescriptor) *schema {
+	switch {
+	case fd.IsMap():
+		return &schema{
+			Type:                 "object",
+			Description:          comments(fd),
+			AdditionalProperties: singularSchema(schemas, fd.MapValue()),
+		}
+	case fd.IsList():
+		return &schema{
+			Type:        "array",
+			Description: comments(fd),
+			Items:       singularSchema(schemas, fd),
+		}
+	default:
+		s := singularSchema(schemas, fd)
+		if s.Ref == "" {
+			s.Description = comments(fd)
+		}
+		return s
+	}
+}
+
+func singularSchema(schemas map[string]*schema, fd protoreflect.FieldDescriptor) *schema {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return &schema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &schema{Type: "string", Format: "byte"}
+	case protoreflect.BoolKind:
+		return &schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &schema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &schema{Type: "integer", Format: "int64"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// protojson encodes 64 bit integers as strings
+		return &schema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &schema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &schema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &schema{Type: "number", Format: "double"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		s := &schema{Type: "string"}
+		for i := 0; i < values.Len(); i++ {
+			s.Enum = append(s.Enum, string(values.Get(i).Name()))
+		}
+		return s
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if schemas == nil {
+			return &schema{Type: "object"}
+		}
+		return messageSchema(schemas, fd.Message())
+	default:
+		return &schema{}
+	}
+}
+
+// comments returns the leading comments of d when the descriptor was generated with source info.
+func comments(d protoreflect.Descriptor) string {
+	if d.ParentFile() == nil {
+		return ""
+	}
+	loc := d.ParentFile().SourceLocations().ByDescriptor(d)
+	return strings.TrimSpace(loc.LeadingComments)
+}
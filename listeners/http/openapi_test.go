@@ -0,0 +1,126 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	binlogpb "google.golang.org/grpc/binarylog/grpc_binarylog_v1"
+)
+
+func TestBuildDocument(t *testing.T) {
+	doc := buildDocument("greeter", "v1", NewClient(nil).Routes())
+
+	hello := doc.Paths["/v1/hello"]
+	if len(hello) != 2 || hello["get"] == nil || hello["post"] == nil {
+		t.Fatalf("paths[/v1/hello] = %v, want get and post", hello)
+	}
+
+	get := hello["get"]
+	if get.RequestBody != nil {
+		t.Error("get operation has a request body")
+	}
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "name" || get.Parameters[0].In != "query" {
+		t.Errorf("get parameters = %+v, want the name query parameter", get.Parameters)
+	}
+
+	post := hello["post"]
+	if post.RequestBody == nil || post.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/playground.HelloRequest" {
+		t.Errorf("post request body = %+v, want a HelloRequest reference", post.RequestBody)
+	}
+	if post.OperationID == get.OperationID {
+		t.Errorf("operation ids are both %q", post.OperationID)
+	}
+	if got := post.Responses["200"].Content["application/json"].Schema.Ref; got != "#/components/schemas/playground.HelloReply" {
+		t.Errorf("post 200 response = %q, want a HelloReply reference", got)
+	}
+	if got := post.Responses["default"].Content["application/json"].Schema.Ref; got != "#/components/schemas/"+statusSchema {
+		t.Errorf("post default response = %q, want a Status reference", got)
+	}
+
+	for _, name := range []string{"playground.HelloRequest", "playground.HelloReply", statusSchema} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("schemas[%s] is missing", name)
+		}
+	}
+}
+
+func TestMessageSchema(t *testing.T) {
+	schemas := map[string]*schema{}
+	ref := messageSchema(schemas, (&binlogpb.GrpcLogEntry{}).ProtoReflect().Descriptor())
+	if ref.Ref != "#/components/schemas/grpc.binarylog.v1.GrpcLogEntry" {
+		t.Fatalf("messageSchema() = %+v, want a reference", ref)
+	}
+
+	entry := schemas["grpc.binarylog.v1.GrpcLogEntry"]
+	tests := []struct {
+		field      string
+		wantType   string
+		wantFormat string
+		wantRef    string
+	}{
+		{field: "callId", wantType: "string", wantFormat: "uint64"},
+		{field: "sequenceIdWithinCall", wantType: "string", wantFormat: "uint64"},
+		{field: "payloadTruncated", wantType: "boolean"},
+		{field: "type", wantType: "string"},
+		{field: "peer", wantRef: "#/components/schemas/grpc.binarylog.v1.Address"},
+		{field: "timestamp", wantRef: "#/components/schemas/google.protobuf.Timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got := entry.Properties[tt.field]
+			if got == nil {
+				t.Fatalf("properties[%s] is missing", tt.field)
+			}
+			if got.Type != tt.wantType || got.Format != tt.wantFormat || got.Ref != tt.wantRef {
+				t.Errorf("properties[%s] = %+v, want type %q format %q ref %q", tt.field, got, tt.wantType, tt.wantFormat, tt.wantRef)
+			}
+		})
+	}
+
+	if !slices.Contains(entry.Properties["type"].Enum, "EVENT_TYPE_CLIENT_MESSAGE") {
+		t.Errorf("type enum = %v, want the event type names", entry.Properties["type"].Enum)
+	}
+	if schemas["grpc.binarylog.v1.Address"] == nil {
+		t.Error("referenced message schema is missing")
+	}
+}
+
+func TestQueryParameters(t *testing.T) {
+	var got []string
+	for _, p := range queryParameters((&binlogpb.GrpcLogEntry{}).ProtoReflect().Descriptor()) {
+		got = append(got, p.Name)
+	}
+	// message fields, including those in a oneof, can't be set from a query string
+	want := []string{"callId", "sequenceIdWithinCall", "type", "logger", "payloadTruncated"}
+	if !slices.Equal(got, want) {
+		t.Errorf("queryParameters() = %v, want %v", got, want)
+	}
+}
+
+func TestOpenAPIRegister(t *testing.T) {
+	o, err := NewOpenAPI("greeter", "v1", NewClient(nil).Routes()...)
+	if err != nil {
+		t.Fatalf("NewOpenAPI() error = %v", err)
+	}
+	mux := http.NewServeMux()
+	o.Register(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var doc document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Info.Title != "greeter" {
+		t.Errorf("document = %+v, want an OpenAPI 3 document titled greeter", doc.Info)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/explorer", nil))
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Errorf("explorer = %d with %d bytes, want the page", w.Code, w.Body.Len())
+	}
+}
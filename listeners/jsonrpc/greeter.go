@@ -0,0 +1,172 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+type Service interface {
+	Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+	Greetings(ctx context.Context, request service.GreetingsRequest) (service.GreetingsResponse, error)
+}
+
+// Client exposes the Greeter service as JSON-RPC methods:
+//
+//	greeter.sayHello     {name, locale, style} -> {message, greeting}
+//	greeter.greetings    {after, limit} -> {greetings, hasMore, total}
+//	greeter.subscribe    starts greeter.greeting notifications for each greeting issued
+//	greeter.unsubscribe  stops them
+//
+// It implements service.GreetingObserver to feed the notifications, which are sent by Forward.
+type Client struct {
+	service Service
+	server  *Server
+
+	subscribed atomic.Bool
+	greetings  chan Greeting
+}
+
+func NewClient(service Service) *Client {
+	return &Client{
+		service:   service,
+		greetings: make(chan Greeting, 64),
+	}
+}
+
+func (c *Client) Register(s *Server) {
+	c.server = s
+	s.Handle("greeter.sayHello", c.sayHello)
+	s.Handle("greeter.greetings", c.listGreetings)
+	s.Handle("greeter.subscribe", c.subscribe)
+	s.Handle("greeter.unsubscribe", c.unsubscribe)
+}
+
+type Greeting struct {
+	ID       uint64    `json:"id"`
+	Name     string    `json:"name"`
+	Locale   string    `json:"locale,omitempty"`
+	Style    string    `json:"style,omitempty"`
+	Message  string    `json:"message"`
+	IssuedAt time.Time `json:"issuedAt"`
+}
+
+func newGreeting(g service.Greeting) Greeting {
+	return Greeting{
+		ID:       g.ID,
+		Name:     g.Name,
+		Locale:   g.Locale,
+		Style:    g.Style,
+		Message:  g.Message,
+		IssuedAt: g.IssuedAt,
+	}
+}
+
+type SayHelloParams struct {
+	Name   string `json:"name"`
+	Locale string `json:"locale,omitempty"`
+	Style  string `json:"style,omitempty"`
+}
+
+type SayHelloResult struct {
+	Message  string   `json:"message"`
+	Greeting Greeting `json:"greeting"`
+}
+
+func (c *Client) sayHello(ctx context.Context, params json.RawMessage) (any, error) {
+	var p SayHelloParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.service.Respond(ctx, service.RespondRequest{
+		OriginalMessage: p.Name,
+		Locale:          p.Locale,
+		Style:           p.Style,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error occurred: %w", err)
+	}
+
+	return SayHelloResult{
+		Message:  resp.ResponseMessage,
+		Greeting: newGreeting(resp.Greeting),
+	}, nil
+}
+
+type GreetingsParams struct {
+	After uint64 `json:"after,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+type GreetingsResult struct {
+	Greetings []Greeting `json:"greetings"`
+	HasMore   bool       `json:"hasMore"`
+	Total     int        `json:"total"`
+}
+
+func (c *Client) listGreetings(ctx context.Context, params json.RawMessage) (any, error) {
+	var p GreetingsParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.service.Greetings(ctx, service.GreetingsRequest{After: p.After, Limit: p.Limit})
+	if err != nil {
+		return nil, fmt.Errorf("error occurred: %w", err)
+	}
+
+	result := GreetingsResult{
+		Greetings: make([]Greeting, 0, len(resp.Greetings)),
+		HasMore:   resp.HasMore,
+		Total:     resp.Total,
+	}
+	for _, g := range resp.Greetings {
+		result.Greetings = append(result.Greetings, newGreeting(g))
+	}
+	return result, nil
+}
+
+func (c *Client) subscribe(context.Context, json.RawMessage) (any, error) {
+	c.subscribed.Store(true)
+	return true, nil
+}
+
+func (c *Client) unsubscribe(context.Context, json.RawMessage) (any, error) {
+	c.subscribed.Store(false)
+	return true, nil
+}
+
+// Observe implements service.GreetingObserver.  Greetings are dropped when the client is not reading notifications
+// fast enough.
+func (c *Client) Observe(greeting service.Greeting) {
+	if !c.subscribed.Load() {
+		return
+	}
+
+	select {
+	case c.greetings <- newGreeting(greeting):
+	default:
+	}
+}
+
+// Forward sends a greeter.greeting notification for each greeting observed until ctx is done.
+func (c *Client) Forward(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case g := <-c.greetings:
+			if err := c.server.Notify("greeter.greeting", g); err != nil {
+				zaphelper.Debug(ctx, "unable to send greeting notification", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+// Version is the only JSON-RPC version spoken by the Server.
+const Version = "2.0"
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeServerError is used for errors returned by the service, the gRPC status is included in the error data.
+	CodeServerError = -32000
+)
+
+// maxMessageBytes caps the size of a single message read from the stream.
+const maxMessageBytes = 4 << 20
+
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// isNotification reports whether the request expects no response.
+func (r Request) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: %d %s", e.Code, e.Message)
+}
+
+func NewError(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// HandlerFunc answers a request.  Returning an *Error sets the error code, any other error is reported as a server
+// error carrying its gRPC status.
+type HandlerFunc func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Registerer adds methods to a Server, in the same way the grpc Registerer adds services to a grpc.Server.
+type Registerer interface {
+	Register(s *Server)
+}
+
+// Server speaks JSON-RPC 2.0 over a stream of newline delimited messages, such as stdin and stdout.
+type Server struct {
+	methods map[string]HandlerFunc
+
+	writeM sync.Mutex
+	w      io.Writer
+}
+
+func NewServer(rs ...Registerer) *Server {
+	s := &Server{methods: map[string]HandlerFunc{}}
+	for _, r := range rs {
+		r.Register(s)
+	}
+	return s
+}
+
+// Handle registers fn as the handler of method.
+func (s *Server) Handle(method string, fn HandlerFunc) {
+	s.methods[method] = fn
+}
+
+// Serve answers the requests read from r, writing responses to w, until r is exhausted or ctx is done.  Requests are
+// handled one at a time in the order they are received.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.writeM.Lock()
+	s.w = w
+	s.writeM.Unlock()
+
+	lines := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64<<10), maxMessageBytes)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case line := <-lines:
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			if resp := s.handleMessage(ctx, line); resp != nil {
+				if err := s.write(resp); err != nil {
+					return fmt.Errorf("jsonrpc: unable to write response: %w", err)
+				}
+			}
+		}
+	}
+}
+
+// Notify sends a notification to the client.  It fails when the Server is not serving.
+func (s *Server) Notify(method string, params any) error {
+	return s.write(notification{JSONRPC: Version, Method: method, Params: params})
+}
+
+func (s *Server) write(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.writeM.Lock()
+	defer s.writeM.Unlock()
+
+	if s.w == nil {
+		return errors.New("jsonrpc: server is not serving")
+	}
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+// handleMessage answers a single request or a batch, returning nil when no response is due.
+func (s *Server) handleMessage(ctx context.Context, msg []byte) any {
+	msg = bytes.TrimSpace(msg)
+
+	if msg[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(msg, &batch); err != nil {
+			return errorResponse(nil, NewError(CodeParseError, "parse error: %v", err))
+		}
+		if len(batch) == 0 {
+			return errorResponse(nil, NewError(CodeInvalidRequest, "empty batch"))
+		}
+
+		var responses []Response
+		for _, raw := range batch {
+			if resp := s.handleRequest(ctx, raw); resp != nil {
+				responses = append(responses, *resp)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		return responses
+	}
+
+	if resp := s.handleRequest(ctx, msg); resp != nil {
+		return resp
+	}
+	return nil
+}
+
+func (s *Server) handleRequest(ctx context.Context, raw json.RawMessage) *Response {
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return errorResponse(nil, NewError(CodeParseError, "parse error: %v", err))
+		}
+		return errorResponse(nil, NewError(CodeInvalidRequest, "invalid request: %v", err))
+	}
+	if req.JSONRPC != Version || req.Method == "" {
+		return errorResponse(req.ID, NewError(CodeInvalidRequest, "invalid request"))
+	}
+
+	fn, ok := s.methods[req.Method]
+	if !ok {
+		if req.isNotification() {
+			return nil
+		}
+		return errorResponse(req.ID, NewError(CodeMethodNotFound, "method %q not found", req.Method))
+	}
+
+	result, err := fn(ctx, req.Params)
+	if req.isNotification() {
+		if err != nil {
+			zaphelper.Debug(ctx, "jsonrpc notification failed", zap.String("method", req.Method), zap.Error(err))
+		}
+		return nil
+	}
+	if err != nil {
+		return errorResponse(req.ID, toError(err))
+	}
+	if result == nil {
+		result = struct{}{}
+	}
+	return &Response{JSONRPC: Version, ID: req.ID, Result: result}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: Version, ID: id, Error: err}
+}
+
+type statusData struct {
+	Code   string `json:"grpcCode"`
+	Status int32  `json:"grpcStatus"`
+}
+
+func toError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	st := status.Convert(err)
+	return &Error{
+		Code:    CodeServerError,
+		Message: st.Message(),
+		Data:    statusData{Code: st.Code().String(), Status: int32(st.Code())},
+	}
+}
+
+// DecodeParams unmarshals params into v, reporting failures as invalid params.  Missing params leave v unchanged.
+func DecodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return NewError(CodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// testService greets anyone but "nobody" and holds three greetings in its history.
+type testService struct{}
+
+func (testService) Respond(_ context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	if request.OriginalMessage == "nobody" {
+		return service.RespondResponse{}, status.Error(codes.InvalidArgument, "no one to greet")
+	}
+	return service.RespondResponse{
+		ResponseMessage: "Hello " + request.OriginalMessage,
+		Greeting: service.Greeting{
+			ID:       7,
+			Name:     request.OriginalMessage,
+			Style:    request.Style,
+			Message:  "Hello " + request.OriginalMessage,
+			IssuedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}, nil
+}
+
+func (testService) Greetings(_ context.Context, request service.GreetingsRequest) (service.GreetingsResponse, error) {
+	var greetings []service.Greeting
+	for id := request.After + 1; id <= 3; id++ {
+		if request.Limit > 0 && len(greetings) == request.Limit {
+			return service.GreetingsResponse{Greetings: greetings, HasMore: true, Total: 3}, nil
+		}
+		greetings = append(greetings, service.Greeting{ID: id, Name: "name", IssuedAt: time.Unix(0, 0).UTC()})
+	}
+	return service.GreetingsResponse{Greetings: greetings, Total: 3}, nil
+}
+
+// serve answers the lines of in, returning the lines written in response.
+func serve(t *testing.T, s *Server, in string) []string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	s.Handle("echo", func(_ context.Context, params json.RawMessage) (any, error) {
+		var p struct {
+			Text string `json:"text"`
+		}
+		if err := DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	s.Handle("nothing", func(context.Context, json.RawMessage) (any, error) {
+		return nil, nil
+	})
+	s.Handle("fail", func(context.Context, json.RawMessage) (any, error) {
+		return nil, status.Error(codes.NotFound, "no such thing")
+	})
+	s.Handle("refuse", func(context.Context, json.RawMessage) (any, error) {
+		return nil, NewError(CodeInvalidParams, "not today")
+	})
+
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "result",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":"hi"}}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"result":{"text":"hi"}}`},
+		},
+		{
+			name: "string id",
+			in:   `{"jsonrpc":"2.0","id":"a","method":"echo"}`,
+			want: []string{`{"jsonrpc":"2.0","id":"a","result":{"text":""}}`},
+		},
+		{
+			name: "empty result",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"nothing"}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"result":{}}`},
+		},
+		{
+			name: "blank lines skipped",
+			in:   "\n  \n" + `{"jsonrpc":"2.0","id":1,"method":"nothing"}` + "\n\n",
+			want: []string{`{"jsonrpc":"2.0","id":1,"result":{}}`},
+		},
+		{
+			name: "notification",
+			in:   `{"jsonrpc":"2.0","method":"echo","params":{"text":"hi"}}`,
+		},
+		{
+			name: "failed notification",
+			in:   `{"jsonrpc":"2.0","method":"fail"}`,
+		},
+		{
+			name: "unknown notification",
+			in:   `{"jsonrpc":"2.0","method":"missing"}`,
+		},
+		{
+			name: "parse error",
+			in:   `{"jsonrpc":"2.0",`,
+			want: []string{`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error: unexpected end of JSON input"}}`},
+		},
+		{
+			name: "wrong version",
+			in:   `{"jsonrpc":"1.0","id":1,"method":"echo"}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"invalid request"}}`},
+		},
+		{
+			name: "no method",
+			in:   `{"jsonrpc":"2.0","id":1}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"invalid request"}}`},
+		},
+		{
+			name: "not an object",
+			in:   `"echo"`,
+			want: []string{`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request: json: cannot unmarshal string into Go value of type jsonrpc.Request"}}`},
+		},
+		{
+			name: "unknown method",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"missing"}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method \"missing\" not found"}}`},
+		},
+		{
+			name: "invalid params",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":1}}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid params: json: cannot unmarshal number into Go struct field .text of type string"}}`},
+		},
+		{
+			name: "rpc error",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"refuse"}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"not today"}}`},
+		},
+		{
+			name: "server error",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"fail"}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"no such thing","data":{"grpcCode":"NotFound","grpcStatus":5}}}`},
+		},
+		{
+			name: "batch",
+			in:   `[{"jsonrpc":"2.0","id":1,"method":"nothing"},{"jsonrpc":"2.0","method":"nothing"},{"jsonrpc":"2.0","id":2,"method":"missing"},1]`,
+			want: []string{`[{"jsonrpc":"2.0","id":1,"result":{}},{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"method \"missing\" not found"}},{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request: json: cannot unmarshal number into Go value of type jsonrpc.Request"}}]`},
+		},
+		{
+			name: "batch of notifications",
+			in:   `[{"jsonrpc":"2.0","method":"nothing"},{"jsonrpc":"2.0","method":"echo"}]`,
+		},
+		{
+			name: "empty batch",
+			in:   `[]`,
+			want: []string{`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"empty batch"}}`},
+		},
+		{
+			name: "invalid batch",
+			in:   `[{"jsonrpc":"2.0"`,
+			want: []string{`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error: unexpected end of JSON input"}}`},
+		},
+		{
+			name: "in order",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"nothing"}` + "\n" + `{"jsonrpc":"2.0","id":2,"method":"nothing"}`,
+			want: []string{`{"jsonrpc":"2.0","id":1,"result":{}}`, `{"jsonrpc":"2.0","id":2,"result":{}}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serve(t, s, tt.in)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Serve() wrote\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestServerMessageTooLong(t *testing.T) {
+	var out bytes.Buffer
+	err := NewServer().Serve(context.Background(), strings.NewReader(strings.Repeat("a", maxMessageBytes+1)), &out)
+	if err == nil {
+		t.Errorf("Serve() error = nil, want the message refused")
+	}
+}
+
+func TestServerNotify(t *testing.T) {
+	s := NewServer()
+	if err := s.Notify("greeter.greeting", nil); err == nil {
+		t.Errorf("Notify() before Serve error = nil, want an error")
+	}
+
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(""), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if err := s.Notify("greeter.greeting", map[string]int{"id": 1}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","method":"greeter.greeting","params":{"id":1}}` + "\n"; out.String() != want {
+		t.Errorf("Notify() wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestToError(t *testing.T) {
+	wrapped := NewError(CodeInvalidParams, "bad")
+	tests := []struct {
+		name string
+		err  error
+		want *Error
+	}{
+		{name: "rpc error", err: wrapped, want: wrapped},
+		{name: "wrapped rpc error", err: errors.Join(errors.New("context"), wrapped), want: wrapped},
+		{
+			name: "status",
+			err:  status.Error(codes.PermissionDenied, "denied"),
+			want: &Error{Code: CodeServerError, Message: "denied", Data: statusData{Code: "PermissionDenied", Status: 7}},
+		},
+		{
+			name: "plain error",
+			err:  errors.New("boom"),
+			want: &Error{Code: CodeServerError, Message: "boom", Data: statusData{Code: "Unknown", Status: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toError(tt.err); *got != *tt.want {
+				t.Errorf("toError() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	s := NewServer(NewClient(testService{}))
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "say hello",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"greeter.sayHello","params":{"name":"Ann","style":"formal"}}`,
+			want: `{"jsonrpc":"2.0","id":1,"result":{"message":"Hello Ann","greeting":{"id":7,"name":"Ann","style":"formal","message":"Hello Ann","issuedAt":"2024-01-02T03:04:05Z"}}}`,
+		},
+		{
+			name: "say hello error",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"greeter.sayHello","params":{"name":"nobody"}}`,
+			want: `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"error occurred: rpc error: code = InvalidArgument desc = no one to greet","data":{"grpcCode":"InvalidArgument","grpcStatus":3}}}`,
+		},
+		{
+			name: "say hello invalid params",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"greeter.sayHello","params":["Ann"]}`,
+			want: `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid params: json: cannot unmarshal array into Go value of type jsonrpc.SayHelloParams"}}`,
+		},
+		{
+			name: "greetings",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"greeter.greetings","params":{"after":1,"limit":1}}`,
+			want: `{"jsonrpc":"2.0","id":1,"result":{"greetings":[{"id":2,"name":"name","message":"","issuedAt":"1970-01-01T00:00:00Z"}],"hasMore":true,"total":3}}`,
+		},
+		{
+			name: "no greetings",
+			in:   `{"jsonrpc":"2.0","id":1,"method":"greeter.greetings","params":{"after":3}}`,
+			want: `{"jsonrpc":"2.0","id":1,"result":{"greetings":[],"hasMore":false,"total":3}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serve(t, s, tt.in)
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("Serve() wrote\n%s\nwant\n%s", strings.Join(got, "\n"), tt.want)
+			}
+		})
+	}
+}
+
+func TestClientForward(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewClient(testService{})
+	s := NewServer(c)
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	if err := s.Serve(ctx, strings.NewReader(""), pw); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	// greetings are only forwarded while subscribed
+	c.Observe(service.Greeting{ID: 1})
+	if _, err := c.subscribe(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.Observe(service.Greeting{ID: 2, Name: "Ann", Message: "Hello Ann", IssuedAt: time.Unix(0, 0).UTC()})
+	if _, err := c.unsubscribe(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.Observe(service.Greeting{ID: 3})
+
+	go c.Forward(ctx)
+
+	got, err := bufio.NewReader(pr).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"jsonrpc":"2.0","method":"greeter.greeting","params":{"id":2,"name":"Ann","message":"Hello Ann","issuedAt":"1970-01-01T00:00:00Z"}}` + "\n"
+	if got != want {
+		t.Errorf("Forward() wrote %q, want %q", got, want)
+	}
+	if n := len(c.greetings); n != 0 {
+		t.Errorf("pending greetings = %d, want 0", n)
+	}
+}
@@ -0,0 +1,157 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/LewisJAllan/greeter/listeners/jsonrpc"
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// protocolVersions lists the Model Context Protocol revisions supported, newest first.
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+type Service interface {
+	Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+	Greetings(ctx context.Context, request service.GreetingsRequest) (service.GreetingsResponse, error)
+}
+
+// Client serves the Greeter as a Model Context Protocol tool server on a jsonrpc.Server.
+type Client struct {
+	service Service
+	name    string
+	version string
+	tools   []tool
+}
+
+func NewClient(service Service, name, version string) *Client {
+	c := &Client{
+		service: service,
+		name:    name,
+		version: version,
+	}
+	c.tools = c.greeterTools()
+	return c
+}
+
+func (c *Client) Register(s *jsonrpc.Server) {
+	s.Handle("initialize", c.initialize)
+	s.Handle("notifications/initialized", noop)
+	s.Handle("notifications/cancelled", noop)
+	s.Handle("ping", noop)
+	s.Handle("tools/list", c.listTools)
+	s.Handle("tools/call", c.callTool)
+}
+
+func noop(context.Context, json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+type initializeParams struct {
+	ProtocolVersion string `json:"protocolVersion"`
+}
+
+type implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type initializeResult struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ServerInfo      implementation `json:"serverInfo"`
+	Instructions    string         `json:"instructions,omitempty"`
+}
+
+func (c *Client) initialize(_ context.Context, params json.RawMessage) (any, error) {
+	var p initializeParams
+	if err := jsonrpc.DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	// answer with the client's version when supported, otherwise the latest and let the client decide
+	version := protocolVersions[0]
+	if slices.Contains(protocolVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+
+	return initializeResult{
+		ProtocolVersion: version,
+		Capabilities: map[string]any{
+			"tools": map[string]any{"listChanged": false},
+		},
+		ServerInfo:   implementation{Name: c.name, Version: c.version},
+		Instructions: "Use say_hello to greet someone and list_greetings to read the greetings issued so far.",
+	}, nil
+}
+
+type toolDescription struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type listToolsResult struct {
+	Tools []toolDescription `json:"tools"`
+}
+
+func (c *Client) listTools(context.Context, json.RawMessage) (any, error) {
+	result := listToolsResult{Tools: make([]toolDescription, 0, len(c.tools))}
+	for _, t := range c.tools {
+		result.Tools = append(result.Tools, toolDescription{
+			Name:        t.name,
+			Description: t.description,
+			InputSchema: t.inputSchema,
+		})
+	}
+	return result, nil
+}
+
+type callToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type callToolResult struct {
+	Content           []content `json:"content"`
+	StructuredContent any       `json:"structuredContent,omitempty"`
+	IsError           bool      `json:"isError,omitempty"`
+}
+
+// callTool runs a tool.  Failures of the tool itself are reported in the result so the model can see them, only
+// unknown tools and malformed requests are protocol errors.
+func (c *Client) callTool(ctx context.Context, params json.RawMessage) (any, error) {
+	var p callToolParams
+	if err := jsonrpc.DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	i := slices.IndexFunc(c.tools, func(t tool) bool { return t.name == p.Name })
+	if i < 0 {
+		return nil, jsonrpc.NewError(jsonrpc.CodeInvalidParams, "unknown tool %q", p.Name)
+	}
+
+	out, err := c.tools[i].call(ctx, p.Arguments)
+	if err != nil {
+		return callToolResult{
+			Content: []content{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}, nil
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: unable to encode tool result: %w", err)
+	}
+	return callToolResult{
+		Content:           []content{{Type: "text", Text: string(b)}},
+		StructuredContent: out,
+	}, nil
+}
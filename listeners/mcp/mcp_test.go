@@ -0,0 +1,195 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/LewisJAllan/greeter/listeners/jsonrpc"
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// testService greets anyone but "nobody" and holds three greetings in its history.
+type testService struct{}
+
+func (testService) Respond(_ context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	if request.OriginalMessage == "nobody" {
+		return service.RespondResponse{}, errors.New("no one to greet")
+	}
+	return service.RespondResponse{
+		ResponseMessage: "Hello " + request.OriginalMessage,
+		Greeting: service.Greeting{
+			ID:       7,
+			Name:     request.OriginalMessage,
+			Message:  "Hello " + request.OriginalMessage,
+			IssuedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}, nil
+}
+
+func (testService) Greetings(_ context.Context, request service.GreetingsRequest) (service.GreetingsResponse, error) {
+	var greetings []service.Greeting
+	for id := request.After + 1; id <= 3; id++ {
+		if request.Limit > 0 && len(greetings) == request.Limit {
+			return service.GreetingsResponse{Greetings: greetings, HasMore: true, Total: 3}, nil
+		}
+		greetings = append(greetings, service.Greeting{ID: id, Name: "name", IssuedAt: time.Unix(0, 0).UTC()})
+	}
+	return service.GreetingsResponse{Greetings: greetings, Total: 3}, nil
+}
+
+// call sends a single request to a server registering the Client, returning the response line.
+func call(t *testing.T, method, params string) string {
+	t.Helper()
+	s := jsonrpc.NewServer(NewClient(testService{}, "greeter", "v1"))
+	in := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":%q,"params":%s}`, method, params)
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+func TestInitialize(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		wantVersion string
+	}{
+		{name: "latest", version: "2025-06-18", wantVersion: "2025-06-18"},
+		{name: "older", version: "2024-11-05", wantVersion: "2024-11-05"},
+		{name: "unsupported", version: "2023-01-01", wantVersion: "2025-06-18"},
+		{name: "missing", wantVersion: "2025-06-18"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := call(t, "initialize", fmt.Sprintf(`{"protocolVersion":%q,"capabilities":{}}`, tt.version))
+			want := `{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"` + tt.wantVersion + `","capabilities":{"tools":{"listChanged":false}},` +
+				`"serverInfo":{"name":"greeter","version":"v1"},"instructions":"Use say_hello to greet someone and list_greetings to read the greetings issued so far."}}`
+			if got != want {
+				t.Errorf("initialize =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	if got, want := call(t, "ping", "{}"), `{"jsonrpc":"2.0","id":1,"result":{}}`; got != want {
+		t.Errorf("ping = %s, want %s", got, want)
+	}
+}
+
+func TestListTools(t *testing.T) {
+	got := call(t, "tools/list", "{}")
+	want := `{"jsonrpc":"2.0","id":1,"result":{"tools":[` +
+		`{"name":"say_hello","description":"Greet someone by name, recording the greeting in the history.",` +
+		`"inputSchema":{"additionalProperties":false,"properties":{"name":{"minLength":1,"type":"string"}},"required":["name"],"type":"object"}},` +
+		`{"name":"list_greetings","description":"List the greetings issued so far, oldest first.  Pass the id of the last greeting seen as after to read the next page.",` +
+		`"inputSchema":{"additionalProperties":false,"properties":{"after":{"description":"Only list greetings with a greater id.","minimum":0,"type":"integer"},` +
+		`"limit":{"description":"The most greetings to list, defaults to 100.","maximum":100,"minimum":1,"type":"integer"}},"type":"object"}}]}}`
+	if got != want {
+		t.Errorf("tools/list =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCallTool(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{
+			name:   "say hello",
+			params: `{"name":"say_hello","arguments":{"name":"Ann"}}`,
+			want: `{"content":[{"type":"text","text":"{\"message\":\"Hello Ann\",\"greeting\":{\"id\":7,\"name\":\"Ann\",\"message\":\"Hello Ann\",\"issuedAt\":\"2024-01-02T03:04:05Z\"}}"}],` +
+				`"structuredContent":{"message":"Hello Ann","greeting":{"id":7,"name":"Ann","message":"Hello Ann","issuedAt":"2024-01-02T03:04:05Z"}}}`,
+		},
+		{
+			name:   "say hello without a name",
+			params: `{"name":"say_hello","arguments":{}}`,
+			want:   `{"content":[{"type":"text","text":"invalid arguments: name is required"}],"isError":true}`,
+		},
+		{
+			name:   "say hello without arguments",
+			params: `{"name":"say_hello"}`,
+			want:   `{"content":[{"type":"text","text":"invalid arguments: name is required"}],"isError":true}`,
+		},
+		{
+			name:   "say hello service error",
+			params: `{"name":"say_hello","arguments":{"name":"nobody"}}`,
+			want:   `{"content":[{"type":"text","text":"error occurred: no one to greet"}],"isError":true}`,
+		},
+		{
+			name:   "list greetings",
+			params: `{"name":"list_greetings","arguments":{"after":1,"limit":1}}`,
+			want: `{"content":[{"type":"text","text":"{\"greetings\":[{\"id\":2,\"name\":\"name\",\"message\":\"\",\"issuedAt\":\"1970-01-01T00:00:00Z\"}],\"hasMore\":true,\"total\":3}"}],` +
+				`"structuredContent":{"greetings":[{"id":2,"name":"name","message":"","issuedAt":"1970-01-01T00:00:00Z"}],"hasMore":true,"total":3}}`,
+		},
+		{
+			name:   "list no greetings",
+			params: `{"name":"list_greetings","arguments":{"after":3}}`,
+			want:   `{"content":[{"type":"text","text":"{\"greetings\":[],\"hasMore\":false,\"total\":3}"}],"structuredContent":{"greetings":[],"hasMore":false,"total":3}}`,
+		},
+		{
+			name:   "list greetings invalid arguments",
+			params: `{"name":"list_greetings","arguments":{"after":"one"}}`,
+			want:   `{"content":[{"type":"text","text":"invalid arguments: json: cannot unmarshal string into Go struct field listGreetingsArguments.after of type uint64"}],"isError":true}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := call(t, "tools/call", tt.params)
+			if want := `{"jsonrpc":"2.0","id":1,"result":` + tt.want + `}`; got != want {
+				t.Errorf("tools/call =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestCallToolProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{
+			name:   "unknown tool",
+			params: `{"name":"say_goodbye"}`,
+			want:   `{"code":-32602,"message":"unknown tool \"say_goodbye\""}`,
+		},
+		{
+			name:   "invalid params",
+			params: `["say_hello"]`,
+			want:   `{"code":-32602,"message":"invalid params: json: cannot unmarshal array into Go value of type mcp.callToolParams"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := call(t, "tools/call", tt.params)
+			if want := `{"jsonrpc":"2.0","id":1,"error":` + tt.want + `}`; got != want {
+				t.Errorf("tools/call =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestInputSchema(t *testing.T) {
+	// HelloRequest has a single required string
+	hello := fmt.Sprint(inputSchema((&schemas.HelloRequest{}).ProtoReflect().Descriptor()))
+	if want := "map[additionalProperties:false properties:map[name:map[minLength:1 type:string]] required:[name] type:object]"; hello != want {
+		t.Errorf("inputSchema(HelloRequest) = %s, want %s", hello, want)
+	}
+
+	// 64 bit integers are left out and nothing is required
+	duration := fmt.Sprint(inputSchema((&durationpb.Duration{}).ProtoReflect().Descriptor()))
+	if want := "map[additionalProperties:false properties:map[nanos:map[type:integer]] type:object]"; duration != want {
+		t.Errorf("inputSchema(Duration) = %s, want %s", duration, want)
+	}
+}
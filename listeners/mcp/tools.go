@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// maxGreetings mirrors the largest page service.Service returns.
+const maxGreetings = 100
+
+type tool struct {
+	name        string
+	description string
+	inputSchema map[string]any
+	call        func(ctx context.Context, arguments json.RawMessage) (any, error)
+}
+
+func (c *Client) greeterTools() []tool {
+	return []tool{
+		{
+			name:        "say_hello",
+			description: "Greet someone by name, recording the greeting in the history.",
+			inputSchema: inputSchema((&schemas.HelloRequest{}).ProtoReflect().Descriptor()),
+			call:        c.sayHello,
+		},
+		{
+			name:        "list_greetings",
+			description: "List the greetings issued so far, oldest first.  Pass the id of the last greeting seen as after to read the next page.",
+			inputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"after": map[string]any{
+						"type":        "integer",
+						"minimum":     0,
+						"description": "Only list greetings with a greater id.",
+					},
+					"limit": map[string]any{
+						"type":        "integer",
+						"minimum":     1,
+						"maximum":     maxGreetings,
+						"description": "The most greetings to list, defaults to 100.",
+					},
+				},
+				"additionalProperties": false,
+			},
+			call: c.listGreetings,
+		},
+	}
+}
+
+type greeting struct {
+	ID       uint64    `json:"id"`
+	Name     string    `json:"name"`
+	Message  string    `json:"message"`
+	IssuedAt time.Time `json:"issuedAt"`
+}
+
+func newGreeting(g service.Greeting) greeting {
+	return greeting{
+		ID:       g.ID,
+		Name:     g.Name,
+		Message:  g.Message,
+		IssuedAt: g.IssuedAt,
+	}
+}
+
+type sayHelloResult struct {
+	Message  string   `json:"message"`
+	Greeting greeting `json:"greeting"`
+}
+
+// sayHello decodes the arguments as a HelloRequest so they are checked against the same descriptor the input schema
+// was derived from.
+func (c *Client) sayHello(ctx context.Context, arguments json.RawMessage) (any, error) {
+	req := &schemas.HelloRequest{}
+	if len(arguments) > 0 && string(arguments) != "null" {
+		if err := protojson.Unmarshal(arguments, req); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+	if req.GetName() == "" {
+		return nil, fmt.Errorf("invalid arguments: name is required")
+	}
+
+	resp, err := c.service.Respond(ctx, service.RespondRequest{
+		OriginalMessage: req.GetName(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error occurred: %w", err)
+	}
+
+	return sayHelloResult{
+		Message:  resp.ResponseMessage,
+		Greeting: newGreeting(resp.Greeting),
+	}, nil
+}
+
+type listGreetingsArguments struct {
+	After uint64 `json:"after"`
+	Limit int    `json:"limit"`
+}
+
+type listGreetingsResult struct {
+	Greetings []greeting `json:"greetings"`
+	HasMore   bool       `json:"hasMore"`
+	Total     int        `json:"total"`
+}
+
+func (c *Client) listGreetings(ctx context.Context, arguments json.RawMessage) (any, error) {
+	var args listGreetingsArguments
+	if len(arguments) > 0 && string(arguments) != "null" {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	resp, err := c.service.Greetings(ctx, service.GreetingsRequest{After: args.After, Limit: args.Limit})
+	if err != nil {
+		return nil, fmt.Errorf("error occurred: %w", err)
+	}
+
+	result := listGreetingsResult{
+		Greetings: make([]greeting, 0, len(resp.Greetings)),
+		HasMore:   resp.HasMore,
+		Total:     resp.Total,
+	}
+	for _, g := range resp.Greetings {
+		result.Greetings = append(result.Greetings, newGreeting(g))
+	}
+	return result, nil
+}
+
+// inputSchema describes md as the JSON Schema of its protojson encoding.  Singular string fields are required since
+// the service has nothing to answer an empty one with.
+func inputSchema(md protoreflect.MessageDescriptor) map[string]any {
+	properties := map[string]any{}
+	var required []string
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		s := fieldSchema(fd)
+		if s == nil {
+			continue
+		}
+		properties[fd.JSONName()] = s
+		if fd.Kind() == protoreflect.StringKind && fd.Cardinality() != protoreflect.Repeated {
+			required = append(required, fd.JSONName())
+		}
+	}
+
+	out := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor) map[string]any {
+	if fd.IsMap() {
+		return nil
+	}
+
+	var s map[string]any
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		s = map[string]any{"type": "string", "minLength": 1}
+	case protoreflect.BoolKind:
+		s = map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		s = map[string]any{"type": "integer"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		s = map[string]any{"type": "number"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		s = map[string]any{"type": "string", "enum": names}
+	default:
+		// 64 bit integers, bytes and nested messages are not expected from a model
+		return nil
+	}
+
+	if fd.IsList() {
+		return map[string]any{"type": "array", "items": s}
+	}
+	return s
+}
@@ -0,0 +1,300 @@
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+type ListenConfig interface {
+	Listen(ctx context.Context, net, addr string) (net.Listener, error)
+}
+
+type Service interface {
+	Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+}
+
+type options struct {
+	addr         string
+	idleTimeout  time.Duration
+	writeTimeout time.Duration
+	maxLineBytes int
+	maxConns     int
+	wrapListener func(net.Listener) net.Listener
+}
+
+type Option func(o *options)
+
+func defaultOpts() options {
+	return options{
+		addr:         ":7070",
+		idleTimeout:  time.Minute * 5,
+		writeTimeout: time.Second * 10,
+		maxLineBytes: 1024,
+		maxConns:     256,
+	}
+}
+
+// WithAddr sets the address the Handler listens on.  Defaults to :7070.
+func WithAddr(addr string) Option {
+	return func(o *options) {
+		o.addr = addr
+	}
+}
+
+// WithIdleTimeout sets how long a connection may wait between lines before it is closed.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// WithMaxLineBytes sets the longest line accepted, excluding the line ending.  Longer lines close the connection.
+func WithMaxLineBytes(n int) Option {
+	return func(o *options) {
+		o.maxLineBytes = n
+	}
+}
+
+// WithMaxConnections sets how many connections are served at once.  Further connections are refused.
+func WithMaxConnections(n int) Option {
+	return func(o *options) {
+		o.maxConns = n
+	}
+}
+
+// WithListenerWrapper wraps the listener the Handler accepts connections from, such as to close unwanted ones early.
+func WithListenerWrapper(wrap func(net.Listener) net.Listener) Option {
+	return func(o *options) {
+		o.wrapListener = wrap
+	}
+}
+
+// Handler is an app.Runner serving a plain-text line protocol: each line received is a name and each line sent back
+// is its greeting.  Errors are sent as lines starting with "ERR " followed by the name of their gRPC code, such as
+// "ERR InvalidArgument name is required", so that clients can tell a bad request from a fault of the server.
+type Handler struct {
+	service Service
+	opts    options
+
+	listenCfg ListenConfig
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	draining bool
+	wg       sync.WaitGroup
+}
+
+func New(service Service, opts ...Option) *Handler {
+	o := defaultOpts()
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Handler{
+		service:   service,
+		opts:      o,
+		listenCfg: &net.ListenConfig{},
+		conns:     map[net.Conn]struct{}{},
+	}
+}
+
+func (h *Handler) Start(ctx context.Context) error {
+	l, err := h.listenCfg.Listen(ctx, "tcp", h.opts.addr)
+	if err != nil {
+		return fmt.Errorf("tcp: unable to create listener: %w", err)
+	}
+	if h.opts.wrapListener != nil {
+		l = h.opts.wrapListener(l)
+	}
+
+	h.mu.Lock()
+	h.listener = l
+	h.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			h.mu.Lock()
+			draining := h.draining
+			h.mu.Unlock()
+
+			if draining {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return fmt.Errorf("tcp: unable to accept connection: %w", err)
+		}
+
+		if !h.track(c) {
+			_ = c.SetWriteDeadline(time.Now().Add(h.opts.writeTimeout))
+			_, _ = fmt.Fprintf(c, "ERR %s too many connections\n", codes.ResourceExhausted)
+			_ = c.Close()
+			continue
+		}
+
+		go h.serve(ctx, c)
+	}
+}
+
+// track registers c, reporting false when the connection limit is reached or the Handler is draining.
+func (h *Handler) track(c net.Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.draining || len(h.conns) >= h.opts.maxConns {
+		return false
+	}
+	h.conns[c] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+func (h *Handler) untrack(c net.Conn) {
+	h.mu.Lock()
+	delete(h.conns, c)
+	h.mu.Unlock()
+
+	_ = c.Close()
+	h.wg.Done()
+}
+
+func (h *Handler) isDraining() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.draining
+}
+
+func (h *Handler) serve(ctx context.Context, c net.Conn) {
+	defer h.untrack(c)
+
+	ctx = zaphelper.With(ctx, zaphelper.FromContext(ctx).With(zap.Stringer("peer", c.RemoteAddr())))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: c.RemoteAddr(), LocalAddr: c.LocalAddr()})
+	r := bufio.NewReaderSize(c, h.opts.maxLineBytes+2)
+
+	for !h.isDraining() {
+		_ = c.SetReadDeadline(time.Now().Add(h.opts.idleTimeout))
+		line, err := readLine(r, h.opts.maxLineBytes)
+		switch {
+		case errors.Is(err, errLineTooLong):
+			h.write(ctx, c, fmt.Sprintf("ERR %s line too long", codes.InvalidArgument))
+			return
+		case err != nil:
+			if !errors.Is(err, io.EOF) && !h.isDraining() {
+				zaphelper.Debug(ctx, "tcp connection ended", zap.Error(err))
+			}
+			return
+		}
+
+		name := strings.TrimSpace(line)
+		if name == "" {
+			continue
+		}
+
+		resp, err := h.service.Respond(ctx, service.RespondRequest{
+			OriginalMessage: name,
+		})
+		if err != nil {
+			h.write(ctx, c, errorLine(ctx, err))
+			continue
+		}
+
+		if !h.write(ctx, c, resp.ResponseMessage) {
+			return
+		}
+	}
+}
+
+// errorLine formats err as "ERR <code> <message>", where code is the name of its gRPC code such as InvalidArgument.
+// Errors the service did not give a status to are server faults, whose details are logged rather than sent.
+func errorLine(ctx context.Context, err error) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		zaphelper.Error(ctx, "tcp greeting failed", zap.Error(err))
+		st = status.New(codes.Internal, "internal error")
+	}
+	return fmt.Sprintf("ERR %s %s", st.Code(), st.Message())
+}
+
+func (h *Handler) write(ctx context.Context, c net.Conn, line string) bool {
+	_ = c.SetWriteDeadline(time.Now().Add(h.opts.writeTimeout))
+	if _, err := io.WriteString(c, line+"\n"); err != nil {
+		zaphelper.Debug(ctx, "unable to write tcp response", zap.Error(err))
+		return false
+	}
+	return true
+}
+
+var errLineTooLong = errors.New("tcp: line too long")
+
+// readLine reads a line ending in \n or \r\n, failing once more than max bytes have been read without a line ending.
+func readLine(r *bufio.Reader, max int) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) || len(strings.TrimRight(string(line), "\r\n")) > max {
+		return "", errLineTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// Stop stops accepting connections and lets each connection finish the line it is handling.  Connections still open
+// when ctx is done are closed.
+func (h *Handler) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	h.draining = true
+	l := h.listener
+	for c := range h.conns {
+		// wake connections waiting for their next line, a line already being handled is still answered
+		_ = c.SetReadDeadline(time.Now())
+	}
+	h.mu.Unlock()
+
+	if l == nil {
+		return nil
+	}
+	err := l.Close()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		h.mu.Lock()
+		for c := range h.conns {
+			_ = c.Close()
+		}
+		h.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (h *Handler) Name() string {
+	return "tcp"
+}
@@ -0,0 +1,227 @@
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func TestReadLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr error
+	}{
+		{name: "lines", in: "Ann\nBob\n", want: []string{"Ann", "Bob"}, wantErr: io.EOF},
+		{name: "crlf", in: "Ann\r\nBob\r\n", want: []string{"Ann", "Bob"}, wantErr: io.EOF},
+		{name: "at the limit", in: "12345678\n", want: []string{"12345678"}, wantErr: io.EOF},
+		{name: "at the limit with crlf", in: "12345678\r\n", want: []string{"12345678"}, wantErr: io.EOF},
+		{name: "too long", in: "123456789\n", wantErr: errLineTooLong},
+		{name: "too long without an ending", in: "1234567890123", wantErr: errLineTooLong},
+		{name: "unterminated", in: "Ann", wantErr: io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReaderSize(strings.NewReader(tt.in), 10)
+			var got []string
+			for {
+				line, err := readLine(r, 8)
+				if err != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("readLine() error = %v, want %v", err, tt.wantErr)
+					}
+					break
+				}
+				got = append(got, line)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("readLine() lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type respondFunc func(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+
+func (f respondFunc) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	return f(ctx, request)
+}
+
+// listenConfig listens on a free port of the loopback interface, sending the listener on ready.
+type listenConfig struct {
+	ready chan net.Listener
+}
+
+func (lc listenConfig) Listen(ctx context.Context, network, _ string) (net.Listener, error) {
+	l, err := (&net.ListenConfig{}).Listen(ctx, network, "127.0.0.1:0")
+	if err == nil {
+		lc.ready <- l
+	}
+	return l, err
+}
+
+// start starts h, returning the address it listens on.  h is stopped when the test ends.
+func start(t *testing.T, h *Handler) string {
+	t.Helper()
+	lc := listenConfig{ready: make(chan net.Listener, 1)}
+	h.listenCfg = lc
+
+	done := make(chan error, 1)
+	go func() { done <- h.Start(context.Background()) }()
+	l := <-lc.ready
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		if err := h.Stop(ctx); err != nil {
+			t.Errorf("Stop() error = %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	})
+	return l.Addr().String()
+}
+
+func dial(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	_ = c.SetDeadline(time.Now().Add(time.Second * 5))
+	return c, bufio.NewReader(c)
+}
+
+func TestHandler(t *testing.T) {
+	h := New(respondFunc(func(_ context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+		switch request.OriginalMessage {
+		case "nobody":
+			return service.RespondResponse{}, status.Error(codes.InvalidArgument, "no one to greet")
+		case "crash":
+			return service.RespondResponse{}, errors.New("database password is hunter2")
+		}
+		return service.RespondResponse{ResponseMessage: "Hello " + request.OriginalMessage}, nil
+	}), WithMaxLineBytes(16))
+	c, r := dial(t, start(t, h))
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "Ann\n", want: "Hello Ann"},
+		{line: "  \n\r\n  Bob \r\n", want: "Hello Bob"},
+		{line: "nobody\n", want: "ERR InvalidArgument no one to greet"},
+		{line: "crash\n", want: "ERR Internal internal error"},
+		{line: "Ann\n", want: "Hello Ann"},
+		{line: strings.Repeat("a", 17) + "\n", want: "ERR InvalidArgument line too long"},
+	}
+	for _, tt := range tests {
+		if _, err := io.WriteString(c, tt.line); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading the answer to %q: %v", tt.line, err)
+		}
+		if got = strings.TrimSuffix(got, "\n"); got != tt.want {
+			t.Errorf("answer to %q = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	// a line too long closes the connection
+	if _, err := r.ReadString('\n'); !errors.Is(err, io.EOF) {
+		t.Errorf("read after a line too long error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestHandlerMaxConnections(t *testing.T) {
+	h := New(respondFunc(func(_ context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+		return service.RespondResponse{ResponseMessage: "Hello " + request.OriginalMessage}, nil
+	}), WithMaxConnections(1))
+	addr := start(t, h)
+
+	c, r := dial(t, addr)
+	// answered once the first connection is tracked
+	if _, err := io.WriteString(c, "Ann\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	_, r2 := dial(t, addr)
+	if got, _ := r2.ReadString('\n'); got != "ERR ResourceExhausted too many connections\n" {
+		t.Errorf("second connection = %q, want it refused", got)
+	}
+}
+
+func TestHandlerStop(t *testing.T) {
+	h := New(respondFunc(func(_ context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+		return service.RespondResponse{ResponseMessage: "Hello " + request.OriginalMessage}, nil
+	}))
+	lc := listenConfig{ready: make(chan net.Listener, 1)}
+	h.listenCfg = lc
+	done := make(chan error, 1)
+	go func() { done <- h.Start(context.Background()) }()
+
+	c, r := dial(t, (<-lc.ready).Addr().String())
+	if _, err := io.WriteString(c, "Ann\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := h.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+	// the connection, idle by now, is closed
+	if _, err := r.ReadString('\n'); !errors.Is(err, io.EOF) {
+		t.Errorf("read after Stop error = %v, want %v", err, io.EOF)
+	}
+}
+
+// closingListener closes every connection it accepts, as a filter blocking every peer does.
+type closingListener struct {
+	net.Listener
+}
+
+func (l closingListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		_ = c.Close()
+	}
+}
+
+func TestHandlerListenerWrapper(t *testing.T) {
+	h := New(respondFunc(func(_ context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+		return service.RespondResponse{ResponseMessage: "Hello " + request.OriginalMessage}, nil
+	}), WithListenerWrapper(func(l net.Listener) net.Listener { return closingListener{Listener: l} }))
+	c, r := dial(t, start(t, h))
+
+	_, _ = io.WriteString(c, "Ann\n")
+	if got, err := r.ReadString('\n'); err == nil {
+		t.Errorf("answer = %q, want the connection closed by the wrapped listener", got)
+	}
+}
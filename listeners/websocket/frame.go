@@ -0,0 +1,293 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the subset of RFC 6455 needed to serve JSON text frames
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+
+	closeNormal          = 1000
+	closeGoingAway       = 1001
+	closeProtocolError   = 1002
+	closeUnsupportedData = 1003
+	closePolicyViolation = 1008
+	closeMessageTooBig   = 1009
+
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+var errClosed = errors.New("websocket: connection closed")
+
+// closeError is returned by readMessage when the peer sends a close frame.
+type closeError struct {
+	code   int
+	reason string
+}
+
+func (e *closeError) Error() string {
+	return fmt.Sprintf("websocket: closed by peer: %d %s", e.code, e.reason)
+}
+
+// conn is a server side websocket connection.  Reads must happen from a single goroutine, writes are safe for
+// concurrent use.
+type conn struct {
+	c  net.Conn
+	br *bufio.Reader
+
+	maxMessageBytes int64
+
+	writeM  sync.Mutex
+	closed  bool
+	writeTO time.Duration
+}
+
+// upgrade performs the opening handshake and takes over the underlying connection.
+func upgrade(w http.ResponseWriter, r *http.Request, maxMessageBytes int64, writeTimeout time.Duration) (*conn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "websocket: method not allowed", http.StatusMethodNotAllowed)
+		return nil, errors.New("websocket: method not allowed")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket: upgrade required", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "websocket: unsupported version", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "websocket: missing key", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing key")
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket: hijacking not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response writer does not support hijacking")
+	}
+	c, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: unable to hijack connection: %w", err)
+	}
+
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+
+	_ = c.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.Write([]byte(response)); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("websocket: unable to complete handshake: %w", err)
+	}
+
+	return &conn{
+		c:               c,
+		br:              brw.Reader,
+		maxMessageBytes: maxMessageBytes,
+		writeTO:         writeTimeout,
+	}, nil
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readMessage returns the next data message, answering pings and collecting fragments along the way.  onFrame is
+// called for every frame received so callers can extend read deadlines.
+func (c *conn) readMessage(onFrame func()) (int, []byte, error) {
+	var (
+		opcode  int
+		message []byte
+	)
+
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		onFrame()
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			ce := &closeError{code: closeNormal}
+			if len(payload) >= 2 {
+				ce.code = int(binary.BigEndian.Uint16(payload))
+				ce.reason = string(payload[2:])
+			}
+			_ = c.close(ce.code, "")
+			return 0, nil, ce
+		case opContinuation:
+			if opcode == 0 {
+				return 0, nil, c.fail(closeProtocolError, "unexpected continuation frame")
+			}
+		case opText, opBinary:
+			if opcode != 0 {
+				return 0, nil, c.fail(closeProtocolError, "expected continuation frame")
+			}
+			opcode = op
+		default:
+			return 0, nil, c.fail(closeProtocolError, "unknown opcode")
+		}
+
+		if int64(len(message)+len(payload)) > c.maxMessageBytes {
+			return 0, nil, c.fail(closeMessageTooBig, "message too big")
+		}
+		message = append(message, payload...)
+
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *conn) readFrame() (bool, int, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(closeProtocolError, "reserved bits set")
+	}
+	op := int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if !masked {
+		return false, 0, nil, c.fail(closeProtocolError, "client frames must be masked")
+	}
+	if op >= opClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(closeProtocolError, "invalid control frame")
+	}
+	if length < 0 || length > c.maxMessageBytes {
+		return false, 0, nil, c.fail(closeMessageTooBig, "frame too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, op, payload, nil
+}
+
+func (c *conn) writeFrame(op int, payload []byte) error {
+	c.writeM.Lock()
+	defer c.writeM.Unlock()
+
+	if c.closed {
+		return errClosed
+	}
+	return c.writeFrameLocked(op, payload)
+}
+
+func (c *conn) writeFrameLocked(op int, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | byte(op)
+
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	_ = c.c.SetWriteDeadline(time.Now().Add(c.writeTO))
+	if _, err := c.c.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *conn) writeText(b []byte) error {
+	return c.writeFrame(opText, b)
+}
+
+// close sends a close frame.  Further writes fail but the connection stays open so the peer's close frame can be read.
+func (c *conn) close(code int, reason string) error {
+	c.writeM.Lock()
+	defer c.writeM.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	return c.writeFrameLocked(opClose, payload)
+}
+
+// fail sends a close frame with code and returns an error describing the failure.
+func (c *conn) fail(code int, reason string) error {
+	_ = c.close(code, reason)
+	return fmt.Errorf("websocket: %s", reason)
+}
@@ -0,0 +1,303 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clientFrame encodes a frame as a client sends it, masked unless told otherwise.
+func clientFrame(fin bool, op int, payload []byte, masked bool) []byte {
+	b := []byte{byte(op), 0}
+	if fin {
+		b[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		b[1] = byte(n)
+	case n <= 0xffff:
+		b[1] = 126
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b[1] = 127
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	if !masked {
+		return append(b, payload...)
+	}
+
+	b[1] |= 0x80
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	b = append(b, mask...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+type serverFrame struct {
+	op      int
+	payload []byte
+}
+
+// readServerFrame decodes an unmasked frame written by the server.
+func readServerFrame(t *testing.T, r io.Reader) serverFrame {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		t.Fatalf("server frame header %x, want fin set and no mask", header)
+	}
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			t.Fatal(err)
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			t.Fatal(err)
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	return serverFrame{op: int(header[0] & 0x0f), payload: payload}
+}
+
+func readServerFrames(t *testing.T, b []byte) []serverFrame {
+	t.Helper()
+	var frames []serverFrame
+	for r := bytes.NewReader(b); r.Len() > 0; {
+		frames = append(frames, readServerFrame(t, r))
+	}
+	return frames
+}
+
+// fakeConn reads what the client sent and records what the server writes.
+type fakeConn struct {
+	net.Conn
+	written bytes.Buffer
+}
+
+func (c *fakeConn) Write(b []byte) (int, error)      { return c.written.Write(b) }
+func (c *fakeConn) SetWriteDeadline(time.Time) error { return nil }
+func (c *fakeConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *fakeConn) Close() error                     { return nil }
+
+func newTestConn(in []byte, max int64) (*conn, *fakeConn) {
+	fc := &fakeConn{}
+	return &conn{c: fc, br: bufio.NewReader(bytes.NewReader(in)), maxMessageBytes: max, writeTO: time.Second}, fc
+}
+
+func TestConnReadMessage(t *testing.T) {
+	join := func(frames ...[]byte) []byte { return bytes.Join(frames, nil) }
+	long := bytes.Repeat([]byte("a"), 200)
+
+	tests := []struct {
+		name        string
+		in          []byte
+		wantOp      int
+		wantMessage string
+		// wantClose is the code of the close frame the server sends, when it sends one
+		wantClose int
+		// wantPong is the payload of the pong the server answers with, when it answers a ping
+		wantPong string
+	}{
+		{name: "text", in: clientFrame(true, opText, []byte("hello"), true), wantOp: opText, wantMessage: "hello"},
+		{name: "binary", in: clientFrame(true, opBinary, []byte{1, 2}, true), wantOp: opBinary, wantMessage: "\x01\x02"},
+		{name: "empty", in: clientFrame(true, opText, nil, true), wantOp: opText, wantMessage: ""},
+		{name: "16 bit length", in: clientFrame(true, opText, long, true), wantOp: opText, wantMessage: string(long)},
+		{
+			name: "fragmented",
+			in: join(
+				clientFrame(false, opText, []byte("hel"), true),
+				clientFrame(false, opContinuation, []byte("l"), true),
+				clientFrame(true, opContinuation, []byte("o"), true),
+			),
+			wantOp:      opText,
+			wantMessage: "hello",
+		},
+		{
+			name: "ping between fragments",
+			in: join(
+				clientFrame(false, opText, []byte("hel"), true),
+				clientFrame(true, opPing, []byte("are you there"), true),
+				clientFrame(true, opContinuation, []byte("lo"), true),
+			),
+			wantOp:      opText,
+			wantMessage: "hello",
+			wantPong:    "are you there",
+		},
+		{
+			name:        "pong ignored",
+			in:          join(clientFrame(true, opPong, nil, true), clientFrame(true, opText, []byte("hello"), true)),
+			wantOp:      opText,
+			wantMessage: "hello",
+		},
+		{name: "unmasked", in: clientFrame(true, opText, []byte("hello"), false), wantClose: closeProtocolError},
+		{name: "reserved bits", in: append([]byte{0xC1}, clientFrame(true, opText, []byte("hello"), true)[1:]...), wantClose: closeProtocolError},
+		{name: "unknown opcode", in: clientFrame(true, 0x3, []byte("hello"), true), wantClose: closeProtocolError},
+		{name: "fragmented control frame", in: clientFrame(false, opPing, nil, true), wantClose: closeProtocolError},
+		{name: "long control frame", in: clientFrame(true, opPing, bytes.Repeat([]byte("a"), 126), true), wantClose: closeProtocolError},
+		{name: "unexpected continuation", in: clientFrame(true, opContinuation, []byte("hello"), true), wantClose: closeProtocolError},
+		{
+			name:      "missing continuation",
+			in:        join(clientFrame(false, opText, []byte("hel"), true), clientFrame(true, opText, []byte("lo"), true)),
+			wantClose: closeProtocolError,
+		},
+		{name: "frame too big", in: clientFrame(true, opText, bytes.Repeat([]byte("a"), 1025), true), wantClose: closeMessageTooBig},
+		{
+			name: "message too big",
+			in: join(
+				clientFrame(false, opText, bytes.Repeat([]byte("a"), 1000), true),
+				clientFrame(true, opContinuation, bytes.Repeat([]byte("a"), 25), true),
+			),
+			wantClose: closeMessageTooBig,
+		},
+		{name: "64 bit length", in: clientFrame(true, opText, bytes.Repeat([]byte("a"), 70000), true), wantClose: closeMessageTooBig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fc := newTestConn(tt.in, 1024)
+			op, message, err := c.readMessage(func() {})
+
+			frames := readServerFrames(t, fc.written.Bytes())
+			if tt.wantClose != 0 {
+				if err == nil {
+					t.Fatalf("readMessage() = %q, want an error", message)
+				}
+				if len(frames) != 1 || frames[0].op != opClose || int(binary.BigEndian.Uint16(frames[0].payload)) != tt.wantClose {
+					t.Errorf("server frames = %v, want a close frame with code %d", frames, tt.wantClose)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("readMessage() error = %v", err)
+			}
+			if op != tt.wantOp || string(message) != tt.wantMessage {
+				t.Errorf("readMessage() = %d %q, want %d %q", op, message, tt.wantOp, tt.wantMessage)
+			}
+			var pong string
+			for _, f := range frames {
+				if f.op == opPong {
+					pong = string(f.payload)
+				}
+			}
+			if pong != tt.wantPong {
+				t.Errorf("pong = %q, want %q", pong, tt.wantPong)
+			}
+		})
+	}
+}
+
+func TestConnReadMessageClosed(t *testing.T) {
+	payload := binary.BigEndian.AppendUint16(nil, closeGoingAway)
+	c, fc := newTestConn(clientFrame(true, opClose, append(payload, "bye"...), true), 1024)
+
+	_, _, err := c.readMessage(func() {})
+	var ce *closeError
+	if !errors.As(err, &ce) || ce.code != closeGoingAway || ce.reason != "bye" {
+		t.Fatalf("readMessage() error = %v, want the close of the peer", err)
+	}
+
+	frames := readServerFrames(t, fc.written.Bytes())
+	if len(frames) != 1 || frames[0].op != opClose || binary.BigEndian.Uint16(frames[0].payload) != closeGoingAway {
+		t.Errorf("server frames = %v, want the close echoed", frames)
+	}
+	if err := c.writeText([]byte("late")); !errors.Is(err, errClosed) {
+		t.Errorf("writeText() after close error = %v, want %v", err, errClosed)
+	}
+}
+
+func TestConnWriteFrame(t *testing.T) {
+	tests := []struct {
+		name       string
+		length     int
+		wantHeader []byte
+	}{
+		{name: "7 bit length", length: 125, wantHeader: []byte{0x81, 125}},
+		{name: "16 bit length", length: 126, wantHeader: []byte{0x81, 126, 0, 126}},
+		{name: "64 bit length", length: 0x10000, wantHeader: []byte{0x81, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fc := newTestConn(nil, 1024)
+			payload := bytes.Repeat([]byte("a"), tt.length)
+			if err := c.writeText(payload); err != nil {
+				t.Fatalf("writeText() error = %v", err)
+			}
+			b := fc.written.Bytes()
+			if !bytes.Equal(b[:len(tt.wantHeader)], tt.wantHeader) || !bytes.Equal(b[len(tt.wantHeader):], payload) {
+				t.Errorf("frame header = %x, want %x followed by the payload", b[:len(tt.wantHeader)], tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestConnCloseReasonTruncated(t *testing.T) {
+	c, fc := newTestConn(nil, 1024)
+	if err := c.close(closePolicyViolation, strings.Repeat("a", 200)); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+	frames := readServerFrames(t, fc.written.Bytes())
+	if len(frames) != 1 || len(frames[0].payload) != 125 {
+		t.Errorf("close frames = %v, want one of 125 bytes", frames)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	valid := http.Header{
+		"Connection":            {"keep-alive, Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+	}
+	without := func(name string) http.Header {
+		h := valid.Clone()
+		h.Del(name)
+		return h
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		header     http.Header
+		wantStatus int
+	}{
+		{name: "not get", method: http.MethodPost, header: valid, wantStatus: http.StatusMethodNotAllowed},
+		{name: "no upgrade", method: http.MethodGet, header: without("Upgrade"), wantStatus: http.StatusUpgradeRequired},
+		{name: "no connection upgrade", method: http.MethodGet, header: without("Connection"), wantStatus: http.StatusUpgradeRequired},
+		{name: "unsupported version", method: http.MethodGet, header: without("Sec-Websocket-Version"), wantStatus: http.StatusUpgradeRequired},
+		{name: "no key", method: http.MethodGet, header: without("Sec-Websocket-Key"), wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/v1/ws", nil)
+			r.Header = tt.header
+			w := httptest.NewRecorder()
+			if _, err := upgrade(w, r, 1024, time.Second); err == nil {
+				t.Fatal("upgrade() error = nil, want an error")
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/ratelimit"
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// inbound is a frame sent by the client.  ID is echoed back on the matching outbound frame.
+type inbound struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type outbound struct {
+	ID      string      `json:"id,omitempty"`
+	Type    string      `json:"type"`
+	Message string      `json:"message,omitempty"`
+	Error   *frameError `json:"error,omitempty"`
+}
+
+type frameError struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message"`
+}
+
+// session serves the frames of a single connection.
+type session struct {
+	c       *conn
+	service Service
+	opts    options
+	limiter *ratelimit.Bucket
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newSession(c *conn, service Service, opts options) *session {
+	return &session{
+		c:       c,
+		service: service,
+		opts:    opts,
+		limiter: ratelimit.NewBucket(opts.rate, opts.burst),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *session) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	defer func() {
+		close(s.done)
+		s.terminate()
+	}()
+
+	go s.keepalive()
+
+	extend := func() {
+		_ = s.c.c.SetReadDeadline(time.Now().Add(2 * s.opts.pingInterval))
+	}
+	extend()
+
+	for {
+		op, message, err := s.c.readMessage(extend)
+		if err != nil {
+			var ce *closeError
+			if !errors.As(err, &ce) && !errors.Is(err, errClosed) {
+				zaphelper.Debug(ctx, "websocket connection ended", zap.Error(err))
+			}
+			return
+		}
+
+		if op != opText {
+			_ = s.c.close(closeUnsupportedData, "only text frames are supported")
+			continue
+		}
+
+		s.handle(ctx, message)
+	}
+}
+
+func (s *session) handle(ctx context.Context, message []byte) {
+	// limited before decoding so floods of frames cost as little as possible, and invalid ones count too
+	if !s.limiter.Allow(time.Now()) {
+		s.send(ctx, errorFrame("", status.Error(codes.ResourceExhausted, "rate limit exceeded")))
+		return
+	}
+
+	var in inbound
+	if err := json.Unmarshal(message, &in); err != nil {
+		s.send(ctx, errorFrame("", status.Errorf(codes.InvalidArgument, "invalid frame: %v", err)))
+		return
+	}
+
+	switch in.Type {
+	case "hello":
+		resp, err := s.service.Respond(ctx, service.RespondRequest{
+			OriginalMessage: in.Name,
+		})
+		if err != nil {
+			s.send(ctx, errorFrame(in.ID, fmt.Errorf("error occurred: %w", err)))
+			return
+		}
+		s.send(ctx, outbound{ID: in.ID, Type: "hello", Message: resp.ResponseMessage})
+	default:
+		s.send(ctx, errorFrame(in.ID, status.Errorf(codes.Unimplemented, "unknown frame type %q", in.Type)))
+	}
+}
+
+func errorFrame(id string, err error) outbound {
+	st := status.Convert(err)
+	return outbound{
+		ID:    id,
+		Type:  "error",
+		Error: &frameError{Code: st.Code(), Message: st.Message()},
+	}
+}
+
+func (s *session) send(ctx context.Context, out outbound) {
+	b, err := json.Marshal(out)
+	if err != nil {
+		zaphelper.Error(ctx, "unable to encode websocket frame", zap.Error(err))
+		return
+	}
+	if err := s.c.writeText(b); err != nil && !errors.Is(err, errClosed) {
+		zaphelper.Debug(ctx, "unable to write websocket frame", zap.Error(err))
+	}
+}
+
+func (s *session) keepalive() {
+	t := time.NewTicker(s.opts.pingInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-t.C:
+			if err := s.c.writeFrame(opPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// goAway starts the closing handshake, giving the client a short time to reply before the connection is dropped.
+func (s *session) goAway() {
+	_ = s.c.close(closeGoingAway, "server shutting down")
+	_ = s.c.c.SetReadDeadline(time.Now().Add(s.opts.writeTimeout))
+}
+
+func (s *session) terminate() {
+	s.closeOnce.Do(func() {
+		_ = s.c.c.Close()
+	})
+}
@@ -0,0 +1,273 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/peer"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+type ListenConfig interface {
+	Listen(ctx context.Context, net, addr string) (net.Listener, error)
+}
+
+type Service interface {
+	Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+}
+
+type options struct {
+	addr            string
+	path            string
+	pingInterval    time.Duration
+	writeTimeout    time.Duration
+	maxMessageBytes int64
+	rate            float64
+	burst           int
+	allowedOrigins  []string
+	middleware      []func(http.Handler) http.Handler
+	wrapListener    func(net.Listener) net.Listener
+}
+
+type Option func(o *options)
+
+func defaultOpts() options {
+	return options{
+		addr:            ":8082",
+		path:            "/v1/ws",
+		pingInterval:    time.Second * 30,
+		writeTimeout:    time.Second * 10,
+		maxMessageBytes: 64 << 10,
+		rate:            5,
+		burst:           10,
+	}
+}
+
+// WithAddr sets the address the Handler listens on.  Defaults to :8082.
+func WithAddr(addr string) Option {
+	return func(o *options) {
+		o.addr = addr
+	}
+}
+
+// WithPath sets the path the websocket is served on.  Defaults to /v1/ws.
+func WithPath(path string) Option {
+	return func(o *options) {
+		o.path = path
+	}
+}
+
+// WithPingInterval sets how often connections are pinged.  A connection that sends nothing for two intervals is
+// closed.
+func WithPingInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.pingInterval = d
+	}
+}
+
+// WithMaxMessageBytes sets the largest message accepted from a client.
+func WithMaxMessageBytes(n int64) Option {
+	return func(o *options) {
+		o.maxMessageBytes = n
+	}
+}
+
+// WithRateLimit sets the number of messages per second each connection may send, with bursts of up to burst messages.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(o *options) {
+		o.rate = perSecond
+		o.burst = burst
+	}
+}
+
+// WithAllowedOrigins sets the origins browsers may connect from, "*" allowing any.  Browsers are refused by default,
+// clients that send no Origin header are always let in.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(o *options) {
+		o.allowedOrigins = append(o.allowedOrigins, origins...)
+	}
+}
+
+// WithMiddleware wraps the websocket handshake in middleware, the first one given being the outermost.
+func WithMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// WithListenerWrapper wraps the listener the Handler accepts connections from, such as to close unwanted ones early.
+func WithListenerWrapper(wrap func(net.Listener) net.Listener) Option {
+	return func(o *options) {
+		o.wrapListener = wrap
+	}
+}
+
+// Handler is an app.Runner exposing the Greeter service to browsers over websockets.  Stop sends a going away close
+// frame to every open connection and waits for them to finish.
+type Handler struct {
+	service Service
+	opts    options
+
+	listenCfg ListenConfig
+
+	mu       sync.Mutex
+	server   *http.Server
+	sessions map[*session]struct{}
+	stopping bool
+	wg       sync.WaitGroup
+}
+
+func New(service Service, opts ...Option) *Handler {
+	o := defaultOpts()
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Handler{
+		service:   service,
+		opts:      o,
+		listenCfg: &net.ListenConfig{},
+		sessions:  map[*session]struct{}{},
+	}
+}
+
+func (h *Handler) Start(ctx context.Context) error {
+	l, err := h.listenCfg.Listen(ctx, "tcp", h.opts.addr)
+	if err != nil {
+		return fmt.Errorf("websocket: unable to create listener: %w", err)
+	}
+	if h.opts.wrapListener != nil {
+		l = h.opts.wrapListener(l)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+h.opts.path, h.serveWebsocket)
+
+	var handler http.Handler = mux
+	for i := len(h.opts.middleware) - 1; i >= 0; i-- {
+		handler = h.opts.middleware[i](handler)
+	}
+
+	s := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: time.Second * 10,
+		BaseContext: func(net.Listener) context.Context {
+			return context.WithoutCancel(ctx)
+		},
+		// the peer address is found as it is for gRPC calls, by those such as the abuse detector
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return peer.NewContext(ctx, &peer.Peer{Addr: c.RemoteAddr(), LocalAddr: c.LocalAddr()})
+		},
+	}
+
+	h.mu.Lock()
+	h.server = s
+	h.mu.Unlock()
+
+	if err := s.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (h *Handler) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	s := h.server
+	h.stopping = true
+	sessions := make([]*session, 0, len(h.sessions))
+	for sess := range h.sessions {
+		sessions = append(sessions, sess)
+	}
+	h.mu.Unlock()
+
+	if s == nil {
+		return nil
+	}
+
+	// hijacked connections are not tracked by the http.Server so they are closed separately
+	err := s.Shutdown(ctx)
+	for _, sess := range sessions {
+		sess.goAway()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		for _, sess := range sessions {
+			sess.terminate()
+		}
+		return ctx.Err()
+	}
+	return err
+}
+
+func (h *Handler) Name() string {
+	return "websocket"
+}
+
+func (h *Handler) originAllowed(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.opts.allowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	if !h.originAllowed(r.Header.Get("Origin")) {
+		http.Error(w, "websocket: origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	h.mu.Lock()
+	if h.stopping {
+		h.mu.Unlock()
+		http.Error(w, "websocket: shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	h.wg.Add(1)
+	h.mu.Unlock()
+	defer h.wg.Done()
+
+	c, err := upgrade(w, r, h.opts.maxMessageBytes, h.opts.writeTimeout)
+	if err != nil {
+		return
+	}
+
+	sess := newSession(c, h.service, h.opts)
+
+	h.mu.Lock()
+	h.sessions[sess] = struct{}{}
+	stopping := h.stopping
+	h.mu.Unlock()
+
+	// Stop may have run while the handshake was in flight
+	if stopping {
+		sess.goAway()
+	}
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, sess)
+		h.mu.Unlock()
+	}()
+
+	sess.run(r.Context())
+}
@@ -0,0 +1,223 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+type respondFunc func(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error)
+
+func (f respondFunc) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	return f(ctx, request)
+}
+
+func newTestServer(t *testing.T, opts ...Option) *httptest.Server {
+	t.Helper()
+	h := New(respondFunc(func(_ context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+		return service.RespondResponse{ResponseMessage: "Hello " + request.OriginalMessage}, nil
+	}), opts...)
+	s := httptest.NewServer(http.HandlerFunc(h.serveWebsocket))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// dial opens a websocket to s, returning the handshake response and, when it succeeded, the connection.
+func dial(t *testing.T, s *httptest.Server, origin string) (*http.Response, net.Conn, *bufio.Reader) {
+	t.Helper()
+	c, err := net.Dial("tcp", s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	_ = c.SetDeadline(time.Now().Add(time.Second * 5))
+
+	request := "GET /v1/ws HTTP/1.1\r\n" +
+		"Host: greeter\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+	if origin != "" {
+		request += "Origin: " + origin + "\r\n"
+	}
+	if _, err := c.Write([]byte(request + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, c, br
+}
+
+func TestHandlerOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    int
+	}{
+		{name: "no origin", want: http.StatusSwitchingProtocols},
+		{name: "browser by default", origin: "https://app.example", want: http.StatusForbidden},
+		{name: "allowed", allowed: []string{"https://app.example"}, origin: "https://app.example", want: http.StatusSwitchingProtocols},
+		{name: "not allowed", allowed: []string{"https://app.example"}, origin: "https://evil.example", want: http.StatusForbidden},
+		{name: "any", allowed: []string{"*"}, origin: "https://evil.example", want: http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _, _ := dial(t, newTestServer(t, WithAllowedOrigins(tt.allowed...)), tt.origin)
+			if resp.StatusCode != tt.want {
+				t.Errorf("handshake status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerSession(t *testing.T) {
+	resp, c, br := dial(t, newTestServer(t, WithRateLimit(0.001, 3)), "")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	// the accept value from the example of RFC 6455
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	exchange := func(message string) outbound {
+		t.Helper()
+		if _, err := c.Write(clientFrame(true, opText, []byte(message), true)); err != nil {
+			t.Fatal(err)
+		}
+		f := readServerFrame(t, br)
+		var out outbound
+		if err := json.Unmarshal(f.payload, &out); err != nil {
+			t.Fatalf("invalid frame %q: %v", f.payload, err)
+		}
+		return out
+	}
+
+	if out := exchange(`{"id":"1","type":"hello","name":"Ann"}`); out.ID != "1" || out.Type != "hello" || out.Message != "Hello Ann" {
+		t.Errorf("hello = %+v, want a greeting for Ann", out)
+	}
+	if out := exchange(`{"id":"2","type":"bye"}`); out.Type != "error" || out.Error.Code != codes.Unimplemented {
+		t.Errorf("unknown type = %+v, want an Unimplemented error", out)
+	}
+	if out := exchange(`{"id":`); out.Type != "error" || out.Error.Code != codes.InvalidArgument {
+		t.Errorf("invalid frame = %+v, want an InvalidArgument error", out)
+	}
+	// the burst is spent, even by the invalid frame, so the next is refused before it is decoded
+	if out := exchange(`{"id":`); out.Type != "error" || out.Error.Code != codes.ResourceExhausted {
+		t.Errorf("frame over the limit = %+v, want a ResourceExhausted error", out)
+	}
+
+	if _, err := c.Write(clientFrame(true, opBinary, []byte("hello"), true)); err != nil {
+		t.Fatal(err)
+	}
+	f := readServerFrame(t, br)
+	if f.op != opClose || !strings.HasSuffix(string(f.payload), "only text frames are supported") {
+		t.Errorf("binary frame answered with %d %q, want a close frame", f.op, f.payload)
+	}
+}
+
+// listenConfig listens on a free port of the loopback interface, sending the listener on ready.
+type listenConfig struct {
+	ready chan net.Listener
+}
+
+func (lc listenConfig) Listen(ctx context.Context, network, _ string) (net.Listener, error) {
+	l, err := (&net.ListenConfig{}).Listen(ctx, network, "127.0.0.1:0")
+	if err == nil {
+		lc.ready <- l
+	}
+	return l, err
+}
+
+// countingListener counts the connections accepted through it.
+type countingListener struct {
+	net.Listener
+	accepted chan struct{}
+}
+
+func (l countingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err == nil {
+		select {
+		case l.accepted <- struct{}{}:
+		default:
+		}
+	}
+	return c, err
+}
+
+func TestHandlerMiddlewareAndListenerWrapper(t *testing.T) {
+	accepted := make(chan struct{}, 1)
+	var order []string
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				if name == "inner" && r.Header.Get("X-Blocked") != "" {
+					http.Error(w, "address not allowed", http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := New(respondFunc(func(_ context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+		return service.RespondResponse{ResponseMessage: "Hello " + request.OriginalMessage}, nil
+	}),
+		WithMiddleware(mw("outer"), mw("inner")),
+		WithListenerWrapper(func(l net.Listener) net.Listener { return countingListener{Listener: l, accepted: accepted} }),
+	)
+	lc := listenConfig{ready: make(chan net.Listener, 1)}
+	h.listenCfg = lc
+	done := make(chan error, 1)
+	go func() { done <- h.Start(context.Background()) }()
+	addr := (<-lc.ready).Addr().String()
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/v1/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Blocked", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("middleware order = %v, want outer first", order)
+	}
+	select {
+	case <-accepted:
+	default:
+		t.Error("connection not accepted through the wrapped listener")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := h.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Package loadshed sheds load when the gRPC listener slows down, with a concurrency limit adapting to the latency of
+// the calls it admits.
+package loadshed
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// limit adapts the number of calls allowed in flight, additively increasing it while calls are as fast as usual and
+// multiplicatively decreasing it when they slow down or fail under load.
+//
+// Usual is the long term average latency, following a gradient approach: a sample slower than tolerance times the
+// average shows a queue building up, so the limit is cut before the latency of every call rises.
+type limit struct {
+	min, max  float64
+	backoff   float64
+	tolerance float64
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	// average is the exponential moving average of the latency, in seconds.
+	average float64
+}
+
+// averageWeight is the weight of each sample in the long term average, about the last 500 calls.
+const averageWeight = 1.0 / 500
+
+func newLimit(o options) *limit {
+	return &limit{
+		min:       float64(o.minLimit),
+		max:       float64(o.maxLimit),
+		backoff:   o.backoff,
+		tolerance: o.tolerance,
+		limit:     float64(o.initialLimit),
+	}
+}
+
+// acquire admits a call while fewer than ratio of the limit are in flight, returning the limit when it does not.
+func (l *limit) acquire(ratio float64) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	allowed := int(math.Max(1, math.Floor(l.limit*ratio)))
+	if l.inflight >= allowed {
+		return int(l.limit), false
+	}
+	l.inflight++
+	return 0, true
+}
+
+// release records the end of a call that took latency, overloaded reporting whether it failed because the server is
+// overloaded.
+func (l *limit) release(latency time.Duration, overloaded bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inflight := l.inflight
+	l.inflight--
+
+	sample := latency.Seconds()
+	if l.average == 0 {
+		l.average = sample
+	}
+
+	switch {
+	case overloaded || sample > l.average*l.tolerance:
+		l.limit = math.Max(l.min, l.limit*l.backoff)
+	case float64(inflight)*2 >= l.limit:
+		// only grow a limit that is being used, otherwise it grows without bound while the server is idle
+		l.limit = math.Min(l.max, l.limit+1/math.Max(1, math.Sqrt(l.limit)))
+	}
+
+	// slow samples count for less so that a sustained slowdown is not quickly taken as the usual latency
+	if sample <= l.average*l.tolerance {
+		l.average += (sample - l.average) * averageWeight
+	} else {
+		l.average += (sample - l.average) * averageWeight / 10
+	}
+
+	limitGauge.Set(l.limit)
+}
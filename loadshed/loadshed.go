@@ -0,0 +1,160 @@
+package loadshed
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PriorityMetadataKey is the metadata clients tag critical calls with, sending PriorityCritical.
+const (
+	PriorityMetadataKey = "x-priority"
+	PriorityCritical    = "critical"
+)
+
+var (
+	limitGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "greeter",
+		Subsystem: "loadshed",
+		Name:      "concurrency_limit",
+		Help:      "Calls currently allowed in flight.",
+	})
+
+	inflightGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "greeter",
+		Subsystem: "loadshed",
+		Name:      "inflight",
+		Help:      "Calls in flight.",
+	})
+
+	rejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "greeter",
+		Subsystem: "loadshed",
+		Name:      "rejected_total",
+		Help:      "Calls rejected for exceeding the concurrency limit, by priority.",
+	}, []string{"priority"})
+)
+
+func init() {
+	prometheus.MustRegister(limitGauge, inflightGauge, rejected)
+}
+
+type options struct {
+	initialLimit   int
+	minLimit       int
+	maxLimit       int
+	backoff        float64
+	tolerance      float64
+	criticalShare  float64
+	methods        []string
+	overloadedCode []codes.Code
+}
+
+type Option func(o *options)
+
+// WithLimits sets the concurrency limit to start with and the bounds it adapts between.
+func WithLimits(initial, min, max int) Option {
+	return func(o *options) {
+		o.initialLimit, o.minLimit, o.maxLimit = initial, min, max
+	}
+}
+
+// WithTolerance sets how many times slower than usual a call may be before the limit is cut.
+func WithTolerance(tolerance float64) Option {
+	return func(o *options) {
+		o.tolerance = tolerance
+	}
+}
+
+// WithCriticalReserve keeps a share of the limit for critical calls, other calls are rejected once the rest is in use.
+func WithCriticalReserve(share float64) Option {
+	return func(o *options) {
+		o.criticalShare = share
+	}
+}
+
+// WithMethods restricts the interceptor to the given full method names, calls to other methods are passed through.
+func WithMethods(methods ...string) Option {
+	return func(o *options) {
+		o.methods = append(o.methods, methods...)
+	}
+}
+
+// Limiter provides an interceptor rejecting calls with Unavailable when the adaptive concurrency limit is reached.
+// It only applies to unary calls, the duration of a stream says nothing about the load of the server.  It must be
+// installed after the interceptors refusing calls for the caller's sake, such as quotas and rate limits, so that
+// their refusals neither take a slot nor count as fast calls.
+type Limiter struct {
+	limit *limit
+	opts  options
+}
+
+func NewLimiter(opts ...Option) *Limiter {
+	// only Unavailable tells of an overloaded server: ResourceExhausted from quotas and DeadlineExceeded from short
+	// client deadlines do not, and slow calls are caught by their latency
+	o := options{
+		initialLimit:   20,
+		minLimit:       1,
+		maxLimit:       1000,
+		backoff:        0.9,
+		tolerance:      2,
+		criticalShare:  0.1,
+		overloadedCode: []codes.Code{codes.Unavailable},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	l := &Limiter{limit: newLimit(o), opts: o}
+	limitGauge.Set(float64(o.initialLimit))
+	return l
+}
+
+func (l *Limiter) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if len(l.opts.methods) > 0 && !slices.Contains(l.opts.methods, info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	priority, ratio := "normal", 1-l.opts.criticalShare
+	if critical(ctx) {
+		priority, ratio = PriorityCritical, 1
+	}
+
+	if limit, ok := l.limit.acquire(ratio); !ok {
+		rejected.WithLabelValues(priority).Inc()
+		zaphelper.Debug(ctx, "call shed",
+			zap.String("method", info.FullMethod),
+			zap.String("priority", priority),
+			zap.Int("limit", limit))
+		return nil, status.Error(codes.Unavailable, fmt.Sprintf("server overloaded, concurrency limit of %d reached", limit))
+	}
+	inflightGauge.Inc()
+
+	start := time.Now()
+	overloaded := true
+	defer func() {
+		// a panicking handler counts as overloaded, its slot is released all the same
+		inflightGauge.Dec()
+		l.limit.release(time.Since(start), overloaded)
+	}()
+
+	res, err := handler(ctx, req)
+	overloaded = slices.Contains(l.opts.overloadedCode, status.Code(err))
+	return res, err
+}
+
+func critical(ctx context.Context) bool {
+	return slices.ContainsFunc(metadata.ValueFromIncomingContext(ctx, PriorityMetadataKey), func(v string) bool {
+		return strings.EqualFold(v, PriorityCritical)
+	})
+}
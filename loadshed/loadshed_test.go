@@ -0,0 +1,173 @@
+package loadshed
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func testOptions() options {
+	return options{initialLimit: 10, minLimit: 2, maxLimit: 12, backoff: 0.5, tolerance: 2}
+}
+
+func TestLimitRelease(t *testing.T) {
+	type call struct {
+		latency    time.Duration
+		overloaded bool
+	}
+	tests := []struct {
+		name string
+		// start is the limit to start from, 10 when unset
+		start float64
+		// inflight calls are acquired before the calls are released one by one
+		inflight int
+		calls    []call
+		want     float64
+	}{
+		{
+			name:     "as fast as usual and in use",
+			inflight: 5,
+			calls:    []call{{latency: time.Millisecond * 10}},
+			want:     10 + 1/math.Sqrt(10),
+		},
+		{
+			name:     "as fast as usual and idle",
+			inflight: 1,
+			calls:    []call{{latency: time.Millisecond * 10}},
+			want:     10,
+		},
+		{
+			name:     "slower than tolerated",
+			inflight: 2,
+			calls:    []call{{latency: time.Millisecond * 10}, {latency: time.Millisecond * 30}},
+			want:     5,
+		},
+		{
+			name:     "slower within tolerance",
+			inflight: 2,
+			calls:    []call{{latency: time.Millisecond * 10}, {latency: time.Millisecond * 19}},
+			want:     10,
+		},
+		{
+			name:     "overloaded",
+			inflight: 1,
+			calls:    []call{{latency: time.Millisecond * 10, overloaded: true}},
+			want:     5,
+		},
+		{
+			name:     "not below the minimum",
+			inflight: 3,
+			calls: []call{
+				{latency: time.Millisecond, overloaded: true},
+				{latency: time.Millisecond, overloaded: true},
+				{latency: time.Millisecond, overloaded: true},
+			},
+			want: 2,
+		},
+		{
+			name:     "not above the maximum",
+			start:    11.9,
+			inflight: 10,
+			calls:    []call{{latency: time.Millisecond}},
+			want:     12,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLimit(testOptions())
+			if tt.start != 0 {
+				l.limit = tt.start
+			}
+			for range tt.inflight {
+				if _, ok := l.acquire(1); !ok {
+					t.Fatal("acquire() refused a call under the limit")
+				}
+			}
+			for _, c := range tt.calls {
+				l.release(c.latency, c.overloaded)
+			}
+			if l.limit != tt.want {
+				t.Errorf("limit = %v, want %v", l.limit, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitAcquire(t *testing.T) {
+	tests := []struct {
+		name     string
+		ratio    float64
+		inflight int
+		want     bool
+	}{
+		{name: "under the limit", ratio: 1, inflight: 9, want: true},
+		{name: "at the limit", ratio: 1, inflight: 10, want: false},
+		{name: "reserve kept", ratio: 0.8, inflight: 8, want: false},
+		{name: "at least one call", ratio: 0.01, inflight: 0, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLimit(testOptions())
+			l.inflight = tt.inflight
+			if _, got := l.acquire(tt.ratio); got != tt.want {
+				t.Errorf("acquire(%v) = %v, want %v", tt.ratio, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/playground.Greeter/SayHello"}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantLimit float64
+	}{
+		{name: "success", err: nil, wantLimit: 10},
+		{name: "unavailable", err: status.Error(codes.Unavailable, "down"), wantLimit: 9},
+		{name: "quota exceeded", err: status.Error(codes.ResourceExhausted, "quota"), wantLimit: 10},
+		{name: "client deadline", err: status.Error(codes.DeadlineExceeded, "deadline"), wantLimit: 10},
+		{name: "invalid argument", err: status.Error(codes.InvalidArgument, "name"), wantLimit: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(WithLimits(10, 1, 100))
+			_, err := l.UnaryServerInterceptor(context.Background(), nil, info, func(context.Context, any) (any, error) {
+				return nil, tt.err
+			})
+			if status.Code(err) != status.Code(tt.err) {
+				t.Fatalf("UnaryServerInterceptor() error = %v, want %v", err, tt.err)
+			}
+			if l.limit.limit != tt.wantLimit || l.limit.inflight != 0 {
+				t.Errorf("limit = %v with %d in flight, want %v with none", l.limit.limit, l.limit.inflight, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestLimiterSheds(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/playground.Greeter/SayHello"}
+	l := NewLimiter(WithLimits(10, 1, 100), WithCriticalReserve(0.2))
+	l.limit.inflight = 8
+
+	_, err := l.UnaryServerInterceptor(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		return nil, nil
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("UnaryServerInterceptor() normal call code = %v, want %v", status.Code(err), codes.Unavailable)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(PriorityMetadataKey, "Critical"))
+	if _, err := l.UnaryServerInterceptor(ctx, nil, info, func(context.Context, any) (any, error) {
+		return nil, nil
+	}); err != nil {
+		t.Errorf("UnaryServerInterceptor() critical call error = %v", err)
+	}
+}
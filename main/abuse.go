@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/LewisJAllan/greeter/abuse"
+	"github.com/LewisJAllan/greeter/admin"
+	"github.com/LewisJAllan/greeter/internal/clientconn"
+)
+
+// runAbuse manages the peers blocked by a greeter running with -abuse, connecting with the flags of greeter-cli such
+// as -tls, -ca and -token:
+//
+//	greeter abuse [-addr host:port] list
+//	greeter abuse [-addr host:port] unblock [-forgive] <peer>
+func runAbuse(args []string) error {
+	fs := flag.NewFlagSet("abuse", flag.ContinueOnError)
+	var connOpts clientconn.Options
+	connOpts.RegisterFlags(fs, "localhost:50051")
+	timeout := fs.Duration("timeout", time.Second*10, "deadline of the call")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	conn, err := connOpts.Dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(connOpts.OutgoingContext(context.Background()), *timeout)
+	defer cancel()
+
+	var out any
+	switch fs.Arg(0) {
+	case "list":
+		out, err = admin.Invoke[abuse.ListBlocksResponse](ctx, conn, abuse.ListBlocksFullMethodName, &abuse.Empty{})
+		if err != nil {
+			return err
+		}
+	case "unblock":
+		unblock := flag.NewFlagSet("abuse unblock", flag.ContinueOnError)
+		forgive := unblock.Bool("forgive", false, "also forget the offences of the peer")
+		if err := unblock.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+		if unblock.NArg() != 1 {
+			return errors.New("usage: greeter abuse unblock [-forgive] <peer>")
+		}
+		out, err = admin.Invoke[abuse.Block](ctx, conn, abuse.UnblockFullMethodName, &abuse.UnblockRequest{
+			Peer:    unblock.Arg(0),
+			Forgive: *forgive,
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("usage: greeter abuse [-addr host:port] list | unblock <peer>")
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
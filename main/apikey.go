@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/LewisJAllan/greeter/admin"
+	"github.com/LewisJAllan/greeter/apikey"
+	"github.com/LewisJAllan/greeter/internal/clientconn"
+)
+
+// runAPIKey manages the API keys of a greeter running with -apikey-store, connecting with the flags of greeter-cli
+// such as -tls, -ca and -token:
+//
+//	greeter apikey [-addr host:port] create [-per-minute n] [-per-day n] <name>
+//	greeter apikey [-addr host:port] revoke <id>
+//	greeter apikey [-addr host:port] list
+func runAPIKey(args []string) error {
+	fs := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	var connOpts clientconn.Options
+	connOpts.RegisterFlags(fs, "localhost:50051")
+	timeout := fs.Duration("timeout", time.Second*10, "deadline of the call")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	conn, err := connOpts.Dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(connOpts.OutgoingContext(context.Background()), *timeout)
+	defer cancel()
+
+	var out any
+	switch fs.Arg(0) {
+	case "create":
+		create := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		perMinute := create.Int("per-minute", 0, "calls allowed per minute, 0 for no limit")
+		perDay := create.Int("per-day", 0, "calls allowed per UTC day, 0 for no limit")
+		if err := create.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+		if create.NArg() != 1 {
+			return errors.New("usage: greeter apikey create [-per-minute n] [-per-day n] <name>")
+		}
+		out, err = admin.Invoke[apikey.CreateKeyResponse](ctx, conn, apikey.CreateKeyFullMethodName, &apikey.CreateKeyRequest{
+			Name:      create.Arg(0),
+			PerMinute: *perMinute,
+			PerDay:    *perDay,
+		})
+		if err != nil {
+			return err
+		}
+	case "revoke":
+		if fs.NArg() != 2 {
+			return errors.New("usage: greeter apikey revoke <id>")
+		}
+		out, err = admin.Invoke[apikey.Key](ctx, conn, apikey.RevokeKeyFullMethodName, &apikey.RevokeKeyRequest{ID: fs.Arg(1)})
+		if err != nil {
+			return err
+		}
+	case "list":
+		out, err = admin.Invoke[apikey.ListKeysResponse](ctx, conn, apikey.ListKeysFullMethodName, &apikey.Empty{})
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("usage: greeter apikey [-addr host:port] create <name> | revoke <id> | list")
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/internal/clientconn"
+)
+
+type benchConfig struct {
+	conn        clientconn.Options
+	rps         float64
+	concurrency int
+	duration    time.Duration
+	timeout     time.Duration
+	namesFile   string
+	field       string
+	jsonOutput  bool
+}
+
+// runBench drives Greeter.SayHello and reports latency, errors and throughput.  With -rps the requests are paced at
+// that rate, otherwise each worker sends its next request as soon as the last one completes.  It connects with the
+// flags of greeter-cli such as -tls, -ca and -token:
+//
+//	greeter bench [-addr host:port] [-rps n] [-duration d] [-names names.jsonl [-field name]] [-json]
+func runBench(args []string) error {
+	cfg, err := parseBenchFlags(args)
+	if err != nil {
+		return err
+	}
+
+	names := []string{"World"}
+	if cfg.namesFile != "" {
+		if names, err = loadNames(cfg.namesFile, cfg.field); err != nil {
+			return err
+		}
+	}
+
+	conn, err := cfg.conn.Dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	report := bench(ctx, cfg, schemas.NewGreeterClient(conn), names)
+
+	if cfg.jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.write(os.Stdout)
+}
+
+func parseBenchFlags(args []string) (benchConfig, error) {
+	var cfg benchConfig
+
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	cfg.conn.RegisterFlags(fs, "localhost:50051")
+	fs.Float64Var(&cfg.rps, "rps", 0, "requests per second to send, 0 to send as fast as the workers allow")
+	fs.IntVar(&cfg.concurrency, "concurrency", 10, "number of requests in flight at once")
+	fs.DurationVar(&cfg.duration, "duration", time.Second*10, "how long to send requests for")
+	fs.DurationVar(&cfg.timeout, "timeout", time.Second*5, "deadline of each request")
+	fs.StringVar(&cfg.namesFile, "names", "", "JSONL file to take names from, one JSON string per line, or one object per line with -field")
+	fs.StringVar(&cfg.field, "field", "", "field of each JSONL object holding the name, required when -names holds objects")
+	fs.BoolVar(&cfg.jsonOutput, "json", false, "print the report as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return benchConfig{}, err
+	}
+	if cfg.concurrency < 1 {
+		return benchConfig{}, errors.New("concurrency must be at least 1")
+	}
+	if cfg.rps < 0 || cfg.duration <= 0 {
+		return benchConfig{}, errors.New("rps must not be negative and duration must be positive")
+	}
+	if err := cfg.conn.Check(); err != nil {
+		return benchConfig{}, err
+	}
+	return cfg, nil
+}
+
+// loadNames reads a name from each line of a JSONL file.  Lines may be JSON strings, or objects holding the name in
+// field.  Objects without the field are skipped, objects are refused when no field is given since the name could only
+// be guessed.
+func loadNames(path, field string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open names: %w", err)
+	}
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var v any
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		switch v := v.(type) {
+		case string:
+			names = append(names, v)
+		case map[string]any:
+			if field == "" {
+				return nil, fmt.Errorf("%s:%d: -field is required to take names from JSON objects", path, line)
+			}
+			if name, ok := v[field].(string); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read names: %w", err)
+	}
+	if len(names) == 0 {
+		if field == "" {
+			return nil, fmt.Errorf("no names found in %s", path)
+		}
+		return nil, fmt.Errorf("no names found in %s under %q", path, field)
+	}
+	return names, nil
+}
+
+type benchResult struct {
+	latencies []time.Duration
+	errors    map[codes.Code]int
+}
+
+func bench(ctx context.Context, cfg benchConfig, client schemas.GreeterClient, names []string) benchReport {
+	// with a target rate the workers wait for a tick, without one they are unpaced
+	var ticks chan struct{}
+	if cfg.rps > 0 {
+		ticks = make(chan struct{}, cfg.concurrency)
+		go pace(ctx, cfg.rps, ticks)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]benchResult, cfg.concurrency)
+		next    = make(chan string)
+		md      = cfg.conn.Metadata()
+	)
+
+	go func() {
+		defer close(next)
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case next <- names[i%len(names)]:
+			}
+		}
+	}()
+
+	start := time.Now()
+	for w := range results {
+		wg.Add(1)
+		go func(r *benchResult) {
+			defer wg.Done()
+			r.errors = map[codes.Code]int{}
+
+			for name := range next {
+				if ticks != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticks:
+					}
+				}
+
+				// requests in flight when the duration ends are allowed to finish
+				reqCtx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.WithoutCancel(ctx), md), cfg.timeout)
+				begin := time.Now()
+				_, err := client.SayHello(reqCtx, &schemas.HelloRequest{Name: name})
+				r.latencies = append(r.latencies, time.Since(begin))
+				cancel()
+
+				if err != nil {
+					r.errors[status.Code(err)]++
+				}
+			}
+		}(&results[w])
+	}
+	wg.Wait()
+
+	return newBenchReport(cfg, time.Since(start), results)
+}
+
+// pace sends rps ticks a second, skipping ticks when every worker is busy so a slow server is not flooded afterwards.
+func pace(ctx context.Context, rps float64, ticks chan<- struct{}) {
+	interval := time.Duration(float64(time.Second) / rps)
+	if interval <= 0 {
+		interval = time.Nanosecond
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			select {
+			case ticks <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+type latencyReport struct {
+	Min  float64 `json:"minMs"`
+	Mean float64 `json:"meanMs"`
+	P50  float64 `json:"p50Ms"`
+	P90  float64 `json:"p90Ms"`
+	P99  float64 `json:"p99Ms"`
+	Max  float64 `json:"maxMs"`
+}
+
+// histogramBucket counts the latencies up to UpperBound, the last bucket has no bound.
+type histogramBucket struct {
+	UpperBound *float64 `json:"leMs,omitempty"`
+	Count      int      `json:"count"`
+}
+
+type benchReport struct {
+	Target      string            `json:"target"`
+	TargetRPS   float64           `json:"targetRps,omitempty"`
+	Concurrency int               `json:"concurrency"`
+	Duration    float64           `json:"durationSeconds"`
+	Requests    int               `json:"requests"`
+	Succeeded   int               `json:"succeeded"`
+	Failed      int               `json:"failed"`
+	Throughput  float64           `json:"throughputRps"`
+	Latency     latencyReport     `json:"latency"`
+	Histogram   []histogramBucket `json:"histogram"`
+	Errors      map[string]int    `json:"errors"`
+}
+
+// histogramBounds are the upper bounds of the latency buckets in milliseconds, followed by an unbounded bucket.
+var histogramBounds = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+func newBenchReport(cfg benchConfig, elapsed time.Duration, results []benchResult) benchReport {
+	report := benchReport{
+		Target:      cfg.conn.Addr,
+		TargetRPS:   cfg.rps,
+		Concurrency: cfg.concurrency,
+		Duration:    elapsed.Seconds(),
+		Errors:      map[string]int{},
+	}
+
+	var latencies []time.Duration
+	for _, r := range results {
+		latencies = append(latencies, r.latencies...)
+		for code, n := range r.errors {
+			report.Errors[code.String()] += n
+			report.Failed += n
+		}
+	}
+	slices.Sort(latencies)
+
+	report.Requests = len(latencies)
+	report.Succeeded = report.Requests - report.Failed
+	if elapsed > 0 {
+		report.Throughput = float64(report.Succeeded) / elapsed.Seconds()
+	}
+
+	report.Histogram = make([]histogramBucket, len(histogramBounds)+1)
+	for i := range histogramBounds {
+		report.Histogram[i].UpperBound = &histogramBounds[i]
+	}
+	if len(latencies) == 0 {
+		return report
+	}
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+		i := sort.SearchFloat64s(histogramBounds, ms(l))
+		report.Histogram[i].Count++
+	}
+
+	report.Latency = latencyReport{
+		Min:  ms(latencies[0]),
+		Mean: ms(total / time.Duration(len(latencies))),
+		P50:  ms(percentile(latencies, 50)),
+		P90:  ms(percentile(latencies, 90)),
+		P99:  ms(percentile(latencies, 99)),
+		Max:  ms(latencies[len(latencies)-1]),
+	}
+	return report
+}
+
+// percentile returns the nearest-rank percentile p of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (r benchReport) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "target:\t%s\n", r.Target)
+	if r.TargetRPS > 0 {
+		fmt.Fprintf(tw, "target rps:\t%.1f\n", r.TargetRPS)
+	}
+	fmt.Fprintf(tw, "concurrency:\t%d\n", r.Concurrency)
+	fmt.Fprintf(tw, "duration:\t%.2fs\n", r.Duration)
+	fmt.Fprintf(tw, "requests:\t%d (%d ok, %d failed)\n", r.Requests, r.Succeeded, r.Failed)
+	fmt.Fprintf(tw, "throughput:\t%.1f rps\n", r.Throughput)
+	fmt.Fprintf(tw, "\nlatency (ms):\tmin %.2f\tmean %.2f\tp50 %.2f\tp90 %.2f\tp99 %.2f\tmax %.2f\n",
+		r.Latency.Min, r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+
+	fmt.Fprintln(tw, "\nhistogram (ms):")
+	for _, b := range r.Histogram {
+		if b.Count == 0 {
+			continue
+		}
+		bound := fmt.Sprintf("> %g", histogramBounds[len(histogramBounds)-1])
+		if b.UpperBound != nil {
+			bound = fmt.Sprintf("<= %g", *b.UpperBound)
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%s\n", bound, b.Count, bar(b.Count, r.Requests))
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintln(tw, "\nerrors:")
+		codes := make([]string, 0, len(r.Errors))
+		for code := range r.Errors {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(tw, "  %s\t%d\n", code, r.Errors[code])
+		}
+	}
+
+	return tw.Flush()
+}
+
+func bar(n, total int) string {
+	const width = 40
+	b := make([]byte, n*width/total)
+	for i := range b {
+		b[i] = '#'
+	}
+	return string(b)
+}
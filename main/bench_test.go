@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseBenchFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "tls and metadata", args: []string{"-tls", "-H", "x-team: a", "-token", "t"}},
+		{name: "no workers", args: []string{"-concurrency", "0"}, wantErr: "concurrency must be at least 1"},
+		{name: "negative rps", args: []string{"-rps", "-1"}, wantErr: "rps must not be negative"},
+		{name: "no duration", args: []string{"-duration", "0s"}, wantErr: "duration must be positive"},
+		{name: "cert without key", args: []string{"-cert", "c.pem"}, wantErr: "-cert and -key must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseBenchFlags(tt.args)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("parseBenchFlags() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("parseBenchFlags() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   string
+		field   string
+		want    []string
+		wantErr string
+	}{
+		{name: "strings", lines: "\"Ann\"\n\n\"Bob\"\n", want: []string{"Ann", "Bob"}},
+		{name: "objects", lines: `{"title":"Ann"}` + "\n" + `{"body":"Bob"}` + "\n" + `{"title":""}` + "\n" + `{"title":"Cy"}`, field: "title", want: []string{"Ann", "Cy"}},
+		{name: "mixed", lines: `"Ann"` + "\n" + `{"title":"Bob"}` + "\n" + `3`, field: "title", want: []string{"Ann", "Bob"}},
+		{name: "objects without a field", lines: `"Ann"` + "\n" + `{"title":"Bob"}`, wantErr: "names.jsonl:2: -field is required"},
+		{name: "field missing everywhere", lines: `{"title":"Ann"}`, field: "name", wantErr: `no names found in `},
+		{name: "invalid json", lines: `"Ann"` + "\n" + `{`, wantErr: "names.jsonl:2: unexpected end of JSON input"},
+		{name: "empty", lines: "", wantErr: "no names found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "names.jsonl")
+			if err := os.WriteFile(path, []byte(tt.lines), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadNames(path, tt.field)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadNames() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadNames() error = %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("loadNames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 10; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: time.Millisecond},
+		{p: 50, want: time.Millisecond * 5},
+		{p: 90, want: time.Millisecond * 9},
+		{p: 99, want: time.Millisecond * 10},
+		{p: 100, want: time.Millisecond * 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%g) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestNewBenchReport(t *testing.T) {
+	cfg := benchConfig{concurrency: 2}
+	cfg.conn.Addr = "localhost:50051"
+	results := []benchResult{
+		{latencies: []time.Duration{time.Millisecond, time.Millisecond * 3}, errors: map[codes.Code]int{}},
+		{latencies: []time.Duration{time.Millisecond * 2, time.Second * 6}, errors: map[codes.Code]int{codes.Unavailable: 1}},
+	}
+
+	r := newBenchReport(cfg, time.Second*2, results)
+
+	if r.Target != "localhost:50051" || r.Requests != 4 || r.Succeeded != 3 || r.Failed != 1 || r.Throughput != 1.5 {
+		t.Errorf("report = %+v, want 4 requests to localhost:50051, 3 succeeded at 1.5 rps", r)
+	}
+	if r.Errors["Unavailable"] != 1 || len(r.Errors) != 1 {
+		t.Errorf("errors = %v, want one Unavailable", r.Errors)
+	}
+	if want := (latencyReport{Min: 1, Mean: 1501.5, P50: 2, P90: 6000, P99: 6000, Max: 6000}); r.Latency != want {
+		t.Errorf("latency = %+v, want %+v", r.Latency, want)
+	}
+
+	counts := map[string]int{}
+	for _, b := range r.Histogram {
+		bound := "+Inf"
+		if b.UpperBound != nil {
+			bound = fmt.Sprint(*b.UpperBound)
+		}
+		if b.Count > 0 {
+			counts[bound] = b.Count
+		}
+	}
+	if want := "map[+Inf:1 1:1 2:1 5:1]"; fmt.Sprint(counts) != want {
+		t.Errorf("histogram = %v, want %s", counts, want)
+	}
+
+	var out strings.Builder
+	if err := r.write(&out); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	for _, want := range []string{"requests:     4 (3 ok, 1 failed)", "> 5000", "Unavailable"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("write() =\n%s\nwant it to contain %q", out.String(), want)
+		}
+	}
+}
+
+// greeterClient fails every third call and records the metadata of the last.
+type greeterClient struct {
+	calls atomic.Int64
+	md    atomic.Value
+}
+
+func (c *greeterClient) SayHello(ctx context.Context, in *schemas.HelloRequest, _ ...grpc.CallOption) (*schemas.HelloReply, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	c.md.Store(md)
+	if c.calls.Add(1)%3 == 0 {
+		return nil, status.Error(codes.ResourceExhausted, "slow down")
+	}
+	return &schemas.HelloReply{Message: "Hello " + in.GetName()}, nil
+}
+
+func TestBench(t *testing.T) {
+	cfg, err := parseBenchFlags([]string{"-concurrency", "3", "-H", "x-team: a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &greeterClient{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	r := bench(ctx, cfg, client, []string{"Ann", "Bob"})
+
+	calls := int(client.calls.Load())
+	if r.Requests != calls || r.Failed != calls/3 || r.Errors["ResourceExhausted"] != calls/3 {
+		t.Errorf("report = %d requests, %d failed, want %d requests, %d failed", r.Requests, r.Failed, calls, calls/3)
+	}
+	if md, _ := client.md.Load().(metadata.MD); len(md.Get("x-team")) != 1 || md.Get("x-team")[0] != "a" {
+		t.Errorf("metadata = %v, want x-team: a", md)
+	}
+}
+
+func TestBenchPaced(t *testing.T) {
+	cfg, err := parseBenchFlags([]string{"-rps", "100", "-concurrency", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &greeterClient{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+	r := bench(ctx, cfg, client, []string{"Ann"})
+
+	// about 20 requests, bounded loosely for slow machines
+	if r.Requests < 1 || r.Requests > 25 {
+		t.Errorf("requests = %d, want about 20 at 100 rps over 200ms", r.Requests)
+	}
+}
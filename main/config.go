@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/LewisJAllan/greeter/tlsconfig"
+)
+
+// config holds the flags of the server mode.
+type config struct {
+	captureFile    string
+	mockFile       string
+	moderation     string
+	crashReports   string
+	grpcWeb        bool
+	grpcWebOrigins []string
+	websocket      bool
+	wsOrigins      []string
+	tcp            bool
+	tls            tlsconfig.Options
+	tlsReload      time.Duration
+
+	ipFilter       string
+	ipFilterReload time.Duration
+
+	abuse         bool
+	abuseWindow   time.Duration
+	abuseBlock    time.Duration
+	abuseMaxBlock time.Duration
+
+	loadShed          bool
+	loadShedInitial   int
+	loadShedMax       int
+	loadShedTolerance float64
+
+	jwksFile    string
+	jwtIssuer   string
+	jwtAudience string
+	jwtSkew     time.Duration
+	jwtOptional bool
+
+	apiKeyStore    string
+	apiKeyOptional bool
+
+	rateLimitConfig string
+
+	rbacPolicy string
+	rbacDryRun bool
+}
+
+func parseConfig(args []string) (config, error) {
+	var cfg config
+
+	fs := flag.NewFlagSet(ServiceName, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(&cfg.mockFile, "mock", "", "answer SayHello on every listener from this scenario file instead of the service, and serve the MockAdmin service, which -rbac-policy must restrict")
+	fs.StringVar(&cfg.moderation, "moderation", "", "JSON file of the blocklists and rules names are moderated with before they are greeted")
+	fs.StringVar(&cfg.crashReports, "crash-reports", "", "directory to write a JSON crash report to for every panic recovered from a gRPC handler")
+	fs.StringVar(&cfg.captureFile, "capture", "", "record SayHello calls to this file in the grpc binary log format, replacing its contents")
+	fs.BoolVar(&cfg.grpcWeb, "grpc-web", false, "serve the Greeter to gRPC-Web clients on :8081, over TLS with -tls-cert")
+	webOrigins := fs.String("grpc-web-origins", "", "comma separated origins browsers may call gRPC-Web from with credentials, * lets any other origin call without them")
+	fs.BoolVar(&cfg.websocket, "websocket", false, "serve greetings to websocket clients on :8082")
+	wsOrigins := fs.String("websocket-origins", "", "comma separated origins browsers may open websockets from, * for any, none by default")
+	fs.BoolVar(&cfg.tcp, "tcp", false, "serve greetings over the plain-text line protocol on :7070")
+
+	fs.StringVar(&cfg.ipFilter, "ip-filter", "", "JSON file of the CIDR ranges allowed and denied on every listener")
+	fs.DurationVar(&cfg.ipFilterReload, "ip-filter-reload-interval", time.Second*10, "how often the ip filter file is checked for changes, must be positive")
+
+	fs.BoolVar(&cfg.abuse, "abuse", false, "temporarily block peers greeting abusively on every listener, and serve the AbuseAdmin service, which -rbac-policy must restrict")
+	fs.DurationVar(&cfg.abuseWindow, "abuse-window", time.Minute, "how far back the calls of a peer are looked at")
+	fs.DurationVar(&cfg.abuseBlock, "abuse-block", time.Minute, "first block of an abusive peer, quadrupled on every new offence")
+	fs.DurationVar(&cfg.abuseMaxBlock, "abuse-max-block", time.Hour*24, "longest block of an abusive peer")
+
+	fs.BoolVar(&cfg.loadShed, "loadshed", false, "reject SayHello calls with Unavailable beyond an adaptive concurrency limit")
+	fs.IntVar(&cfg.loadShedInitial, "loadshed-initial-limit", 20, "concurrency limit to start from, between 1 and -loadshed-max-limit")
+	fs.IntVar(&cfg.loadShedMax, "loadshed-max-limit", 1000, "highest concurrency limit, at least 1")
+	fs.Float64Var(&cfg.loadShedTolerance, "loadshed-tolerance", 2, "how many times slower than usual a call may be before the limit is cut, more than 1")
+
+	fs.StringVar(&cfg.tls.CertFile, "tls-cert", "", "PEM certificate of the gRPC listener, enables TLS")
+	fs.StringVar(&cfg.tls.KeyFile, "tls-key", "", "PEM private key of the gRPC listener")
+	fs.StringVar(&cfg.tls.ClientCAFile, "tls-client-ca", "", "PEM bundle of the CAs client certificates are verified against")
+	fs.StringVar(&cfg.tls.ClientAuth, "tls-client-auth", "", "client certificates: none, request or require, defaults to require with -tls-client-ca")
+	fs.StringVar(&cfg.tls.MinVersion, "tls-min-version", "1.2", "minimum TLS version: 1.2 or 1.3")
+	fs.DurationVar(&cfg.tlsReload, "tls-reload-interval", time.Second*10, "how often the TLS files are checked for changes, must be positive")
+	ciphers := fs.String("tls-ciphers", "", "comma separated TLS 1.2 cipher suites to allow, defaults to the Go defaults")
+
+	fs.StringVar(&cfg.jwksFile, "jwt-jwks", "", "JWKS file of the keys bearer tokens are verified with, enables token authentication on gRPC and gRPC-Web only")
+	fs.StringVar(&cfg.jwtIssuer, "jwt-issuer", "", "issuer bearer tokens must carry, required with -jwt-jwks")
+	fs.StringVar(&cfg.jwtAudience, "jwt-audience", "", "audience bearer tokens must carry, required with -jwt-jwks")
+	fs.DurationVar(&cfg.jwtSkew, "jwt-clock-skew", time.Minute, "clock difference tolerated when checking token times")
+	fs.BoolVar(&cfg.jwtOptional, "jwt-optional", false, "let calls without a bearer token through unauthenticated")
+
+	fs.StringVar(&cfg.apiKeyStore, "apikey-store", "", "JSON file of the hashed API keys, enables API keys on SayHello over every listener but websocket and tcp, which cannot send one, and the KeyAdmin service, which -rbac-policy must restrict")
+	fs.BoolVar(&cfg.apiKeyOptional, "apikey-optional", false, "let SayHello calls without an API key through")
+
+	fs.StringVar(&cfg.rateLimitConfig, "ratelimit-config", "", "JSON file of the per method rate limits of the gRPC listener")
+
+	fs.StringVar(&cfg.rbacPolicy, "rbac-policy", "", "JSON policy of the roles allowed to call each gRPC method, enables authorization on gRPC and gRPC-Web only")
+	fs.BoolVar(&cfg.rbacDryRun, "rbac-dry-run", false, "only log the calls the rbac policy would deny")
+
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+	if *ciphers != "" {
+		cfg.tls.CipherSuites = strings.Split(*ciphers, ",")
+	}
+	if *webOrigins != "" {
+		cfg.grpcWebOrigins = strings.Split(*webOrigins, ",")
+	}
+	if *wsOrigins != "" {
+		cfg.wsOrigins = strings.Split(*wsOrigins, ",")
+	}
+	if cfg.grpcWeb && cfg.tls.Enabled() && cfg.tls.ClientCertRequired() {
+		return config{}, errors.New("-grpc-web cannot be used with mutual TLS, gRPC-Web clients have no certificate to present")
+	}
+	if cfg.tlsReload <= 0 {
+		return config{}, errors.New("-tls-reload-interval must be positive")
+	}
+	if cfg.ipFilterReload <= 0 {
+		return config{}, errors.New("-ip-filter-reload-interval must be positive")
+	}
+	if cfg.apiKeyStore != "" && (cfg.websocket || cfg.tcp) {
+		return config{}, errors.New("-websocket and -tcp cannot be used with -apikey-store, their clients have no way to send an API key")
+	}
+	if cfg.loadShed {
+		switch {
+		case cfg.loadShedMax < 1:
+			return config{}, errors.New("-loadshed-max-limit must be at least 1")
+		case cfg.loadShedInitial < 1 || cfg.loadShedInitial > cfg.loadShedMax:
+			return config{}, errors.New("-loadshed-initial-limit must be between 1 and -loadshed-max-limit")
+		case cfg.loadShedTolerance <= 1:
+			return config{}, errors.New("-loadshed-tolerance must be more than 1, calls as fast as usual would cut the limit otherwise")
+		}
+	}
+	if cfg.jwksFile != "" && (cfg.jwtIssuer == "" || cfg.jwtAudience == "") {
+		return config{}, errors.New("-jwt-jwks requires -jwt-issuer and -jwt-audience, tokens issued for other services would be accepted otherwise")
+	}
+	return cfg, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "tls", args: []string{"-tls-cert", "cert.pem", "-tls-key", "key.pem", "-tls-reload-interval", "1m"}},
+		{
+			name:    "grpc-web with mutual tls",
+			args:    []string{"-grpc-web", "-tls-cert", "cert.pem", "-tls-key", "key.pem", "-tls-client-ca", "ca.pem"},
+			wantErr: "-grpc-web cannot be used with mutual TLS",
+		},
+		{name: "no tls reload interval", args: []string{"-tls-reload-interval", "0s"}, wantErr: "-tls-reload-interval must be positive"},
+		{name: "negative tls reload interval", args: []string{"-tls-reload-interval", "-1s"}, wantErr: "-tls-reload-interval must be positive"},
+		{name: "no ip filter reload interval", args: []string{"-ip-filter-reload-interval", "0s"}, wantErr: "-ip-filter-reload-interval must be positive"},
+		{name: "jwt", args: []string{"-jwt-jwks", "keys.json", "-jwt-issuer", "https://issuer", "-jwt-audience", "greeter"}},
+		{name: "jwt without issuer", args: []string{"-jwt-jwks", "keys.json", "-jwt-audience", "greeter"}, wantErr: "-jwt-jwks requires -jwt-issuer and -jwt-audience"},
+		{name: "jwt without audience", args: []string{"-jwt-jwks", "keys.json", "-jwt-issuer", "https://issuer"}, wantErr: "-jwt-jwks requires -jwt-issuer and -jwt-audience"},
+		{name: "api keys", args: []string{"-apikey-store", "keys.json"}},
+		{name: "api keys with websocket", args: []string{"-apikey-store", "keys.json", "-websocket"}, wantErr: "-websocket and -tcp cannot be used with -apikey-store"},
+		{name: "api keys with tcp", args: []string{"-apikey-store", "keys.json", "-tcp"}, wantErr: "-websocket and -tcp cannot be used with -apikey-store"},
+		{name: "loadshed", args: []string{"-loadshed", "-loadshed-initial-limit", "10", "-loadshed-max-limit", "10", "-loadshed-tolerance", "1.5"}},
+		{name: "loadshed limits unchecked when off", args: []string{"-loadshed-max-limit", "0"}},
+		{name: "loadshed no max", args: []string{"-loadshed", "-loadshed-max-limit", "0"}, wantErr: "-loadshed-max-limit must be at least 1"},
+		{name: "loadshed initial above max", args: []string{"-loadshed", "-loadshed-initial-limit", "11", "-loadshed-max-limit", "10"}, wantErr: "-loadshed-initial-limit must be between 1 and -loadshed-max-limit"},
+		{name: "loadshed no initial", args: []string{"-loadshed", "-loadshed-initial-limit", "0"}, wantErr: "-loadshed-initial-limit must be between 1 and -loadshed-max-limit"},
+		{name: "loadshed tolerance of 1", args: []string{"-loadshed", "-loadshed-tolerance", "1"}, wantErr: "-loadshed-tolerance must be more than 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig(tt.args)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("parseConfig() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("parseConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
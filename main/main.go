@@ -2,28 +2,105 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
 
 	async "github.com/LewisJAllan/application-helper/listeners/asynchronous"
 	grpclistener "github.com/LewisJAllan/application-helper/listeners/grpc"
 	app "github.com/LewisJAllan/application-helper/runner"
 	"github.com/LewisJAllan/application-helper/zaphelper"
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/LewisJAllan/greeter/abuse"
+	"github.com/LewisJAllan/greeter/apikey"
+	"github.com/LewisJAllan/greeter/auth"
+	"github.com/LewisJAllan/greeter/capture"
+	"github.com/LewisJAllan/greeter/ipfilter"
+	"github.com/LewisJAllan/greeter/listeners/connect"
+	"github.com/LewisJAllan/greeter/listeners/graphql"
 	"github.com/LewisJAllan/greeter/listeners/grpc"
+	"github.com/LewisJAllan/greeter/listeners/grpcweb"
+	"github.com/LewisJAllan/greeter/listeners/http"
+	"github.com/LewisJAllan/greeter/listeners/tcp"
+	"github.com/LewisJAllan/greeter/listeners/websocket"
+	"github.com/LewisJAllan/greeter/loadshed"
+	"github.com/LewisJAllan/greeter/mock"
+	"github.com/LewisJAllan/greeter/moderation"
+	"github.com/LewisJAllan/greeter/ratelimit"
+	"github.com/LewisJAllan/greeter/rbac"
+	"github.com/LewisJAllan/greeter/recovery"
 	"github.com/LewisJAllan/greeter/service"
+	"github.com/LewisJAllan/greeter/tlsconfig"
 )
 
 const ServiceName = "Greeter"
 
 func main() {
-	if err := app.Run(ServiceName, setup); err != nil {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "stdio":
+			if err := runStdio(); err != nil {
+				stderrLogger().Fatal("stdio mode failed", zap.Error(err))
+			}
+			return
+		case "mcp":
+			if err := runMCP(); err != nil {
+				stderrLogger().Fatal("mcp mode failed", zap.Error(err))
+			}
+			return
+		case "bench":
+			if err := runBench(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+				stderrLogger().Fatal("bench failed", zap.Error(err))
+			}
+			return
+		case "replay":
+			if err := runReplay(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+				stderrLogger().Fatal("replay failed", zap.Error(err))
+			}
+			return
+		case "abuse":
+			if err := runAbuse(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+				stderrLogger().Fatal("abuse failed", zap.Error(err))
+			}
+			return
+		case "apikey":
+			if err := runAPIKey(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+				stderrLogger().Fatal("apikey failed", zap.Error(err))
+			}
+			return
+		case "scenario":
+			if err := runScenario(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+				stderrLogger().Fatal("scenario failed", zap.Error(err))
+			}
+			return
+		}
+	}
+
+	cfg, err := parseConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		stderrLogger().Fatal("invalid flags", zap.Error(err))
+	}
+
+	if err := app.Run(ServiceName, cfg.setup); err != nil {
 		zaphelper.FromContext(context.Background()).Fatal("failed to start service",
 			zap.String("service_name", ServiceName),
 			zap.Error(err))
 	}
 }
 
-func setup(ctx context.Context, s *app.Service) ([]app.Runner, context.Context, error) {
+func (cfg config) setup(ctx context.Context, s *app.Service) ([]app.Runner, context.Context, error) {
 	s.OnShutdown(func(ctx context.Context) {
 		zaphelper.Info(ctx, "shutdown",
 			zap.String("service_name", s.Name()))
@@ -35,12 +112,344 @@ func setup(ctx context.Context, s *app.Service) ([]app.Runner, context.Context,
 
 	asyncWaiter := async.NewAsyncWaiter()
 
-	svc := service.NewService(&asyncWaiter)
+	events := http.NewEvents(256, time.Second*15)
+	subscriptions := graphql.NewSubscriptions()
+
+	svcOpts := []service.Option{service.WithGreetingObservers(events, subscriptions)}
+	if cfg.moderation != "" {
+		moderator, err := moderation.Load(cfg.moderation)
+		if err != nil {
+			return nil, ctx, err
+		}
+		svcOpts = append(svcOpts, service.WithModerator(moderator))
+
+		zaphelper.Info(ctx, "moderating names", zap.String("config", cfg.moderation))
+	}
+	svc := service.NewService(&asyncWaiter, svcOpts...)
+
+	// in mock mode every listener answers from the scenario instead of the service
+	var responder grpc.Service = &svc
+	var mockAdmin *mock.Admin
+	if cfg.mockFile != "" {
+		scenario, err := mock.Load(cfg.mockFile)
+		if err != nil {
+			return nil, ctx, err
+		}
+		m, err := mock.New(scenario)
+		if err != nil {
+			return nil, ctx, err
+		}
+		responder, mockAdmin = m, mock.NewAdmin(m)
+
+		zaphelper.Info(ctx, "answering from mock scenario",
+			zap.String("file", cfg.mockFile),
+			zap.String("scenario", scenario.Name))
+	}
+
+	var policy *rbac.Policy
+	if cfg.rbacPolicy != "" {
+		var err error
+		policy, err = rbac.Load(cfg.rbacPolicy)
+		if err != nil {
+			return nil, ctx, err
+		}
+	}
+	if mockAdmin != nil {
+		if err := cfg.checkAdminProtected(policy, "-mock",
+			mock.SetScenarioFullMethodName, mock.GetScenarioFullMethodName, mock.ResetScenarioFullMethodName); err != nil {
+			return nil, ctx, err
+		}
+	}
+
+	// the gRPC listeners count greetings against the API key quotas and track them with the interceptors, the others
+	// through the guarded service
+	var guarded grpc.Service = responder
+	var (
+		apiKeys    *apikey.Store
+		apiKeyAuth *apikey.Authenticator
+	)
+	if cfg.apiKeyStore != "" {
+		if err := cfg.checkAdminProtected(policy, "-apikey-store",
+			apikey.CreateKeyFullMethodName, apikey.RevokeKeyFullMethodName, apikey.ListKeysFullMethodName); err != nil {
+			return nil, ctx, err
+		}
+
+		var err error
+		apiKeys, err = apikey.OpenStore(cfg.apiKeyStore)
+		if err != nil {
+			return nil, ctx, err
+		}
+		apiKeyOpts := []apikey.Option{apikey.WithMethods(schemas.Greeter_SayHello_FullMethodName)}
+		if cfg.apiKeyOptional {
+			apiKeyOpts = append(apiKeyOpts, apikey.WithOptional())
+		}
+		apiKeyAuth = apikey.NewAuthenticator(apiKeys, apiKeyOpts...)
+		guarded = apiKeyAuth.Guard(guarded)
+	}
+
+	var detector *abuse.Detector
+	if cfg.abuse {
+		if err := cfg.checkAdminProtected(policy, "-abuse",
+			abuse.ListBlocksFullMethodName, abuse.UnblockFullMethodName); err != nil {
+			return nil, ctx, err
+		}
+
+		detector = abuse.NewDetector(
+			abuse.WithMethods(schemas.Greeter_SayHello_FullMethodName),
+			abuse.WithWindow(cfg.abuseWindow),
+			abuse.WithBlockDurations(cfg.abuseBlock, cfg.abuseMaxBlock),
+		)
+		guarded = detector.Guard(guarded)
+	}
+
+	client := grpc.NewClient(responder)
+	gateway := http.NewClient(guarded)
+	connectClient := connect.NewClient(guarded)
+
+	grpcRegisterers := []grpclistener.Registerer{client}
+	if mockAdmin != nil {
+		grpcRegisterers = append(grpcRegisterers, mockAdmin)
+	}
+
+	if apiKeys != nil {
+		grpcRegisterers = append(grpcRegisterers, apikey.NewAdmin(apiKeys))
+	}
+	if detector != nil {
+		grpcRegisterers = append(grpcRegisterers, abuse.NewAdmin(detector))
+	}
+	grpcRegisterer := grpclistener.MultiListener(grpcRegisterers...)
+	graphqlClient := graphql.NewClient(greetings{Service: &svc, guarded: guarded}, subscriptions)
+
+	openAPI, err := http.NewOpenAPI(ServiceName, "v1", gateway.Routes()...)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	var (
+		runners   []app.Runner
+		grpcOpts  []grpclistener.Option
+		grpcCreds credentials.TransportCredentials
+		httpOpts  = []http.Option{http.WithOnShutdown(events.Close, subscriptions.Close)}
+		webOpts   = []grpcweb.Option{grpcweb.WithAllowedOrigins(cfg.grpcWebOrigins...)}
+		wsOpts    = []websocket.Option{websocket.WithAllowedOrigins(cfg.wsOrigins...)}
+		tcpOpts   []tcp.Option
+		// the interceptors of the gRPC listener, shared with the gRPC-Web runner
+		unary  []googlegrpc.UnaryServerInterceptor
+		stream []googlegrpc.StreamServerInterceptor
+	)
+
+	// first in the chain, recovering from panics in every interceptor after it
+	var recoveryOpts []recovery.Option
+	if cfg.crashReports != "" {
+		recoveryOpts = append(recoveryOpts, recovery.WithCrashReports(cfg.crashReports))
+	}
+	recoverer := recovery.NewRecoverer(recoveryOpts...)
+	unary = append(unary, recoverer.UnaryServerInterceptor)
+	stream = append(stream, recoverer.StreamServerInterceptor)
+
+	var filter *ipfilter.Filter
+	if cfg.ipFilter != "" {
+		filter, err = ipfilter.NewFilter(ctx, cfg.ipFilter, cfg.ipFilterReload)
+		if err != nil {
+			return nil, ctx, err
+		}
+		runners = append(runners, filter)
+
+		unary = append(unary, filter.UnaryServerInterceptor)
+		stream = append(stream, filter.StreamServerInterceptor)
+		httpOpts = append(httpOpts,
+			http.WithMiddleware(filter.Middleware),
+			http.WithListenerWrapper(func(l net.Listener) net.Listener { return filter.Listener("http", l) }),
+		)
+		wsOpts = append(wsOpts,
+			websocket.WithMiddleware(filter.Middleware),
+			websocket.WithListenerWrapper(func(l net.Listener) net.Listener { return filter.Listener("websocket", l) }),
+		)
+		// the line protocol has no requests to filter, only its connections
+		tcpOpts = append(tcpOpts,
+			tcp.WithListenerWrapper(func(l net.Listener) net.Listener { return filter.Listener("tcp", l) }),
+		)
+
+		zaphelper.Info(ctx, "listeners filter peer addresses", zap.String("config", cfg.ipFilter))
+	}
+
+	if cfg.captureFile != "" {
+		recorder, err := capture.Create(cfg.captureFile, schemas.Greeter_SayHello_FullMethodName)
+		if err != nil {
+			return nil, ctx, err
+		}
+		s.OnShutdown(func(ctx context.Context) {
+			if err := recorder.Close(); err != nil {
+				zaphelper.Error(ctx, "unable to close capture", zap.Error(err))
+			}
+		})
+
+		unary = append(unary, recorder.UnaryServerInterceptor)
+
+		zaphelper.Info(ctx, "capturing calls", zap.String("file", cfg.captureFile))
+	}
+
+	if detector != nil {
+		unary = append(unary, detector.UnaryServerInterceptor)
+
+		zaphelper.Info(ctx, "listeners block abusive peers",
+			zap.Duration("window", cfg.abuseWindow),
+			zap.Duration("block", cfg.abuseBlock))
+	}
+
+	if cfg.tls.Enabled() {
+		reloader, err := tlsconfig.NewReloader(ctx, cfg.tls, cfg.tlsReload)
+		if err != nil {
+			return nil, ctx, err
+		}
+		runners = append(runners, reloader)
+
+		grpcCreds = credentials.NewTLS(reloader.Config())
+		// gRPC-Web carries the same tokens and API keys, so it is served over the same certificates
+		webOpts = append(webOpts, grpcweb.WithTLSConfig(reloader.Config()))
+		unary = append(unary, tlsconfig.UnaryServerInterceptor)
+		stream = append(stream, tlsconfig.StreamServerInterceptor)
+
+		zaphelper.Info(ctx, "grpc listener uses tls",
+			zap.String("cert", cfg.tls.CertFile),
+			zap.String("client_ca", cfg.tls.ClientCAFile))
+	}
+
+	if filter != nil {
+		if grpcCreds == nil {
+			grpcCreds = insecure.NewCredentials()
+		}
+		// blocked peers are turned away before the TLS handshake
+		grpcCreds = filter.Credentials(grpcCreds)
+	}
+	if grpcCreds != nil {
+		grpcOpts = append(grpcOpts, grpclistener.WithGRPCOptions(googlegrpc.Creds(grpcCreds)))
+	}
+
+	if cfg.jwksFile != "" {
+		keys, err := auth.LoadKeySet(cfg.jwksFile)
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		var authOpts []auth.Option
+		if cfg.jwtOptional {
+			authOpts = append(authOpts, auth.WithOptional())
+		}
+		authenticator := auth.NewAuthenticator(
+			auth.NewVerifier(keys, cfg.jwtIssuer, cfg.jwtAudience, cfg.jwtSkew),
+			authOpts...,
+		)
+		unary = append(unary, authenticator.UnaryServerInterceptor)
+		stream = append(stream, authenticator.StreamServerInterceptor)
+
+		zaphelper.Info(ctx, "grpc listener requires bearer tokens",
+			zap.String("jwks", cfg.jwksFile),
+			zap.Bool("optional", cfg.jwtOptional))
+	}
+
+	if apiKeyAuth != nil {
+		unary = append(unary, apiKeyAuth.UnaryServerInterceptor)
+		stream = append(stream, apiKeyAuth.StreamServerInterceptor)
+
+		zaphelper.Info(ctx, "listeners require api keys",
+			zap.String("store", cfg.apiKeyStore),
+			zap.Bool("optional", cfg.apiKeyOptional))
+	}
+
+	if cfg.rateLimitConfig != "" {
+		limits, err := ratelimit.LoadConfig(cfg.rateLimitConfig)
+		if err != nil {
+			return nil, ctx, err
+		}
+		limiter := ratelimit.NewLimiter(limits)
+		unary = append(unary, limiter.UnaryServerInterceptor)
+		stream = append(stream, limiter.StreamServerInterceptor)
+
+		zaphelper.Info(ctx, "grpc listener limits call rates", zap.String("config", cfg.rateLimitConfig))
+	}
+
+	if policy != nil {
+		var rbacOpts []rbac.Option
+		if cfg.rbacDryRun {
+			rbacOpts = append(rbacOpts, rbac.WithDryRun())
+		}
+		authorizer := rbac.NewAuthorizer(policy, rbacOpts...)
+		unary = append(unary, authorizer.UnaryServerInterceptor)
+		stream = append(stream, authorizer.StreamServerInterceptor)
+
+		zaphelper.Info(ctx, "grpc listener enforces rbac policy",
+			zap.String("policy", cfg.rbacPolicy),
+			zap.Bool("dry_run", cfg.rbacDryRun))
+	}
+
+	// after the quotas and rate limits, whose refusals are no sign of overload
+	if cfg.loadShed {
+		limiter := loadshed.NewLimiter(
+			loadshed.WithMethods(schemas.Greeter_SayHello_FullMethodName),
+			loadshed.WithLimits(cfg.loadShedInitial, 1, cfg.loadShedMax),
+			loadshed.WithTolerance(cfg.loadShedTolerance),
+		)
+		unary = append(unary, limiter.UnaryServerInterceptor)
 
-	client := grpc.NewClient(&svc)
+		zaphelper.Info(ctx, "grpc listener sheds load",
+			zap.Int("initial_limit", cfg.loadShedInitial),
+			zap.Int("max_limit", cfg.loadShedMax))
+	}
 
-	return []app.Runner{
+	grpcOpts = append(grpcOpts,
+		grpclistener.WithUnaryInterceptors(unary...),
+		grpclistener.WithStreamInterceptors(stream...),
+	)
+	runners = append(runners,
 		&asyncWaiter,
-		grpclistener.New(client),
-	}, ctx, nil
+		grpclistener.New(grpcRegisterer, grpcOpts...),
+	)
+	if cfg.grpcWeb {
+		// only the Greeter is bridged, the admin services are left to the gRPC listener and its transport security
+		runners = append(runners, grpcweb.New(client, append(webOpts, grpcweb.WithGRPCOptions(
+			googlegrpc.ChainUnaryInterceptor(unary...),
+			googlegrpc.ChainStreamInterceptor(stream...),
+		))...))
+	}
+	if cfg.websocket {
+		runners = append(runners, websocket.New(guarded, wsOpts...))
+	}
+	if cfg.tcp {
+		runners = append(runners, tcp.New(guarded, tcpOpts...))
+	}
+
+	return append(runners,
+		http.New(
+			http.MultiRegisterer(
+				gateway, openAPI, connectClient, events, graphqlClient,
+				http.NewMetrics(prometheus.DefaultGatherer),
+			),
+			httpOpts...,
+		),
+	), ctx, nil
+}
+
+// checkAdminProtected refuses to serve the admin methods enabled by flag unless the enforced rbac policy keeps them to
+// the callers it names, as anyone able to reach the listener could call them otherwise.
+func (cfg config) checkAdminProtected(policy *rbac.Policy, flag string, methods ...string) error {
+	if policy == nil || cfg.rbacDryRun {
+		return fmt.Errorf("%s serves admin methods, an enforced -rbac-policy must grant them to named callers", flag)
+	}
+	for _, m := range methods {
+		if !policy.Restricted(m) {
+			return fmt.Errorf("%s serves admin methods, the rbac policy must not grant %s to anyone or authenticated", flag, m)
+		}
+	}
+	return nil
+}
+
+// greetings is the service as GraphQL calls it, responding through the guarded service.
+type greetings struct {
+	*service.Service
+	guarded grpc.Service
+}
+
+func (g greetings) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	return g.guarded.Respond(ctx, request)
 }
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/LewisJAllan/greeter/capture"
+	"github.com/LewisJAllan/greeter/internal/clientconn"
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+type replayConfig struct {
+	conn    clientconn.Options
+	file    string
+	speed   float64
+	timeout time.Duration
+	verbose bool
+}
+
+// replayResult is the outcome of replaying one captured call.  diff is empty when the response matched.
+type replayResult struct {
+	call capture.Call
+	diff []string
+}
+
+// runReplay replays a capture against a server and reports the calls whose responses differ from the recorded ones.
+// It connects with the flags of greeter-cli such as -tls, -ca and -token.  Captures hold no credentials, so those
+// given by -token and -H are sent with every call instead:
+//
+//	greeter replay [-addr host:port] [-speed n] [-v] -file calls.bin
+func runReplay(args []string) error {
+	cfg, err := parseReplayFlags(args)
+	if err != nil {
+		return err
+	}
+
+	calls, err := capture.ReadFile(cfg.file)
+	if err != nil {
+		return err
+	}
+	if len(calls) == 0 {
+		return fmt.Errorf("no calls found in %s", cfg.file)
+	}
+
+	conn, err := cfg.conn.Dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results := replay(ctx, cfg, conn, calls)
+
+	failed := writeReplayReport(os.Stdout, cfg, results)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d replayed calls differed from the capture", failed, len(results))
+	}
+	return ctx.Err()
+}
+
+func parseReplayFlags(args []string) (replayConfig, error) {
+	var cfg replayConfig
+
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	cfg.conn.RegisterFlags(fs, "localhost:50051")
+	fs.StringVar(&cfg.file, "file", "", "capture to replay, as written by -capture")
+	fs.Float64Var(&cfg.speed, "speed", 1, "multiplier of the recorded pace, 0 to replay as fast as possible")
+	fs.DurationVar(&cfg.timeout, "timeout", time.Second*10, "deadline of calls captured without one")
+	fs.BoolVar(&cfg.verbose, "v", false, "list every call, not only those that differ")
+
+	if err := fs.Parse(args); err != nil {
+		return replayConfig{}, err
+	}
+	if cfg.file == "" {
+		return replayConfig{}, errors.New("-file is required")
+	}
+	if cfg.speed < 0 {
+		return replayConfig{}, errors.New("speed must not be negative")
+	}
+	if err := cfg.conn.Check(); err != nil {
+		return replayConfig{}, err
+	}
+	return cfg, nil
+}
+
+// replay sends each call at its recorded offset from the first, scaled by the speed, so calls that overlapped when
+// captured overlap again.
+func replay(ctx context.Context, cfg replayConfig, conn *grpc.ClientConn, calls []capture.Call) []replayResult {
+	results := make([]replayResult, len(calls))
+	first := calls[0].Start
+	begin := time.Now()
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		results[i].call = call
+
+		if cfg.speed > 0 {
+			at := begin.Add(time.Duration(float64(call.Start.Sub(first)) / cfg.speed))
+			select {
+			case <-ctx.Done():
+				results = results[:i]
+				wg.Wait()
+				return results
+			case <-time.After(time.Until(at)):
+			}
+		}
+
+		wg.Add(1)
+		go func(r *replayResult) {
+			defer wg.Done()
+			r.diff = replayCall(ctx, cfg, conn, r.call)
+		}(&results[i])
+
+		if cfg.speed == 0 {
+			// as fast as possible still keeps the calls in order
+			wg.Wait()
+		}
+	}
+	wg.Wait()
+
+	return results
+}
+
+func replayCall(ctx context.Context, cfg replayConfig, conn *grpc.ClientConn, call capture.Call) []string {
+	req, resp, err := newMessages(call.Method)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if err := proto.Unmarshal(call.Request, req); err != nil {
+		return []string{fmt.Sprintf("unable to decode captured request: %v", err)}
+	}
+
+	timeout := call.Timeout
+	if timeout <= 0 {
+		timeout = cfg.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Join(replayMetadata(call.Metadata), cfg.conn.Metadata()))
+
+	callErr := conn.Invoke(ctx, call.Method, req, resp)
+	st := status.Convert(callErr)
+
+	var diff []string
+	if st.Code() != call.Code {
+		diff = append(diff, fmt.Sprintf("code: captured %s, got %s", call.Code, st.Code()))
+	}
+	if st.Message() != call.StatusMessage {
+		diff = append(diff, fmt.Sprintf("message: captured %q, got %q", call.StatusMessage, st.Message()))
+	}
+	if callErr != nil || call.Code != 0 {
+		return diff
+	}
+
+	want := resp.ProtoReflect().New().Interface()
+	if err := proto.Unmarshal(call.Response, want); err != nil {
+		return append(diff, fmt.Sprintf("unable to decode captured response: %v", err))
+	}
+	if !proto.Equal(want, resp) {
+		format := protojson.MarshalOptions{}.Format
+		diff = append(diff, fmt.Sprintf("response: captured %s, got %s", format(want), format(resp)))
+	}
+	return diff
+}
+
+// newMessages returns empty request and response messages of method, which must be linked into the binary.
+func newMessages(method string) (proto.Message, proto.Message, error) {
+	name := protoreflect.FullName(strings.ReplaceAll(strings.TrimPrefix(method, "/"), "/", "."))
+
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown method %s: %w", method, err)
+	}
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a method", method)
+	}
+
+	in, err := protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown request type of %s: %w", method, err)
+	}
+	out, err := protoregistry.GlobalTypes.FindMessageByName(md.Output().FullName())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown response type of %s: %w", method, err)
+	}
+	return in.New().Interface(), out.New().Interface(), nil
+}
+
+// replayMetadata drops the metadata the transport sets itself, and the credentials, which were redacted when captured.
+func replayMetadata(md metadata.MD) metadata.MD {
+	out := metadata.MD{}
+	for k, v := range md {
+		switch {
+		case k == "content-type", k == "user-agent", k == "te", strings.HasPrefix(k, "grpc-"):
+			continue
+		case grpcutil.IsCredential(k):
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// writeReplayReport lists the calls that differed, or every call when verbose, and returns how many differed.
+func writeReplayReport(w io.Writer, cfg replayConfig, results []replayResult) int {
+	failed := 0
+	for _, r := range results {
+		if len(r.diff) == 0 {
+			if cfg.verbose {
+				fmt.Fprintf(w, "ok    #%d %s\n", r.call.ID, r.call.Method)
+			}
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(w, "DIFF  #%d %s\n", r.call.ID, r.call.Method)
+		for _, d := range r.diff {
+			fmt.Fprintf(w, "      %s\n", d)
+		}
+	}
+
+	fmt.Fprintf(w, "replayed %d calls against %s: %d matched, %d differed\n",
+		len(results), cfg.conn.Addr, len(results)-failed, failed)
+	return failed
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	schemas "github.com/LewisJAllan/schemas/playgroundpb/playground"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/LewisJAllan/greeter/capture"
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+func TestReplayMetadata(t *testing.T) {
+	md := metadata.MD{
+		"content-type":  {"application/grpc"},
+		"user-agent":    {"grpc-go"},
+		"te":            {"trailers"},
+		"grpc-timeout":  {"1S"},
+		"authorization": {grpcutil.Redacted},
+		"x-api-key":     {grpcutil.Redacted},
+		"x-request-id":  {"42"},
+	}
+	got := replayMetadata(md)
+	if len(got) != 1 || len(got.Get("x-request-id")) != 1 {
+		t.Errorf("replayMetadata() = %v, want only x-request-id", got)
+	}
+}
+
+func TestParseReplayFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "file", args: []string{"-file", "calls.bin"}},
+		{name: "tls and token", args: []string{"-file", "calls.bin", "-tls", "-token", "t"}},
+		{name: "no file", wantErr: "-file is required"},
+		{name: "negative speed", args: []string{"-file", "calls.bin", "-speed", "-1"}, wantErr: "speed must not be negative"},
+		{name: "key without cert", args: []string{"-file", "calls.bin", "-key", "k.pem"}, wantErr: "-cert and -key must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseReplayFlags(tt.args)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("parseReplayFlags() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("parseReplayFlags() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// replayGreeter greets in capitals when asked to shout and refuses calls without the bearer token "t".
+type replayGreeter struct {
+	schemas.UnimplementedGreeterServer
+}
+
+func (replayGreeter) SayHello(ctx context.Context, req *schemas.HelloRequest) (*schemas.HelloReply, error) {
+	if v := metadata.ValueFromIncomingContext(ctx, "authorization"); len(v) != 1 || v[0] != "Bearer t" {
+		return nil, status.Error(codes.Unauthenticated, "missing token")
+	}
+	if req.GetName() == "nobody" {
+		return nil, status.Error(codes.InvalidArgument, "no one to greet")
+	}
+	message := "Hello " + req.GetName()
+	if v := metadata.ValueFromIncomingContext(ctx, "x-shout"); len(v) > 0 {
+		message = strings.ToUpper(message)
+	}
+	return &schemas.HelloReply{Message: message}, nil
+}
+
+func capturedCall(t *testing.T, id uint64, start time.Time, name, reply string, code codes.Code, md metadata.MD) capture.Call {
+	t.Helper()
+	req, err := proto.Marshal(&schemas.HelloRequest{Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := capture.Call{
+		ID:       id,
+		Method:   schemas.Greeter_SayHello_FullMethodName,
+		Metadata: md,
+		Start:    start,
+		Request:  req,
+		Code:     code,
+	}
+	if code == codes.OK {
+		if call.Response, err = proto.Marshal(&schemas.HelloReply{Message: reply}); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		call.StatusMessage = reply
+	}
+	return call
+}
+
+func TestReplay(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	schemas.RegisterGreeterServer(s, replayGreeter{})
+	go func() { _ = s.Serve(l) }()
+	defer s.Stop()
+
+	cfg, err := parseReplayFlags([]string{"-addr", l.Addr().String(), "-file", "calls.bin", "-token", "t", "-speed", "0", "-v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := cfg.conn.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	redacted := metadata.Pairs("authorization", grpcutil.Redacted)
+	calls := []capture.Call{
+		capturedCall(t, 1, start, "Ann", "Hello Ann", codes.OK, redacted),
+		capturedCall(t, 2, start, "Bob", "HELLO BOB", codes.OK, metadata.Pairs("x-shout", "1")),
+		capturedCall(t, 3, start, "nobody", "no one to greet", codes.InvalidArgument, nil),
+		capturedCall(t, 4, start, "Cy", "Hello Cy", codes.OK, nil),
+		capturedCall(t, 5, start, "Di", "nope", codes.NotFound, nil),
+	}
+	// the server changed its mind about Cy
+	calls[3].Response, _ = proto.Marshal(&schemas.HelloReply{Message: "Hi Cy"})
+
+	results := replay(context.Background(), cfg, conn, calls)
+
+	var out bytes.Buffer
+	if failed := writeReplayReport(&out, cfg, results); failed != 2 {
+		t.Errorf("writeReplayReport() = %d, want 2", failed)
+	}
+	want := `ok    #1 /playground.Greeter/SayHello
+ok    #2 /playground.Greeter/SayHello
+ok    #3 /playground.Greeter/SayHello
+DIFF  #4 /playground.Greeter/SayHello
+      response: captured {"message":"Hi Cy"}, got {"message":"Hello Cy"}
+DIFF  #5 /playground.Greeter/SayHello
+      code: captured NotFound, got OK
+      message: captured "nope", got ""
+replayed 5 calls against ` + l.Addr().String() + `: 3 matched, 2 differed
+`
+	// protojson varies its whitespace from build to build
+	if got := strings.ReplaceAll(out.String(), " ", ""); got != strings.ReplaceAll(want, " ", "") {
+		t.Errorf("report =\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/LewisJAllan/greeter/admin"
+	"github.com/LewisJAllan/greeter/mock"
+)
+
+// runScenario manages the scenario of a greeter running with -mock:
+//
+//	greeter scenario [-addr host:port] set <file>
+//	greeter scenario [-addr host:port] get
+//	greeter scenario [-addr host:port] reset
+func runScenario(args []string) error {
+	fs := flag.NewFlagSet("scenario", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:50051", "address of the mock greeter")
+	timeout := fs.Duration("timeout", time.Second*10, "deadline of the call")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("unable to create client: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var out any
+	switch fs.Arg(0) {
+	case "set":
+		if fs.NArg() != 2 {
+			return errors.New("usage: greeter scenario set <file>")
+		}
+		scenario, err := mock.Load(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		out, err = admin.Invoke[mock.ScenarioInfo](ctx, conn, mock.SetScenarioFullMethodName, scenario)
+		if err != nil {
+			return err
+		}
+	case "get":
+		out, err = admin.Invoke[mock.ScenarioResponse](ctx, conn, mock.GetScenarioFullMethodName, &mock.Empty{})
+		if err != nil {
+			return err
+		}
+	case "reset":
+		out, err = admin.Invoke[mock.ScenarioInfo](ctx, conn, mock.ResetScenarioFullMethodName, &mock.Empty{})
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("usage: greeter scenario [-addr host:port] set <file> | get | reset")
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
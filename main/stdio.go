@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	async "github.com/LewisJAllan/application-helper/listeners/asynchronous"
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/LewisJAllan/greeter/listeners/jsonrpc"
+	"github.com/LewisJAllan/greeter/listeners/mcp"
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// stderrLogger logs to stderr for the modes where stdout carries a protocol.
+func stderrLogger() *zap.Logger {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+
+	return zap.New(zapcore.NewCore(
+		zapcore.NewConsoleEncoder(cfg),
+		zapcore.Lock(os.Stderr),
+		zap.NewAtomicLevelAt(zapcore.InfoLevel),
+	)).With(zap.String("service", ServiceName))
+}
+
+// stdioContext returns a context logging to stderr and cancelled on SIGINT or SIGTERM.  Anything else printing to
+// stdout is redirected to stderr, the returned writer is the original stdout.
+func stdioContext() (context.Context, context.CancelFunc, io.Writer) {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	ctx := zaphelper.With(context.Background(), stderrLogger())
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	return ctx, stop, out
+}
+
+// runStdio serves JSON-RPC 2.0 over stdin and stdout so tools can run the greeter as a subprocess without it opening
+// a port.
+func runStdio() error {
+	ctx, stop, out := stdioContext()
+	defer stop()
+
+	asyncWaiter := async.NewAsyncWaiter()
+	defer asyncWaiter.Wait()
+
+	// the client observes greetings issued by the service it calls, so it is given the service by reference
+	var svc service.Service
+	client := jsonrpc.NewClient(&svc)
+	svc = service.NewService(&asyncWaiter, service.WithGreetingObservers(client))
+
+	server := jsonrpc.NewServer(client)
+	go client.Forward(ctx)
+
+	zaphelper.Info(ctx, "serving json-rpc over stdio")
+
+	err := server.Serve(ctx, os.Stdin, out)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// runMCP serves the greeter as a Model Context Protocol tool server over stdin and stdout.
+func runMCP() error {
+	ctx, stop, out := stdioContext()
+	defer stop()
+
+	asyncWaiter := async.NewAsyncWaiter()
+	defer asyncWaiter.Wait()
+
+	svc := service.NewService(&asyncWaiter)
+	server := jsonrpc.NewServer(mcp.NewClient(&svc, ServiceName, "v1"))
+
+	zaphelper.Info(ctx, "serving mcp over stdio")
+
+	err := server.Serve(ctx, os.Stdin, out)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
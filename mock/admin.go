@@ -0,0 +1,78 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/LewisJAllan/greeter/admin"
+)
+
+const AdminServiceName = "greeter.mock.v1.MockAdmin"
+
+const (
+	SetScenarioFullMethodName   = "/" + AdminServiceName + "/SetScenario"
+	GetScenarioFullMethodName   = "/" + AdminServiceName + "/GetScenario"
+	ResetScenarioFullMethodName = "/" + AdminServiceName + "/ResetScenario"
+)
+
+// ScenarioInfo describes the scenario in use.
+type ScenarioInfo struct {
+	Name     string    `json:"name"`
+	Rules    int       `json:"rules"`
+	LoadedAt time.Time `json:"loadedAt"`
+}
+
+// ScenarioResponse returns the scenario in use.
+type ScenarioResponse struct {
+	Scenario Scenario     `json:"scenario"`
+	Info     ScenarioInfo `json:"info"`
+}
+
+type Empty struct{}
+
+// Admin is a grpc Registerer for the MockAdmin service, which swaps the scenario of a Service at runtime.
+type Admin struct {
+	service *Service
+}
+
+func NewAdmin(service *Service) *Admin {
+	return &Admin{service: service}
+}
+
+func (a *Admin) Register(s *grpc.Server) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: AdminServiceName,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			admin.UnaryMethod(AdminServiceName, "SetScenario", a.setScenario),
+			admin.UnaryMethod(AdminServiceName, "GetScenario", a.getScenario),
+			admin.UnaryMethod(AdminServiceName, "ResetScenario", a.resetScenario),
+		},
+	}, a)
+}
+
+func (a *Admin) setScenario(_ context.Context, s *Scenario) (*ScenarioInfo, error) {
+	if err := a.service.Swap(s); err != nil {
+		return nil, err
+	}
+	return a.info(), nil
+}
+
+func (a *Admin) getScenario(context.Context, *Empty) (*ScenarioResponse, error) {
+	s, _ := a.service.Scenario()
+	return &ScenarioResponse{Scenario: s, Info: *a.info()}, nil
+}
+
+func (a *Admin) resetScenario(context.Context, *Empty) (*ScenarioInfo, error) {
+	if err := a.service.Reset(); err != nil {
+		return nil, err
+	}
+	return a.info(), nil
+}
+
+func (a *Admin) info() *ScenarioInfo {
+	s, loadedAt := a.service.Scenario()
+	return &ScenarioInfo{Name: s.Name, Rules: len(s.Rules), LoadedAt: loadedAt}
+}
@@ -0,0 +1,145 @@
+// Package mock answers greetings from a Scenario instead of the service, giving client teams a predictable greeter for
+// their integration tests.
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/LewisJAllan/greeter/errdetails"
+	"github.com/LewisJAllan/greeter/service"
+)
+
+type compiled struct {
+	scenario *Scenario
+	rules    []*rule
+	fallback *response
+	loadedAt time.Time
+}
+
+type rule struct {
+	match     Match
+	pattern   *regexp.Regexp
+	responses []response
+	loop      bool
+
+	// calls counts the calls answered, selecting the next response of the sequence
+	calls atomic.Uint64
+}
+
+type response struct {
+	message string
+	delay   time.Duration
+
+	code       codes.Code
+	errMessage string
+	details    []*anypb.Any
+}
+
+// next returns the response for the next call, moving the sequence on.
+func (r *rule) next() response {
+	n := r.calls.Add(1) - 1
+	if r.loop {
+		return r.responses[n%uint64(len(r.responses))]
+	}
+	return r.responses[min(n, uint64(len(r.responses)-1))]
+}
+
+// Service answers greetings from the current scenario.  It satisfies the Service interfaces of the listeners so it
+// can stand in for service.Service.
+type Service struct {
+	current atomic.Pointer[compiled]
+}
+
+func New(s *Scenario) (*Service, error) {
+	m := &Service{}
+	if err := m.Swap(s); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Swap replaces the scenario, starting every sequence afresh.  The current scenario is kept when s is invalid.
+func (m *Service) Swap(s *Scenario) error {
+	c, err := s.compile()
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid scenario: %v", err)
+	}
+	c.loadedAt = time.Now()
+	m.current.Store(c)
+	return nil
+}
+
+// Reset starts every sequence of the current scenario afresh.
+func (m *Service) Reset() error {
+	return m.Swap(m.current.Load().scenario)
+}
+
+func (m *Service) Respond(ctx context.Context, request service.RespondRequest) (service.RespondResponse, error) {
+	c := m.current.Load()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var resp *response
+	for i, r := range c.rules {
+		if r.matches(request.OriginalMessage, md) {
+			next := r.next()
+			resp = &next
+			zaphelper.Debug(ctx, "mock rule matched", zap.Int("rule", i), zap.String("scenario", c.scenario.Name))
+			break
+		}
+	}
+	if resp == nil {
+		resp = c.fallback
+	}
+	if resp == nil {
+		return service.RespondResponse{}, status.Errorf(codes.Unimplemented, "no mock rule matches %q", request.OriginalMessage)
+	}
+
+	if resp.delay > 0 {
+		t := time.NewTimer(resp.delay)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return service.RespondResponse{}, status.FromContextError(ctx.Err()).Err()
+		case <-t.C:
+		}
+	}
+
+	if resp.code != codes.OK {
+		return service.RespondResponse{}, errdetails.Error(resp.code, resp.errMessage, resp.details...)
+	}
+
+	message := strings.ReplaceAll(resp.message, "{name}", request.OriginalMessage)
+	return service.RespondResponse{
+		ResponseMessage: message,
+		Greeting: service.Greeting{
+			Name:     request.OriginalMessage,
+			Locale:   request.Locale,
+			Style:    request.Style,
+			Message:  message,
+			IssuedAt: time.Now(),
+		},
+	}, nil
+}
+
+// Scenario returns a copy of the current scenario and when it was loaded.
+func (m *Service) Scenario() (Scenario, time.Time) {
+	c := m.current.Load()
+
+	// round trip through JSON so callers cannot modify the scenario in use
+	var s Scenario
+	b, _ := json.Marshal(c.scenario)
+	_ = json.Unmarshal(b, &s)
+	return s, c.loadedAt
+}
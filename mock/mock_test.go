@@ -0,0 +1,305 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func parseScenario(t *testing.T, src string) *Scenario {
+	t.Helper()
+	var s Scenario
+	if err := json.Unmarshal([]byte(src), &s); err != nil {
+		t.Fatalf("invalid scenario: %v", err)
+	}
+	return &s
+}
+
+func newService(t *testing.T, src string) *Service {
+	t.Helper()
+	m, err := New(parseScenario(t, src))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m
+}
+
+// answer greets name with md as the incoming metadata, returning the message or the error code and message.
+func answer(m *Service, name string, md metadata.MD) string {
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	resp, err := m.Respond(ctx, service.RespondRequest{OriginalMessage: name})
+	if err != nil {
+		st := status.Convert(err)
+		return st.Code().String() + " " + st.Message()
+	}
+	return resp.ResponseMessage
+}
+
+func TestServiceSequences(t *testing.T) {
+	m := newService(t, `{
+		"rules": [
+			{"match": {"name": "Ann"}, "responses": [
+				{"error": {"code": "UNAVAILABLE", "message": "try again"}},
+				{"message": "Hello {name}"}
+			]},
+			{"match": {"name": "Bob"}, "loop": true, "responses": [
+				{"message": "one"},
+				{"message": "two"}
+			]}
+		]
+	}`)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		// the last response repeats once the sequence is used up
+		{name: "Ann", want: []string{"Unavailable try again", "Hello Ann", "Hello Ann"}},
+		// a looping sequence starts over
+		{name: "Bob", want: []string{"one", "two", "one", "two"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for range tt.want {
+				got = append(got, answer(m, tt.name, nil))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("answers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Reset starts every sequence afresh
+	if err := m.Reset(); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if got := answer(m, "Ann", nil); got != "Unavailable try again" {
+		t.Errorf("answer after Reset = %q, want the first response", got)
+	}
+}
+
+func TestServiceMatch(t *testing.T) {
+	m := newService(t, `{
+		"rules": [
+			{"match": {"name": "Ann", "metadata": {"X-Env": "ci"}}, "responses": [{"message": "Ann in ci"}]},
+			{"match": {"namePattern": "^B"}, "responses": [{"message": "B {name}"}]},
+			{"match": {"metadata": {"x-env": "staging", "x-team": "web"}}, "responses": [{"message": "staging web"}]}
+		],
+		"default": {"message": "Hello {name}"}
+	}`)
+
+	tests := []struct {
+		name     string
+		greet    string
+		metadata metadata.MD
+		want     string
+	}{
+		{name: "name and metadata", greet: "Ann", metadata: metadata.Pairs("x-env", "ci"), want: "Ann in ci"},
+		{name: "one of several values", greet: "Ann", metadata: metadata.Pairs("x-env", "dev", "x-env", "ci"), want: "Ann in ci"},
+		{name: "metadata value differs", greet: "Ann", metadata: metadata.Pairs("x-env", "dev"), want: "Hello Ann"},
+		{name: "metadata missing", greet: "Ann", want: "Hello Ann"},
+		{name: "pattern", greet: "Bob", want: "B Bob"},
+		{name: "every metadata condition", greet: "Cy", metadata: metadata.Pairs("x-env", "staging", "x-team", "web"), want: "staging web"},
+		{name: "some metadata conditions", greet: "Cy", metadata: metadata.Pairs("x-env", "staging"), want: "Hello Cy"},
+		{name: "default", greet: "Cy", want: "Hello Cy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := answer(m, tt.greet, tt.metadata); got != tt.want {
+				t.Errorf("answer = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceNoDefault(t *testing.T) {
+	m := newService(t, `{"rules": [{"match": {"name": "Ann"}, "responses": [{"message": "Hi"}]}]}`)
+	if got, want := answer(m, "Bob", nil), `Unimplemented no mock rule matches "Bob"`; got != want {
+		t.Errorf("answer = %q, want %q", got, want)
+	}
+}
+
+func TestServiceErrorDetails(t *testing.T) {
+	m := newService(t, `{"rules": [{"responses": [{"error": {"code": "RESOURCE_EXHAUSTED", "message": "slow down", "details": [
+		{"retryInfo": {"retryDelay": "2s"}},
+		{"errorInfo": {"reason": "RATE_LIMITED", "domain": "greeter"}},
+		{"typeUrl": "type.googleapis.com/example.Custom", "value": "AQI="}
+	]}}]}]}`)
+
+	_, err := m.Respond(context.Background(), service.RespondRequest{OriginalMessage: "Ann"})
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted || st.Message() != "slow down" {
+		t.Errorf("Respond() error = %v, want ResourceExhausted slow down", err)
+	}
+
+	var types []string
+	for _, d := range st.Proto().GetDetails() {
+		types = append(types, d.GetTypeUrl())
+	}
+	want := []string{
+		"type.googleapis.com/google.rpc.RetryInfo",
+		"type.googleapis.com/google.rpc.ErrorInfo",
+		"type.googleapis.com/example.Custom",
+	}
+	if strings.Join(types, " ") != strings.Join(want, " ") {
+		t.Errorf("details = %v, want %v", types, want)
+	}
+}
+
+func TestServiceDelay(t *testing.T) {
+	m := newService(t, `{"default": {"message": "Hello", "delay": "1h"}}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err := m.Respond(ctx, service.RespondRequest{OriginalMessage: "Ann"})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Respond() error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestServiceSwap(t *testing.T) {
+	m := newService(t, `{"name": "first", "default": {"message": "first"}}`)
+
+	err := m.Swap(parseScenario(t, `{"name": "broken", "rules": [{"responses": []}]}`))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Swap() error = %v, want InvalidArgument", err)
+	}
+	if got := answer(m, "Ann", nil); got != "first" {
+		t.Errorf("answer after an invalid Swap = %q, want the first scenario kept", got)
+	}
+
+	if err := m.Swap(parseScenario(t, `{"name": "second", "default": {"message": "second"}}`)); err != nil {
+		t.Fatalf("Swap() error = %v", err)
+	}
+	if got := answer(m, "Ann", nil); got != "second" {
+		t.Errorf("answer after Swap = %q, want second", got)
+	}
+
+	// the copy returned cannot change the scenario in use
+	s, loadedAt := m.Scenario()
+	if s.Name != "second" || loadedAt.IsZero() {
+		t.Errorf("Scenario() = %s loaded at %v, want second", s.Name, loadedAt)
+	}
+	s.Default.Message = "changed"
+	if got := answer(m, "Ann", nil); got != "second" {
+		t.Errorf("answer after changing the copy = %q, want second", got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{name: "no responses", src: `{"rules": [{"responses": []}]}`, wantErr: "rule 0: at least one response is required"},
+		{name: "invalid pattern", src: `{"rules": [{"match": {"namePattern": "("}, "responses": [{}]}]}`, wantErr: "rule 0: invalid namePattern"},
+		{name: "negative delay", src: `{"rules": [{"responses": [{"delay": "-1s"}]}]}`, wantErr: "rule 0: response 0: delay must not be negative"},
+		{name: "ok error", src: `{"default": {"error": {"code": "OK"}}}`, wantErr: "default: error code must not be OK"},
+		{name: "empty detail", src: `{"default": {"error": {"code": "INTERNAL", "details": [{}]}}}`, wantErr: "default: detail 0: detail must set one of"},
+		{name: "every error", src: `{"rules": [{"responses": []}, {"responses": []}]}`, wantErr: "rule 0: at least one response is required\nrule 1:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseScenario(t, tt.src).compile()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("compile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	if err := os.WriteFile(valid, []byte(`{"name": "ci", "default": {"message": "Hello", "delay": "250ms"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"default": {"delay": 250}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(valid)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Name != "ci" || time.Duration(s.Default.Delay) != time.Millisecond*250 {
+		t.Errorf("Load() = %+v, want ci with a 250ms delay", s)
+	}
+
+	if _, err := Load(invalid); err == nil || !strings.Contains(err.Error(), "duration must be a string") {
+		t.Errorf("Load() error = %v, want the delay refused", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("Load() error = nil, want an error for a missing file")
+	}
+}
+
+func TestCodeJSON(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want string
+	}{
+		{code: codes.Unavailable, want: `"UNAVAILABLE"`},
+		{code: codes.ResourceExhausted, want: `"RESOURCE_EXHAUSTED"`},
+		{code: codes.InvalidArgument, want: `"INVALID_ARGUMENT"`},
+		{code: codes.OK, want: `"OK"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			b, err := json.Marshal(Code(tt.code))
+			if err != nil || string(b) != tt.want {
+				t.Fatalf("Marshal() = %s, %v, want %s", b, err, tt.want)
+			}
+			var got Code
+			if err := json.Unmarshal(b, &got); err != nil || codes.Code(got) != tt.code {
+				t.Errorf("Unmarshal(%s) = %v, %v, want %v", b, codes.Code(got), err, tt.code)
+			}
+		})
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	m := newService(t, `{"name": "first", "rules": [{"responses": [{"message": "one"}, {"message": "two"}]}]}`)
+	a := NewAdmin(m)
+	ctx := context.Background()
+
+	answer(m, "Ann", nil)
+	if _, err := a.resetScenario(ctx, &Empty{}); err != nil {
+		t.Fatalf("resetScenario() error = %v", err)
+	}
+	if got := answer(m, "Ann", nil); got != "one" {
+		t.Errorf("answer after resetScenario = %q, want one", got)
+	}
+
+	info, err := a.setScenario(ctx, parseScenario(t, `{"name": "second", "rules": [{"responses": [{"message": "a"}]}, {"responses": [{"message": "b"}]}]}`))
+	if err != nil {
+		t.Fatalf("setScenario() error = %v", err)
+	}
+	if info.Name != "second" || info.Rules != 2 {
+		t.Errorf("setScenario() = %+v, want second with 2 rules", info)
+	}
+	if _, err := a.setScenario(ctx, parseScenario(t, `{"rules": [{"responses": []}]}`)); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("setScenario() error = %v, want InvalidArgument", err)
+	}
+
+	got, err := a.getScenario(ctx, &Empty{})
+	if err != nil {
+		t.Fatalf("getScenario() error = %v", err)
+	}
+	if got.Scenario.Name != "second" || got.Info.Rules != 2 {
+		t.Errorf("getScenario() = %+v, want second with 2 rules", got)
+	}
+}
@@ -0,0 +1,259 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/LewisJAllan/greeter/errdetails"
+)
+
+// Scenario is the behaviour of the mock, read from JSON:
+//
+//	{
+//	  "name": "flaky",
+//	  "rules": [{
+//	    "match": {"name": "Ann", "metadata": {"x-env": "ci"}},
+//	    "responses": [
+//	      {"error": {"code": "UNAVAILABLE", "message": "try again", "details": [{"retryInfo": {"retryDelay": "1s"}}]}},
+//	      {"message": "Hello {name}", "delay": "50ms"}
+//	    ]
+//	  }],
+//	  "default": {"message": "Hello World"}
+//	}
+//
+// The first rule matching a call answers it.  A rule answers with its responses in turn, repeating the last one once
+// they are used up unless loop is set.  Calls matching no rule get the default, or Unimplemented without one.
+type Scenario struct {
+	Name    string    `json:"name,omitempty"`
+	Rules   []Rule    `json:"rules"`
+	Default *Response `json:"default,omitempty"`
+}
+
+type Rule struct {
+	Match     Match      `json:"match"`
+	Responses []Response `json:"responses"`
+	Loop      bool       `json:"loop,omitempty"`
+}
+
+// Match selects calls.  Every condition set must hold, an empty Match selects every call.
+type Match struct {
+	Name string `json:"name,omitempty"`
+	// NamePattern is a regular expression the name must match.
+	NamePattern string `json:"namePattern,omitempty"`
+	// Metadata holds request metadata, or HTTP headers, that must be present with the given values.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Response answers a call with Message, in which {name} is replaced by the name greeted, or with Error.
+type Response struct {
+	Message string   `json:"message,omitempty"`
+	Delay   Duration `json:"delay,omitempty"`
+	Error   *Error   `json:"error,omitempty"`
+}
+
+type Error struct {
+	Code    Code     `json:"code"`
+	Message string   `json:"message,omitempty"`
+	Details []Detail `json:"details,omitempty"`
+}
+
+// Detail is one of the well known error details, or any other as its type URL and base64 encoded value.
+type Detail struct {
+	ErrorInfo *struct {
+		Reason   string            `json:"reason"`
+		Domain   string            `json:"domain"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+	} `json:"errorInfo,omitempty"`
+	RetryInfo *struct {
+		RetryDelay Duration `json:"retryDelay"`
+	} `json:"retryInfo,omitempty"`
+	BadRequest *struct {
+		FieldViolations []errdetails.FieldViolation `json:"fieldViolations"`
+	} `json:"badRequest,omitempty"`
+	QuotaFailure *struct {
+		Violations []errdetails.QuotaViolation `json:"violations"`
+	} `json:"quotaFailure,omitempty"`
+
+	TypeURL string `json:"typeUrl,omitempty"`
+	Value   []byte `json:"value,omitempty"`
+}
+
+// Code reads and writes a codes.Code by its canonical name, such as "UNAVAILABLE".
+type Code codes.Code
+
+func (c Code) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	prev := ' '
+	for _, r := range codes.Code(c).String() {
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+		prev = r
+	}
+	return json.Marshal(b.String())
+}
+
+func (c *Code) UnmarshalJSON(b []byte) error {
+	return (*codes.Code)(c).UnmarshalJSON(b)
+}
+
+// Duration reads and writes a time.Duration as a string such as "250ms".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"250ms\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Load reads the scenario in the JSON file at path.
+func Load(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("mock: unable to read scenario: %w", err)
+	}
+
+	var s Scenario
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("mock: invalid scenario %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// compile checks the scenario, preparing its patterns and error details.
+func (s *Scenario) compile() (*compiled, error) {
+	c := &compiled{scenario: s}
+
+	var errs []error
+	for i, r := range s.Rules {
+		rule, err := compileRule(r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i, err))
+			continue
+		}
+		c.rules = append(c.rules, rule)
+	}
+
+	if s.Default != nil {
+		resp, err := compileResponse(*s.Default)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("default: %w", err))
+		}
+		c.fallback = &resp
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func compileRule(r Rule) (*rule, error) {
+	if len(r.Responses) == 0 {
+		return nil, errors.New("at least one response is required")
+	}
+
+	out := &rule{match: r.Match, loop: r.Loop}
+	if r.Match.NamePattern != "" {
+		re, err := regexp.Compile(r.Match.NamePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namePattern: %w", err)
+		}
+		out.pattern = re
+	}
+
+	for i, resp := range r.Responses {
+		c, err := compileResponse(resp)
+		if err != nil {
+			return nil, fmt.Errorf("response %d: %w", i, err)
+		}
+		out.responses = append(out.responses, c)
+	}
+	return out, nil
+}
+
+func compileResponse(r Response) (response, error) {
+	if r.Delay < 0 {
+		return response{}, errors.New("delay must not be negative")
+	}
+	out := response{message: r.Message, delay: time.Duration(r.Delay)}
+
+	if r.Error == nil {
+		return out, nil
+	}
+	if codes.Code(r.Error.Code) == codes.OK {
+		return response{}, errors.New("error code must not be OK")
+	}
+
+	out.code = codes.Code(r.Error.Code)
+	out.errMessage = r.Error.Message
+	for i, d := range r.Error.Details {
+		a, err := d.encode()
+		if err != nil {
+			return response{}, fmt.Errorf("detail %d: %w", i, err)
+		}
+		out.details = append(out.details, a)
+	}
+	return out, nil
+}
+
+func (d Detail) encode() (*anypb.Any, error) {
+	switch {
+	case d.ErrorInfo != nil:
+		return errdetails.ErrorInfo(d.ErrorInfo.Reason, d.ErrorInfo.Domain, d.ErrorInfo.Metadata), nil
+	case d.RetryInfo != nil:
+		return errdetails.RetryInfo(time.Duration(d.RetryInfo.RetryDelay)), nil
+	case d.BadRequest != nil:
+		return errdetails.BadRequest(d.BadRequest.FieldViolations...), nil
+	case d.QuotaFailure != nil:
+		return errdetails.QuotaFailure(d.QuotaFailure.Violations...), nil
+	case d.TypeURL != "":
+		return &anypb.Any{TypeUrl: d.TypeURL, Value: d.Value}, nil
+	default:
+		return nil, errors.New("detail must set one of errorInfo, retryInfo, badRequest, quotaFailure or typeUrl")
+	}
+}
+
+// matches reports whether the call greeting name with metadata md is selected by the rule.
+func (r *rule) matches(name string, md map[string][]string) bool {
+	if r.match.Name != "" && r.match.Name != name {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(name) {
+		return false
+	}
+	for k, want := range r.match.Metadata {
+		found := false
+		for _, v := range md[strings.ToLower(k)] {
+			if v == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
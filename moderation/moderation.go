@@ -0,0 +1,291 @@
+// Package moderation checks the names sent to the greeter before they are greeted, so that it never echoes offensive
+// input back to users.
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+
+	"github.com/LewisJAllan/greeter/errdetails"
+	"github.com/LewisJAllan/greeter/service"
+)
+
+// Action is what is done with a name breaking a rule.
+type Action string
+
+const (
+	// Reject refuses the request with InvalidArgument.
+	Reject Action = "reject"
+	// Mask replaces the offending words with asterisks.
+	Mask Action = "mask"
+	// Anonymous greets the caller under the anonymous name instead of the one given.
+	Anonymous Action = "anonymous"
+)
+
+// severity orders the actions, the most severe one applies when a name breaks several rules.
+func (a Action) severity() int {
+	switch a {
+	case Reject:
+		return 3
+	case Anonymous:
+		return 2
+	case Mask:
+		return 1
+	default:
+		return 0
+	}
+}
+
+var actions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "greeter",
+	Subsystem: "moderation",
+	Name:      "actions_total",
+	Help:      "Names moderated, by action taken.",
+}, []string{"action"})
+
+func init() {
+	prometheus.MustRegister(actions)
+}
+
+// Config is read from JSON:
+//
+//	{
+//	  "action": "mask",
+//	  "anonymousName": "friend",
+//	  "blocklist": ["badword"],
+//	  "rules": [{"name": "threats", "pattern": "kill\\s*(you|u)", "action": "reject"}],
+//	  "locales": {
+//	    "fr": {"blocklist": ["motinterdit"], "rules": []}
+//	  }
+//	}
+//
+// Blocklisted words match whole words of the name once it is lower cased and stripped of accents, leetspeak and
+// look-alike characters, with repeated letters collapsed and spaced out letters joined.  Rules are regular
+// expressions matched against the same normalised name, so they are written in lower case without accents.  Both
+// take the action of the configuration unless a rule names its own.  Locales add their rules to the global ones for
+// requests in that locale, "fr" applying to "fr-CA" too.
+type Config struct {
+	Action        Action                  `json:"action"`
+	AnonymousName string                  `json:"anonymousName"`
+	Blocklist     []string                `json:"blocklist"`
+	Rules         []Rule                  `json:"rules"`
+	Locales       map[string]LocaleConfig `json:"locales"`
+}
+
+type LocaleConfig struct {
+	Blocklist []string `json:"blocklist"`
+	Rules     []Rule   `json:"rules"`
+}
+
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Action  Action `json:"action,omitempty"`
+}
+
+// ruleSet is the compiled blocklist and rules of the global configuration or a locale.
+type ruleSet struct {
+	blocklist map[string]struct{}
+	rules     []compiledRule
+}
+
+type compiledRule struct {
+	name   string
+	re     *regexp.Regexp
+	action Action
+}
+
+// Moderator is a service.Moderator applying a Config.
+type Moderator struct {
+	action        Action
+	anonymousName string
+	global        ruleSet
+	locales       map[string]ruleSet
+}
+
+// Load reads the configuration in the JSON file at path.
+func Load(path string) (*Moderator, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("moderation: unable to read config: %w", err)
+	}
+
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("moderation: invalid config %s: %w", path, err)
+	}
+	m, err := New(c)
+	if err != nil {
+		return nil, fmt.Errorf("moderation: invalid config %s: %w", path, err)
+	}
+	return m, nil
+}
+
+func New(c Config) (*Moderator, error) {
+	if c.Action == "" {
+		c.Action = Reject
+	}
+	if c.AnonymousName == "" {
+		c.AnonymousName = "friend"
+	}
+
+	var errs []error
+	if c.Action.severity() == 0 {
+		errs = append(errs, fmt.Errorf("action must be reject, mask or anonymous, not %q", c.Action))
+	}
+
+	m := &Moderator{
+		action:        c.Action,
+		anonymousName: c.AnonymousName,
+		locales:       make(map[string]ruleSet, len(c.Locales)),
+	}
+	m.global, errs = compile("", c.Blocklist, c.Rules, c.Action, errs)
+	for locale, lc := range c.Locales {
+		m.locales[strings.ToLower(locale)], errs = compile(locale, lc.Blocklist, lc.Rules, c.Action, errs)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func compile(locale string, blocklist []string, rules []Rule, action Action, errs []error) (ruleSet, []error) {
+	prefix := ""
+	if locale != "" {
+		prefix = "locale " + locale + ": "
+	}
+
+	rs := ruleSet{blocklist: make(map[string]struct{}, len(blocklist))}
+	for _, word := range blocklist {
+		rs.blocklist[squeeze(normalize(word).text)] = struct{}{}
+	}
+	for i, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%srule %d: %w", prefix, i, err))
+			continue
+		}
+		if r.Action == "" {
+			r.Action = action
+		}
+		if r.Action.severity() == 0 {
+			errs = append(errs, fmt.Errorf("%srule %d: action must be reject, mask or anonymous, not %q", prefix, i, r.Action))
+		}
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("%srule %d", prefix, i)
+		}
+		rs.rules = append(rs.rules, compiledRule{name: name, re: re, action: r.Action})
+	}
+	return rs, errs
+}
+
+// match is a part of the name breaking a rule, as a byte span of the normalized name.
+type match struct {
+	rule       string
+	action     Action
+	start, end int
+}
+
+// Moderate returns the request with its name masked or replaced, or an InvalidArgument error when it is rejected.
+func (m *Moderator) Moderate(ctx context.Context, request service.RespondRequest) (service.RespondRequest, error) {
+	n := normalize(request.OriginalMessage)
+
+	matches := m.global.find(n, m.action)
+	if rs, ok := m.localeRules(request.Locale); ok {
+		matches = append(matches, rs.find(n, m.action)...)
+	}
+	if len(matches) == 0 {
+		return request, nil
+	}
+
+	action := Mask
+	rules := make([]string, 0, len(matches))
+	for _, mt := range matches {
+		if mt.action.severity() > action.severity() {
+			action = mt.action
+		}
+		rules = append(rules, mt.rule)
+	}
+
+	actions.WithLabelValues(string(action)).Inc()
+	// the name itself is not logged, it is offensive
+	zaphelper.Info(ctx, "name moderated",
+		zap.String("action", string(action)),
+		zap.Strings("rules", rules),
+		zap.String("locale", request.Locale))
+
+	switch action {
+	case Reject:
+		return request, errdetails.Error(codes.InvalidArgument, "name is not allowed",
+			errdetails.BadRequest(errdetails.FieldViolation{Field: "name", Description: "contains words that are not allowed"}),
+		)
+	case Anonymous:
+		request.OriginalMessage = m.anonymousName
+	default:
+		request.OriginalMessage = mask(request.OriginalMessage, n, matches)
+	}
+	return request, nil
+}
+
+// localeRules returns the rules of locale, or of its language when the locale has a region.
+func (m *Moderator) localeRules(locale string) (ruleSet, bool) {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if rs, ok := m.locales[locale]; ok {
+		return rs, true
+	}
+	language, _, _ := strings.Cut(locale, "-")
+	rs, ok := m.locales[language]
+	return rs, ok
+}
+
+func (rs ruleSet) find(n normalized, action Action) []match {
+	var out []match
+	for _, t := range n.tokens() {
+		if _, ok := rs.blocklist[squeeze(t.word)]; ok {
+			out = append(out, match{rule: "blocklist", action: action, start: t.start, end: t.end})
+		}
+	}
+	for _, r := range rs.rules {
+		for _, loc := range r.re.FindAllStringIndex(n.text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			out = append(out, match{rule: r.name, action: r.action, start: loc[0], end: loc[1]})
+		}
+	}
+	return out
+}
+
+// mask replaces every character of the original name covered by a match with an asterisk.
+func mask(original string, n normalized, matches []match) string {
+	masked := make([]bool, len(original))
+	for _, mt := range matches {
+		start, end := n.span(mt.start, mt.end)
+		for i := start; i < end; i++ {
+			masked[i] = true
+		}
+	}
+
+	var b strings.Builder
+	for i, r := range original {
+		if masked[i] && r != ' ' {
+			b.WriteByte('*')
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(original[i:])
+		b.WriteString(original[i : i+size])
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package moderation
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/service"
+)
+
+func TestModerate(t *testing.T) {
+	m, err := New(Config{
+		Action:    Mask,
+		Blocklist: []string{"badword"},
+		Rules: []Rule{
+			{Name: "threats", Pattern: `kill\s*(you|u)`, Action: Reject},
+			{Name: "impersonation", Pattern: `admin`, Action: Anonymous},
+		},
+		Locales: map[string]LocaleConfig{
+			"fr": {Blocklist: []string{"motinterdit"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		request  service.RespondRequest
+		want     string
+		wantCode codes.Code
+	}{
+		{name: "clean", request: service.RespondRequest{OriginalMessage: "Ann"}, want: "Ann"},
+		{name: "blocklisted", request: service.RespondRequest{OriginalMessage: "Ann badword"}, want: "Ann *******"},
+		{name: "leetspeak", request: service.RespondRequest{OriginalMessage: "b4dw0rd"}, want: "*******"},
+		{name: "repeated letters", request: service.RespondRequest{OriginalMessage: "baaadword"}, want: "*********"},
+		{name: "spaced out", request: service.RespondRequest{OriginalMessage: "b a d w o r d"}, want: "* * * * * * *"},
+		{name: "fullwidth", request: service.RespondRequest{OriginalMessage: "ｂａｄｗｏｒｄ"}, want: "*******"},
+		{name: "accents", request: service.RespondRequest{OriginalMessage: "bâdwörd"}, want: "*******"},
+		{name: "word inside another", request: service.RespondRequest{OriginalMessage: "notabadwordy"}, want: "notabadwordy"},
+		{name: "reject rule", request: service.RespondRequest{OriginalMessage: "I will kill you"}, wantCode: codes.InvalidArgument},
+		{name: "most severe action", request: service.RespondRequest{OriginalMessage: "badword admin"}, want: "friend"},
+		{name: "locale", request: service.RespondRequest{OriginalMessage: "motinterdit", Locale: "fr-CA"}, want: "***********"},
+		{name: "other locale", request: service.RespondRequest{OriginalMessage: "motinterdit", Locale: "en"}, want: "motinterdit"},
+
+		{name: "invalid utf-8 after match", request: service.RespondRequest{OriginalMessage: "badword\xff"}, want: "*******\xff"},
+		{name: "invalid utf-8 before match", request: service.RespondRequest{OriginalMessage: "\xff\xfe badword"}, want: "\xff\xfe *******"},
+		{name: "invalid utf-8 inside match", request: service.RespondRequest{OriginalMessage: "bad\xffword"}, want: "bad\xffword"},
+		{name: "invalid utf-8 only", request: service.RespondRequest{OriginalMessage: "\xff"}, want: "\xff"},
+		{name: "truncated rune", request: service.RespondRequest{OriginalMessage: "badword \xe2\x82"}, want: "******* \xe2\x82"},
+		{name: "invalid utf-8 rejected", request: service.RespondRequest{OriginalMessage: "kill you\xff"}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Moderate(context.Background(), tt.request)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Moderate() code = %v, want %v (error %v)", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			if got.OriginalMessage != tt.want {
+				t.Errorf("Moderate() name = %q, want %q", got.OriginalMessage, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "unknown action", config: Config{Action: "shout"}},
+		{name: "unknown rule action", config: Config{Rules: []Rule{{Pattern: "x", Action: "shout"}}}},
+		{name: "invalid pattern", config: Config{Rules: []Rule{{Pattern: "("}}}},
+		{name: "invalid locale pattern", config: Config{Locales: map[string]LocaleConfig{"fr": {Rules: []Rule{{Pattern: "("}}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Error("New() error = nil, want an error")
+			}
+		})
+	}
+}
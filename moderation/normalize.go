@@ -0,0 +1,151 @@
+package moderation
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables maps characters commonly used to disguise words to the letters they pass for: leetspeak digits and
+// symbols, and Cyrillic and Greek letters looking like Latin ones.  Fullwidth forms and accents are handled apart.
+var confusables = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l', '+': 't', '€': 'e', '£': 'l',
+
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't',
+	'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u',
+	'χ': 'x', 'ω': 'w',
+}
+
+// normalized is a text folded for matching, rune for rune, so that matches can be traced back to the original text.
+type normalized struct {
+	text string
+	// offsets holds, for each rune of text, its byte offset in text and the byte span of the rune it came from
+	offsets []runeOffset
+}
+
+type runeOffset struct {
+	normalized int
+	start, end int
+}
+
+// normalize lower cases s, strips accents and replaces fullwidth and confusable characters.
+func normalize(s string) normalized {
+	var (
+		n   normalized
+		out = make([]byte, 0, len(s))
+	)
+	for i, r := range s {
+		// the width of the rune as read, an invalid byte decodes to utf8.RuneError but is a single byte wide
+		_, size := utf8.DecodeRuneInString(s[i:])
+		n.offsets = append(n.offsets, runeOffset{normalized: len(out), start: i, end: i + size})
+		out = utf8.AppendRune(out, fold(r))
+	}
+	n.text = string(out)
+	return n
+}
+
+func fold(r rune) rune {
+	// fullwidth ASCII, as in ｈｅｌｌｏ
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r -= 0xFF01 - '!'
+	}
+	r = unicode.ToLower(r)
+	if c, ok := confusables[r]; ok {
+		return c
+	}
+	// the base letter of an accented one, as in é
+	if d := norm.NFD.String(string(r)); d != string(r) {
+		base, _ := utf8.DecodeRuneInString(d)
+		if c, ok := confusables[base]; ok {
+			return c
+		}
+		return base
+	}
+	return r
+}
+
+// span returns the byte span in the original text of the normalized bytes [start, end).
+func (n normalized) span(start, end int) (int, int) {
+	first, last := -1, -1
+	for i, o := range n.offsets {
+		if o.normalized >= end {
+			break
+		}
+		if o.normalized >= start {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return 0, 0
+	}
+	return n.offsets[first].start, n.offsets[last].end
+}
+
+// token is a word of a normalized text.
+type token struct {
+	word       string
+	start, end int
+}
+
+// tokens splits n into words, as byte spans of the normalized text.  Runs of single letters, as in "b a d" or
+// "b.a.d", are joined into a word as well.
+func (n normalized) tokens() []token {
+	var (
+		out   []token
+		start = -1
+	)
+	for i, r := range n.text {
+		if unicode.IsLetter(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			out = append(out, token{word: n.text[start:i], start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		out = append(out, token{word: n.text[start:], start: start, end: len(n.text)})
+	}
+
+	var joined []token
+	for i := 0; i < len(out); {
+		j := i
+		for j < len(out) && utf8.RuneCountInString(out[j].word) == 1 {
+			j++
+		}
+		if j-i > 1 {
+			var word []byte
+			for _, t := range out[i:j] {
+				word = append(word, t.word...)
+			}
+			joined = append(joined, token{word: string(word), start: out[i].start, end: out[j-1].end})
+		}
+		if j == i {
+			j++
+		}
+		i = j
+	}
+	return append(out, joined...)
+}
+
+// squeeze collapses repeated letters, so that "baaad" matches "bad".
+func squeeze(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if len(out) > 0 && out[len(out)-1] == r {
+			continue
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets that refilled are dropped, a full bucket behaves as a missing one.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// taken describes the state of a bucket after a call tried to take a token.
+type taken struct {
+	ok        bool
+	remaining int
+	// reset is how long until the bucket is full again.
+	reset time.Duration
+	// retryAfter is how long until a token is available, when none was.
+	retryAfter time.Duration
+}
+
+type buckets struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newBuckets() *buckets {
+	return &buckets{buckets: make(map[string]*bucket)}
+}
+
+// take takes a token from the bucket of key, which is refilled as l says.
+func (bs *buckets) take(key string, l Limit, now time.Time) taken {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if now.Sub(bs.lastSweep) >= sweepInterval {
+		bs.sweep(l, now)
+	}
+
+	b, ok := bs.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		bs.buckets[key] = b
+	}
+	return b.take(l, now)
+}
+
+// take refills b as l says and takes a token from it.
+func (b *bucket) take(l Limit, now time.Time) taken {
+	burst := float64(l.Burst)
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	t := taken{ok: b.tokens >= 1}
+	if t.ok {
+		b.tokens--
+	} else {
+		t.retryAfter = seconds((1 - b.tokens) / l.Rate)
+	}
+	t.remaining = int(b.tokens)
+	t.reset = seconds((burst - b.tokens) / l.Rate)
+	return t
+}
+
+// sweep drops the buckets that are full by now.  Limits only differ between methods, which have their own buckets.
+func (bs *buckets) sweep(l Limit, now time.Time) {
+	for key, b := range bs.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.Rate >= float64(l.Burst) {
+			delete(bs.buckets, key)
+		}
+	}
+	bs.lastSweep = now
+}
+
+// Bucket is a single token bucket, for limiting what isn't a gRPC call such as the messages of a connection.  It is
+// not safe for concurrent use.
+type Bucket struct {
+	limit Limit
+	b     bucket
+}
+
+// NewBucket returns a full bucket refilled with rate tokens per second up to burst.
+func NewBucket(rate float64, burst int) *Bucket {
+	return &Bucket{
+		limit: Limit{Rate: rate, Burst: burst},
+		b:     bucket{tokens: float64(burst), last: time.Now()},
+	}
+}
+
+// Allow takes a token from the bucket, reporting whether there was one.
+func (b *Bucket) Allow(now time.Time) bool {
+	return b.b.take(b.limit, now).ok
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
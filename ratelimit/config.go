@@ -0,0 +1,116 @@
+// Package ratelimit limits the rate of gRPC calls with token buckets, one per caller and method.
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyType selects what calls sharing a bucket have in common.
+type KeyType string
+
+const (
+	// KeyIdentity shares a bucket between the calls of an authenticated caller: its API key, token subject or client
+	// certificate.  Unauthenticated calls are keyed on their peer IP.
+	KeyIdentity KeyType = "identity"
+	// KeyIP shares a bucket between the calls from an IP address.
+	KeyIP KeyType = "ip"
+	// KeyTenant shares a bucket between the calls sending the same tenant metadata.  Calls without it are keyed on
+	// their peer IP.
+	KeyTenant KeyType = "tenant"
+)
+
+// Limit is a token bucket refilled with Rate tokens per second up to Burst, each call taking a token.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+	Key   KeyType `json:"key"`
+}
+
+// Config is read from JSON:
+//
+//	{
+//	  "default": {"rate": 20, "burst": 40, "key": "ip"},
+//	  "methods": {
+//	    "/playground.Greeter/SayHello": {"rate": 5, "burst": 10, "key": "identity"}
+//	  },
+//	  "tenantMetadata": "x-tenant"
+//	}
+//
+// Methods are full method names, calls to methods without a limit use the default one and are not limited when there
+// is none.
+type Config struct {
+	Default *Limit           `json:"default"`
+	Methods map[string]Limit `json:"methods"`
+	// TenantMetadata is the metadata key naming the tenant of a call, x-tenant by default.
+	TenantMetadata string `json:"tenantMetadata"`
+}
+
+// LoadConfig reads the configuration in the JSON file at path, checking it is consistent.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: unable to read config: %w", err)
+	}
+
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("ratelimit: invalid config %s: %w", path, err)
+	}
+	if c.TenantMetadata == "" {
+		c.TenantMetadata = "x-tenant"
+	}
+	c.TenantMetadata = strings.ToLower(c.TenantMetadata)
+	if err := c.check(); err != nil {
+		return nil, fmt.Errorf("ratelimit: invalid config %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Config) check() error {
+	var errs []error
+	if c.Default != nil {
+		if err := c.Default.check(); err != nil {
+			errs = append(errs, fmt.Errorf("default: %w", err))
+		}
+	}
+	for method, l := range c.Methods {
+		if !strings.HasPrefix(method, "/") {
+			errs = append(errs, fmt.Errorf("method %q must be a full method name such as /package.Service/Method", method))
+		}
+		if err := l.check(); err != nil {
+			errs = append(errs, fmt.Errorf("method %s: %w", method, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l Limit) check() error {
+	var errs []error
+	if l.Rate <= 0 {
+		errs = append(errs, errors.New("rate must be positive"))
+	}
+	if l.Burst < 1 {
+		errs = append(errs, errors.New("burst must be at least 1"))
+	}
+	switch l.Key {
+	case KeyIdentity, KeyIP, KeyTenant:
+	default:
+		errs = append(errs, fmt.Errorf("key must be identity, ip or tenant, not %q", l.Key))
+	}
+	return errors.Join(errs...)
+}
+
+// limit returns the limit of method.
+func (c *Config) limit(method string) (Limit, bool) {
+	if l, ok := c.Methods[method]; ok {
+		return l, true
+	}
+	if c.Default != nil {
+		return *c.Default, true
+	}
+	return Limit{}, false
+}
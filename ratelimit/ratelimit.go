@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/LewisJAllan/greeter/apikey"
+	"github.com/LewisJAllan/greeter/auth"
+	"github.com/LewisJAllan/greeter/errdetails"
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+	"github.com/LewisJAllan/greeter/ipfilter"
+	"github.com/LewisJAllan/greeter/tlsconfig"
+)
+
+// Headers reporting the bucket of a call, sent with the response headers of limited methods.
+const (
+	LimitHeader     = "x-ratelimit-limit"
+	RemainingHeader = "x-ratelimit-remaining"
+	ResetHeader     = "x-ratelimit-reset"
+)
+
+// exceeded is labelled as the go-grpc-prometheus metrics are, so that it can be compared with grpc_server_started_total.
+var exceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "greeter",
+	Subsystem: "ratelimit",
+	Name:      "exceeded_total",
+	Help:      "Calls refused for exceeding their rate limit, by method and key type.",
+}, []string{"grpc_service", "grpc_method", "key"})
+
+func init() {
+	prometheus.MustRegister(exceeded)
+}
+
+// Limiter provides interceptors enforcing the limits of a Config.  They must be installed after the interceptors
+// establishing the caller identity for limits keyed on identity.
+type Limiter struct {
+	config *Config
+	now    func() time.Time
+
+	mu      sync.Mutex
+	methods map[string]*buckets
+}
+
+func NewLimiter(config *Config) *Limiter {
+	return &Limiter{config: config, now: time.Now, methods: make(map[string]*buckets)}
+}
+
+func (l *Limiter) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, err := l.take(ctx, info.FullMethod)
+	if md != nil {
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			zaphelper.Warn(ctx, "unable to set rate limit headers", zap.Error(err))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *Limiter) StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, err := l.take(ss.Context(), info.FullMethod)
+	if md != nil {
+		if err := ss.SetHeader(md); err != nil {
+			zaphelper.Warn(ss.Context(), "unable to set rate limit headers", zap.Error(err))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// take takes a token for a call to method, returning the headers describing its bucket.
+func (l *Limiter) take(ctx context.Context, method string) (metadata.MD, error) {
+	limit, ok := l.config.limit(method)
+	if !ok {
+		return nil, nil
+	}
+
+	key := l.key(ctx, limit.Key)
+	t := l.buckets(method).take(key, limit, l.now())
+
+	md := metadata.Pairs(
+		LimitHeader, strconv.Itoa(limit.Burst),
+		RemainingHeader, strconv.Itoa(t.remaining),
+		ResetHeader, strconv.Itoa(int(math.Ceil(t.reset.Seconds()))),
+	)
+	if t.ok {
+		return md, nil
+	}
+
+	service, name := grpcutil.SplitMethod(method)
+	exceeded.WithLabelValues(service, name, string(limit.Key)).Inc()
+	zaphelper.Info(ctx, "rate limit exceeded",
+		zap.String("method", method),
+		zap.String("key", key),
+		zap.Duration("retry_after", t.retryAfter))
+
+	return md, errdetails.Error(codes.ResourceExhausted,
+		fmt.Sprintf("rate limit of %g calls per second exceeded", limit.Rate),
+		errdetails.RetryInfo(t.retryAfter),
+		errdetails.QuotaFailure(errdetails.QuotaViolation{Subject: key, Description: "rate limit of " + method + " exceeded"}),
+	)
+}
+
+func (l *Limiter) buckets(method string) *buckets {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bs, ok := l.methods[method]
+	if !ok {
+		bs = newBuckets()
+		l.methods[method] = bs
+	}
+	return bs
+}
+
+// key returns the bucket of the caller, prefixed by its kind so that an IP never shares the bucket of an identity.
+func (l *Limiter) key(ctx context.Context, t KeyType) string {
+	switch t {
+	case KeyIdentity:
+		if k, ok := apikey.KeyFromContext(ctx); ok {
+			return "apikey:" + k.ID
+		}
+		if c, ok := auth.ClaimsFromContext(ctx); ok {
+			return "subject:" + c.Issuer + "/" + c.Subject
+		}
+		if id, ok := tlsconfig.IdentityFromContext(ctx); ok {
+			return "cert:" + id.Name()
+		}
+	case KeyTenant:
+		if v := metadata.ValueFromIncomingContext(ctx, l.config.TenantMetadata); len(v) > 0 {
+			return "tenant:" + v[0]
+		}
+	}
+	return "ip:" + ipfilter.PeerIP(ctx)
+}
@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/apikey"
+	"github.com/LewisJAllan/greeter/auth"
+	"github.com/LewisJAllan/greeter/ipfilter"
+	"github.com/LewisJAllan/greeter/tlsconfig"
+)
+
+func TestBucketsTake(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	limit := Limit{Rate: 2, Burst: 3, Key: KeyIP}
+
+	type call struct {
+		at            time.Duration
+		key           string
+		wantOK        bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}
+	tests := []struct {
+		name  string
+		calls []call
+	}{
+		{
+			name: "burst",
+			calls: []call{
+				{at: 0, key: "a", wantOK: true, wantRemaining: 2},
+				{at: 0, key: "a", wantOK: true, wantRemaining: 1},
+				{at: 0, key: "a", wantOK: true, wantRemaining: 0},
+				{at: 0, key: "a", wantOK: false, wantRemaining: 0, wantRetry: time.Millisecond * 500},
+			},
+		},
+		{
+			name: "refill",
+			calls: []call{
+				{at: 0, key: "a", wantOK: true, wantRemaining: 2},
+				{at: 0, key: "a", wantOK: true, wantRemaining: 1},
+				{at: 0, key: "a", wantOK: true, wantRemaining: 0},
+				{at: time.Millisecond * 250, key: "a", wantOK: false, wantRemaining: 0, wantRetry: time.Millisecond * 250},
+				{at: time.Millisecond * 500, key: "a", wantOK: true, wantRemaining: 0},
+				{at: time.Second * 10, key: "a", wantOK: true, wantRemaining: 2},
+			},
+		},
+		{
+			name: "keys have their own bucket",
+			calls: []call{
+				{at: 0, key: "a", wantOK: true, wantRemaining: 2},
+				{at: 0, key: "a", wantOK: true, wantRemaining: 1},
+				{at: 0, key: "a", wantOK: true, wantRemaining: 0},
+				{at: 0, key: "b", wantOK: true, wantRemaining: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := newBuckets()
+			for i, c := range tt.calls {
+				got := bs.take(c.key, limit, start.Add(c.at))
+				if got.ok != c.wantOK || got.remaining != c.wantRemaining || got.retryAfter != c.wantRetry {
+					t.Errorf("take() call %d = %+v, want ok %v, remaining %d, retry after %v", i, got, c.wantOK, c.wantRemaining, c.wantRetry)
+				}
+			}
+		})
+	}
+}
+
+func TestBucketsSweep(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	limit := Limit{Rate: 1, Burst: 2, Key: KeyIP}
+
+	bs := newBuckets()
+	bs.take("a", limit, start)
+	bs.take("b", limit, start)
+	bs.take("b", limit, start)
+	bs.take("b", limit, start.Add(sweepInterval-time.Millisecond*500))
+
+	// a is full again, b is still missing a token
+	bs.take("c", limit, start.Add(sweepInterval))
+	if _, ok := bs.buckets["a"]; ok {
+		t.Error("sweep() kept the full bucket")
+	}
+	if _, ok := bs.buckets["b"]; !ok {
+		t.Error("sweep() dropped a bucket that is not full")
+	}
+}
+
+func TestBucketAllow(t *testing.T) {
+	b := NewBucket(1, 2)
+	now := b.b.last
+
+	for i, want := range []bool{true, true, false} {
+		if got := b.Allow(now); got != want {
+			t.Errorf("Allow() call %d = %v, want %v", i, got, want)
+		}
+	}
+	if !b.Allow(now.Add(time.Second)) {
+		t.Error("Allow() once refilled = false, want true")
+	}
+}
+
+func TestConfigCheck(t *testing.T) {
+	valid := Limit{Rate: 1, Burst: 1, Key: KeyIP}
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "empty", config: Config{}},
+		{name: "default", config: Config{Default: &valid}},
+		{name: "methods", config: Config{Methods: map[string]Limit{"/playground.Greeter/SayHello": {Rate: 0.5, Burst: 2, Key: KeyTenant}}}},
+		{name: "zero rate", config: Config{Default: &Limit{Burst: 1, Key: KeyIP}}, wantErr: true},
+		{name: "zero burst", config: Config{Default: &Limit{Rate: 1, Key: KeyIP}}, wantErr: true},
+		{name: "unknown key", config: Config{Default: &Limit{Rate: 1, Burst: 1, Key: "user"}}, wantErr: true},
+		{name: "method not a full name", config: Config{Methods: map[string]Limit{"SayHello": valid}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.check(); (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimiterKey(t *testing.T) {
+	l := NewLimiter(&Config{TenantMetadata: "x-tenant"})
+
+	fromPeer := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 4242}})
+	tenant := metadata.NewIncomingContext(fromPeer, metadata.Pairs("x-tenant", "acme"))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		keyType KeyType
+		want    string
+	}{
+		{name: "ip", ctx: fromPeer, keyType: KeyIP, want: "ip:192.0.2.1"},
+		{name: "filtered client ip", ctx: ipfilter.WithClientIP(fromPeer, netip.MustParseAddr("198.51.100.7")), keyType: KeyIP, want: "ip:198.51.100.7"},
+		{name: "api key", ctx: apikey.WithKey(fromPeer, apikey.Key{ID: "k1"}), keyType: KeyIdentity, want: "apikey:k1"},
+		{name: "token", ctx: auth.WithClaims(fromPeer, auth.Claims{Issuer: "https://issuer", Subject: "ann"}), keyType: KeyIdentity, want: "subject:https://issuer/ann"},
+		{name: "certificate", ctx: tlsconfig.WithIdentity(fromPeer, tlsconfig.Identity{CommonName: "ops"}), keyType: KeyIdentity, want: "cert:ops"},
+		{name: "unauthenticated identity", ctx: fromPeer, keyType: KeyIdentity, want: "ip:192.0.2.1"},
+		{name: "tenant", ctx: tenant, keyType: KeyTenant, want: "tenant:acme"},
+		{name: "no tenant", ctx: fromPeer, keyType: KeyTenant, want: "ip:192.0.2.1"},
+		{name: "tenant ignored for ip", ctx: tenant, keyType: KeyIP, want: "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.key(tt.ctx, tt.keyType); got != tt.want {
+				t.Errorf("key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterTake(t *testing.T) {
+	const sayHello = "/playground.Greeter/SayHello"
+	l := NewLimiter(&Config{
+		Methods: map[string]Limit{sayHello: {Rate: 1, Burst: 1, Key: KeyIP}},
+	})
+	now := time.Unix(1_700_000_000, 0)
+	l.now = func() time.Time { return now }
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 4242}})
+
+	md, err := l.take(ctx, sayHello)
+	if err != nil {
+		t.Fatalf("take() error = %v", err)
+	}
+	if got := md.Get(RemainingHeader); len(got) != 1 || got[0] != "0" {
+		t.Errorf("take() %s = %q, want %q", RemainingHeader, got, "0")
+	}
+
+	if _, err := l.take(ctx, sayHello); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("take() over the limit code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+
+	if md, err := l.take(ctx, "/other.Service/Method"); err != nil || md != nil {
+		t.Errorf("take() without limit = %v, %v, want no headers and no error", md, err)
+	}
+}
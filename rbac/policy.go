@@ -0,0 +1,177 @@
+// Package rbac decides which callers may call which methods, from a policy binding caller identities to roles.
+package rbac
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Policy is read from JSON:
+//
+//	{
+//	  "roles": {
+//	    "greeter": ["/playground.Greeter/SayHello"],
+//	    "operator": ["/greeter.mock.v1.MockAdmin/*"]
+//	  },
+//	  "bindings": [
+//	    {"role": "greeter", "members": ["authenticated"]},
+//	    {"role": "operator", "members": ["claim:roles=admin", "cert:spiffe://greeter/ops"]}
+//	  ]
+//	}
+//
+// A role lists full method names, a name ending in /* covers every method of a service and * covers every method.
+// A binding grants its role to callers matching any of its members:
+//
+//	anyone                every caller, authenticated or not
+//	authenticated         callers with a verified token, client certificate or API key
+//	subject:<sub>         callers whose token has the subject
+//	cert:<name>           callers whose client certificate has the name as a URI, DNS or email SAN or common name
+//	claim:<name>=<value>  callers whose token has the value in the claim, which may be a string or a list of them
+//	apikey:<id>           callers using the API key
+//
+// Calls to methods no role granted to the caller covers are denied.
+type Policy struct {
+	Roles    map[string][]string `json:"roles"`
+	Bindings []Binding           `json:"bindings"`
+}
+
+type Binding struct {
+	Role    string   `json:"role"`
+	Members []string `json:"members"`
+}
+
+// Load reads the policy in the JSON file at path, checking it is consistent.
+func Load(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("rbac: unable to read policy: %w", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("rbac: invalid policy %s: %w", path, err)
+	}
+	if err := p.check(); err != nil {
+		return nil, fmt.Errorf("rbac: invalid policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *Policy) check() error {
+	var errs []error
+	for role, methods := range p.Roles {
+		for _, m := range methods {
+			if m != "*" && !strings.HasPrefix(m, "/") {
+				errs = append(errs, fmt.Errorf("role %q: method %q must be a full method name such as /package.Service/Method", role, m))
+			}
+		}
+	}
+	for i, b := range p.Bindings {
+		if _, ok := p.Roles[b.Role]; !ok {
+			errs = append(errs, fmt.Errorf("binding %d: unknown role %q", i, b.Role))
+		}
+		for _, m := range b.Members {
+			if _, err := parseMember(m); err != nil {
+				errs = append(errs, fmt.Errorf("binding %d: %w", i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rolesFor returns the roles covering method, sorted.
+func (p *Policy) rolesFor(method string) []string {
+	var out []string
+	for role, methods := range p.Roles {
+		for _, pattern := range methods {
+			if covers(pattern, method) {
+				out = append(out, role)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Restricted reports whether only callers named by the policy may call method: no role covering it is granted to
+// anyone or to every authenticated caller.
+func (p *Policy) Restricted(method string) bool {
+	roles := p.rolesFor(method)
+	for _, b := range p.Bindings {
+		if !slices.Contains(roles, b.Role) {
+			continue
+		}
+		for _, s := range b.Members {
+			// members were checked when the policy was loaded
+			if m, _ := parseMember(s); m.kind == memberAnyone || m.kind == memberAuthenticated {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func covers(pattern, method string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, "/*"):
+		return strings.HasPrefix(method, strings.TrimSuffix(pattern, "*"))
+	default:
+		return pattern == method
+	}
+}
+
+type memberKind int
+
+const (
+	memberAnyone memberKind = iota
+	memberAuthenticated
+	memberSubject
+	memberCert
+	memberClaim
+	memberAPIKey
+)
+
+type member struct {
+	kind  memberKind
+	key   string
+	value string
+}
+
+func parseMember(s string) (member, error) {
+	switch s {
+	case "anyone":
+		return member{kind: memberAnyone}, nil
+	case "authenticated":
+		return member{kind: memberAuthenticated}, nil
+	}
+
+	kind, rest, ok := strings.Cut(s, ":")
+	if !ok || rest == "" {
+		return member{}, fmt.Errorf("invalid member %q", s)
+	}
+
+	switch kind {
+	case "subject":
+		return member{kind: memberSubject, value: rest}, nil
+	case "cert":
+		return member{kind: memberCert, value: rest}, nil
+	case "apikey":
+		return member{kind: memberAPIKey, value: rest}, nil
+	case "claim":
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok || key == "" {
+			return member{}, fmt.Errorf("invalid member %q, use claim:<name>=<value>", s)
+		}
+		return member{kind: memberClaim, key: key, value: value}, nil
+	default:
+		return member{}, fmt.Errorf("unknown member kind %q in %q", kind, s)
+	}
+}
@@ -0,0 +1,164 @@
+package rbac
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/apikey"
+	"github.com/LewisJAllan/greeter/auth"
+	"github.com/LewisJAllan/greeter/tlsconfig"
+)
+
+const (
+	sayHello   = "/playground.Greeter/SayHello"
+	setMock    = "/greeter.mock.v1.MockAdmin/SetScenario"
+	createKey  = "/greeter.apikey.v1.KeyAdmin/CreateKey"
+	listBlocks = "/greeter.abuse.v1.AbuseAdmin/ListBlocks"
+)
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		pattern string
+		method  string
+		want    bool
+	}{
+		{pattern: "*", method: sayHello, want: true},
+		{pattern: sayHello, method: sayHello, want: true},
+		{pattern: "/playground.Greeter/*", method: sayHello, want: true},
+		{pattern: "/playground.Greeter/*", method: "/playground.GreeterAdmin/SayHello", want: false},
+		{pattern: "/playground.Greeter/Say", method: sayHello, want: false},
+		{pattern: "/greeter.mock.v1.MockAdmin/*", method: sayHello, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.method, func(t *testing.T) {
+			if got := covers(tt.pattern, tt.method); got != tt.want {
+				t.Errorf("covers(%q, %q) = %v, want %v", tt.pattern, tt.method, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr bool
+	}{
+		{name: "valid", policy: `{"roles":{"greeter":["` + sayHello + `"]},"bindings":[{"role":"greeter","members":["anyone","claim:roles=greeter"]}]}`},
+		{name: "method not a full name", policy: `{"roles":{"greeter":["SayHello"]}}`, wantErr: true},
+		{name: "unknown role", policy: `{"roles":{},"bindings":[{"role":"greeter","members":["anyone"]}]}`, wantErr: true},
+		{name: "unknown member kind", policy: `{"roles":{"r":["*"]},"bindings":[{"role":"r","members":["group:ops"]}]}`, wantErr: true},
+		{name: "member without value", policy: `{"roles":{"r":["*"]},"bindings":[{"role":"r","members":["subject:"]}]}`, wantErr: true},
+		{name: "claim without name", policy: `{"roles":{"r":["*"]},"bindings":[{"role":"r","members":["claim:=admin"]}]}`, wantErr: true},
+		{name: "invalid json", policy: `{"roles":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, []byte(tt.policy), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyRestricted(t *testing.T) {
+	p := &Policy{
+		Roles: map[string][]string{
+			"greeter":  {sayHello},
+			"operator": {"/greeter.mock.v1.MockAdmin/*", "/greeter.apikey.v1.KeyAdmin/*"},
+			"auditor":  {"/greeter.apikey.v1.KeyAdmin/ListKeys", "/greeter.abuse.v1.AbuseAdmin/*"},
+			"all":      {"*"},
+		},
+		Bindings: []Binding{
+			{Role: "greeter", Members: []string{"anyone"}},
+			{Role: "operator", Members: []string{"subject:ops", "cert:spiffe://greeter/ops"}},
+			{Role: "auditor", Members: []string{"authenticated"}},
+		},
+	}
+
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{method: sayHello, want: false},
+		{method: setMock, want: true},
+		{method: createKey, want: true},
+		{method: "/greeter.apikey.v1.KeyAdmin/ListKeys", want: false},
+		{method: listBlocks, want: false},
+		{method: "/other.Service/Method", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			if got := p.Restricted(tt.method); got != tt.want {
+				t.Errorf("Restricted(%q) = %v, want %v", tt.method, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizerAuthorize(t *testing.T) {
+	p := &Policy{
+		Roles: map[string][]string{
+			"greeter":  {sayHello},
+			"operator": {"/greeter.mock.v1.MockAdmin/*"},
+			"keys":     {"/greeter.apikey.v1.KeyAdmin/*"},
+			"all":      {"*"},
+		},
+		Bindings: []Binding{
+			{Role: "greeter", Members: []string{"authenticated"}},
+			{Role: "operator", Members: []string{"claim:roles=admin", "cert:spiffe://greeter/ops"}},
+			{Role: "keys", Members: []string{"apikey:k1", "cert:ops.greeter"}},
+			{Role: "all", Members: []string{"subject:root"}},
+		},
+	}
+
+	token := func(sub string, raw map[string]any) context.Context {
+		return auth.WithClaims(context.Background(), auth.Claims{Subject: sub, Raw: raw})
+	}
+	cert := func(id tlsconfig.Identity) context.Context {
+		return tlsconfig.WithIdentity(context.Background(), id)
+	}
+	key := func(id string) context.Context {
+		return apikey.WithKey(context.Background(), apikey.Key{ID: id})
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		wantCode codes.Code
+	}{
+		{name: "unauthenticated", ctx: context.Background(), method: sayHello, wantCode: codes.PermissionDenied},
+		{name: "token", ctx: token("ann", nil), method: sayHello},
+		{name: "certificate", ctx: cert(tlsconfig.Identity{CommonName: "ann"}), method: sayHello},
+		{name: "api key", ctx: key("k2"), method: sayHello},
+		{name: "claim", ctx: token("ann", map[string]any{"roles": []any{"user", "admin"}}), method: setMock},
+		{name: "other claim value", ctx: token("ann", map[string]any{"roles": "user"}), method: setMock, wantCode: codes.PermissionDenied},
+		{name: "certificate uri", ctx: cert(tlsconfig.Identity{URIs: []string{"spiffe://greeter/ops"}}), method: setMock},
+		{name: "certificate dns name", ctx: cert(tlsconfig.Identity{DNSNames: []string{"ops.greeter"}}), method: createKey},
+		{name: "named api key", ctx: key("k1"), method: createKey},
+		{name: "other api key", ctx: key("k2"), method: createKey, wantCode: codes.PermissionDenied},
+		{name: "subject", ctx: token("root", nil), method: listBlocks},
+		{name: "method of no role", ctx: token("ann", nil), method: listBlocks, wantCode: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewAuthorizer(p).authorize(tt.ctx, tt.method)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("authorize() code = %v, want %v (error %v)", code, tt.wantCode, err)
+			}
+			if err := NewAuthorizer(p, WithDryRun()).authorize(tt.ctx, tt.method); err != nil {
+				t.Errorf("authorize() in dry run error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/LewisJAllan/greeter/apikey"
+	"github.com/LewisJAllan/greeter/auth"
+	"github.com/LewisJAllan/greeter/errdetails"
+	"github.com/LewisJAllan/greeter/tlsconfig"
+)
+
+// errorDomain identifies the errors of this package in their ErrorInfo details.
+const errorDomain = "rbac.greeter"
+
+type options struct {
+	dryRun bool
+}
+
+type Option func(o *options)
+
+// WithDryRun logs the calls that would be denied and lets them through, to try a policy before enforcing it.
+func WithDryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// Authorizer provides interceptors enforcing a Policy.  They must be installed after the interceptors establishing
+// the caller identity: tlsconfig for client certificates, auth for tokens and apikey for API keys.
+type Authorizer struct {
+	policy *Policy
+	opts   options
+}
+
+func NewAuthorizer(policy *Policy, opts ...Option) *Authorizer {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Authorizer{policy: policy, opts: o}
+}
+
+func (a *Authorizer) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authorizer) StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// caller is what is known of who is calling.
+type caller struct {
+	claims    auth.Claims
+	hasClaims bool
+	identity  tlsconfig.Identity
+	hasCert   bool
+	key       apikey.Key
+	hasKey    bool
+}
+
+func newCaller(ctx context.Context) caller {
+	var c caller
+	c.claims, c.hasClaims = auth.ClaimsFromContext(ctx)
+	c.identity, c.hasCert = tlsconfig.IdentityFromContext(ctx)
+	c.key, c.hasKey = apikey.KeyFromContext(ctx)
+	return c
+}
+
+func (c caller) String() string {
+	var parts []string
+	if c.hasClaims {
+		parts = append(parts, fmt.Sprintf("subject %q", c.claims.Subject))
+	}
+	if c.hasCert {
+		parts = append(parts, fmt.Sprintf("certificate %q", c.identity.Name()))
+	}
+	if c.hasKey {
+		parts = append(parts, fmt.Sprintf("API key %q", c.key.ID))
+	}
+	if len(parts) == 0 {
+		return "unauthenticated caller"
+	}
+	return "caller with " + strings.Join(parts, " and ")
+}
+
+func (c caller) is(m member) bool {
+	switch m.kind {
+	case memberAnyone:
+		return true
+	case memberAuthenticated:
+		return c.hasClaims || c.hasCert || c.hasKey
+	case memberSubject:
+		return c.hasClaims && c.claims.Subject == m.value
+	case memberCert:
+		if !c.hasCert {
+			return false
+		}
+		id := c.identity
+		return id.CommonName == m.value ||
+			slices.Contains(id.URIs, m.value) ||
+			slices.Contains(id.DNSNames, m.value) ||
+			slices.Contains(id.EmailAddresses, m.value)
+	case memberClaim:
+		return c.hasClaims && slices.Contains(c.claims.Strings(m.key), m.value)
+	case memberAPIKey:
+		return c.hasKey && c.key.ID == m.value
+	default:
+		return false
+	}
+}
+
+// grantedRoles returns the roles bound to the caller.
+func (a *Authorizer) grantedRoles(c caller) []string {
+	var out []string
+	for _, b := range a.policy.Bindings {
+		if slices.Contains(out, b.Role) {
+			continue
+		}
+		for _, s := range b.Members {
+			// members were checked when the policy was loaded
+			m, _ := parseMember(s)
+			if c.is(m) {
+				out = append(out, b.Role)
+				break
+			}
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+func (a *Authorizer) authorize(ctx context.Context, method string) error {
+	c := newCaller(ctx)
+	granted := a.grantedRoles(c)
+	allowing := a.policy.rolesFor(method)
+
+	for _, role := range granted {
+		if slices.Contains(allowing, role) {
+			return nil
+		}
+	}
+
+	reason := fmt.Sprintf("%s is not allowed to call %s", c, method)
+	if len(allowing) > 0 {
+		reason += fmt.Sprintf(", it requires one of the roles %s", strings.Join(allowing, ", "))
+	}
+
+	fields := []zap.Field{
+		zap.String("method", method),
+		zap.Strings("granted_roles", granted),
+		zap.Strings("allowing_roles", allowing),
+	}
+	if a.opts.dryRun {
+		zaphelper.Warn(ctx, "rbac dry run: call would be denied", append(fields, zap.String("reason", reason))...)
+		return nil
+	}
+
+	zaphelper.Info(ctx, "rbac denied call", fields...)
+	return errdetails.Error(codes.PermissionDenied, reason,
+		errdetails.ErrorInfo("PERMISSION_DENIED", errorDomain, map[string]string{"method": method}),
+	)
+}
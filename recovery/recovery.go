@@ -0,0 +1,159 @@
+// Package recovery turns panics in gRPC handlers into Internal errors instead of letting them end the process.
+package recovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+var panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "greeter",
+	Subsystem: "grpc",
+	Name:      "panics_total",
+	Help:      "Panics recovered from gRPC handlers, by method.",
+}, []string{"grpc_service", "grpc_method"})
+
+func init() {
+	prometheus.MustRegister(panics)
+}
+
+type options struct {
+	reportDir string
+}
+
+type Option func(o *options)
+
+// WithCrashReports writes a JSON report of every panic to a new file in dir.
+func WithCrashReports(dir string) Option {
+	return func(o *options) {
+		o.reportDir = dir
+	}
+}
+
+// Recoverer provides interceptors recovering from panics.  They must come first in the chain to also recover from
+// panics in the interceptors after them.
+type Recoverer struct {
+	opts options
+}
+
+func NewRecoverer(opts ...Option) *Recoverer {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Recoverer{opts: o}
+}
+
+func (r *Recoverer) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			res, err = nil, r.recovered(ctx, info.FullMethod, p)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func (r *Recoverer) StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = r.recovered(ss.Context(), info.FullMethod, p)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// Report is the crash report written for a panic.
+type Report struct {
+	Time     time.Time           `json:"time"`
+	Method   string              `json:"method"`
+	Panic    string              `json:"panic"`
+	Stack    string              `json:"stack"`
+	Peer     string              `json:"peer,omitempty"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+}
+
+func (r *Recoverer) recovered(ctx context.Context, method string, p any) error {
+	report := Report{
+		Time:     time.Now().UTC(),
+		Method:   method,
+		Panic:    fmt.Sprint(p),
+		Stack:    string(debug.Stack()),
+		Metadata: requestMetadata(ctx),
+	}
+	if pr, ok := peer.FromContext(ctx); ok && pr.Addr != nil {
+		report.Peer = pr.Addr.String()
+	}
+
+	service, name := grpcutil.SplitMethod(method)
+	panics.WithLabelValues(service, name).Inc()
+
+	fields := []zap.Field{
+		zap.String("method", method),
+		zap.String("panic", report.Panic),
+		zap.String("stack", report.Stack),
+		zap.String("peer", report.Peer),
+		zap.Any("metadata", report.Metadata),
+	}
+	if r.opts.reportDir != "" {
+		path, err := r.writeReport(report)
+		if err != nil {
+			fields = append(fields, zap.NamedError("report_error", err))
+		} else {
+			fields = append(fields, zap.String("report", path))
+		}
+	}
+	zaphelper.Error(ctx, "recovered from panic", fields...)
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func (r *Recoverer) writeReport(report Report) (string, error) {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("recovery: unable to encode crash report: %w", err)
+	}
+
+	name := fmt.Sprintf("crash-%s-*.json", report.Time.Format("20060102T150405Z"))
+	f, err := os.CreateTemp(r.opts.reportDir, name)
+	if err != nil {
+		return "", fmt.Errorf("recovery: unable to create crash report: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(b); err != nil {
+		return "", fmt.Errorf("recovery: unable to write crash report: %w", err)
+	}
+	return filepath.Clean(f.Name()), nil
+}
+
+func requestMetadata(ctx context.Context) map[string][]string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	out := make(map[string][]string, len(md))
+	for k, v := range md {
+		if grpcutil.IsCredential(k) {
+			out[k] = []string{grpcutil.Redacted}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
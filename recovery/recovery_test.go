@@ -0,0 +1,135 @@
+package recovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+const sayHello = "/playground.Greeter/SayHello"
+
+func TestRecovererUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  grpc.UnaryHandler
+		want     any
+		wantCode codes.Code
+	}{
+		{
+			name:    "no panic",
+			handler: func(context.Context, any) (any, error) { return "hello", nil },
+			want:    "hello",
+		},
+		{
+			name:     "error",
+			handler:  func(context.Context, any) (any, error) { return nil, status.Error(codes.InvalidArgument, "name") },
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "panic",
+			handler:  func(context.Context, any) (any, error) { panic("boom") },
+			wantCode: codes.Internal,
+		},
+		{
+			name:     "panic with an error",
+			handler:  func(context.Context, any) (any, error) { panic(errors.New("boom")) },
+			wantCode: codes.Internal,
+		},
+		{
+			name: "nil map",
+			handler: func(context.Context, any) (any, error) {
+				var m map[string]int
+				m["boom"]++
+				return nil, nil
+			},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRecoverer().UnaryServerInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: sayHello}, tt.handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("UnaryServerInterceptor() code = %v, want %v (error %v)", code, tt.wantCode, err)
+			}
+			if got != tt.want {
+				t.Errorf("UnaryServerInterceptor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s testStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRecovererStreamServerInterceptor(t *testing.T) {
+	err := NewRecoverer().StreamServerInterceptor(nil, testStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/playground.Greeter/Chat"},
+		func(any, grpc.ServerStream) error { panic("boom") })
+	if code := status.Code(err); code != codes.Internal {
+		t.Errorf("StreamServerInterceptor() code = %v, want %v", code, codes.Internal)
+	}
+}
+
+func TestRecovererCrashReport(t *testing.T) {
+	dir := t.TempDir()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"authorization", "Bearer secret",
+		"x-api-key", "gk_secret",
+		"cookie", "session=secret",
+		"x-request-id", "42",
+	))
+
+	_, err := NewRecoverer(WithCrashReports(dir)).UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: sayHello},
+		func(context.Context, any) (any, error) { panic("boom") })
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("UnaryServerInterceptor() error = %v", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "crash-*.json"))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("crash reports = %v, %v, want one", paths, err)
+	}
+	b, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report Report
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatalf("invalid crash report: %v", err)
+	}
+
+	if report.Method != sayHello || report.Panic != "boom" || report.Stack == "" {
+		t.Errorf("report = %+v, want the method, panic and stack", report)
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "authorization", want: grpcutil.Redacted},
+		{key: "x-api-key", want: grpcutil.Redacted},
+		{key: "cookie", want: grpcutil.Redacted},
+		{key: "x-request-id", want: "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := report.Metadata[tt.key]; len(got) != 1 || got[0] != tt.want {
+				t.Errorf("report metadata %s = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
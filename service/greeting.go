@@ -0,0 +1,43 @@
+package service
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Greeting records a response issued by Respond.  IDs increase by one for each greeting issued by a Service.
+type Greeting struct {
+	ID       uint64
+	Name     string
+	Locale   string
+	Style    string
+	Message  string
+	IssuedAt time.Time
+}
+
+type sequence struct {
+	last atomic.Uint64
+}
+
+func (s *sequence) next() uint64 {
+	return s.last.Add(1)
+}
+
+func (s *Service) issue(request RespondRequest, message string) Greeting {
+	greeting := Greeting{
+		ID:       s.greetings.next(),
+		Name:     request.OriginalMessage,
+		Locale:   request.Locale,
+		Style:    request.Style,
+		Message:  message,
+		IssuedAt: time.Now(),
+	}
+
+	s.history.Observe(greeting)
+
+	for _, o := range s.observers {
+		o.Observe(greeting)
+	}
+
+	return greeting
+}
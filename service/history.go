@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultHistorySize = 1000
+	maxGreetingsLimit  = 100
+)
+
+type GreetingsRequest struct {
+	// After excludes greetings with an ID up to and including After.
+	After uint64
+	Limit int
+}
+
+type GreetingsResponse struct {
+	Greetings []Greeting
+	HasMore   bool
+	// Total is the number of greetings currently held in the history.
+	Total int
+}
+
+// history holds the most recent greetings issued, oldest first.
+type history struct {
+	mu        sync.RWMutex
+	greetings []Greeting
+	start     int
+}
+
+func newHistory(size int) *history {
+	return &history{greetings: make([]Greeting, 0, size)}
+}
+
+func (h *history) Observe(greeting Greeting) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.greetings) < cap(h.greetings) {
+		h.greetings = append(h.greetings, greeting)
+		return
+	}
+	if cap(h.greetings) == 0 {
+		return
+	}
+	h.greetings[h.start] = greeting
+	h.start = (h.start + 1) % cap(h.greetings)
+}
+
+// WithHistorySize sets how many of the most recent greetings are kept for Greetings.  Defaults to 1000.
+func WithHistorySize(size int) Option {
+	return func(s *Service) {
+		s.history = newHistory(size)
+	}
+}
+
+// Greetings pages through the most recent greetings issued, oldest first.
+func (s *Service) Greetings(_ context.Context, request GreetingsRequest) (GreetingsResponse, error) {
+	if request.Limit < 0 || request.Limit > maxGreetingsLimit {
+		return GreetingsResponse{}, status.Errorf(codes.InvalidArgument, "limit must be between 0 and %d", maxGreetingsLimit)
+	}
+	if request.Limit == 0 {
+		request.Limit = maxGreetingsLimit
+	}
+
+	h := s.history
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	resp := GreetingsResponse{Total: len(h.greetings)}
+	for i := 0; i < len(h.greetings); i++ {
+		greeting := h.greetings[(h.start+i)%len(h.greetings)]
+		if greeting.ID <= request.After {
+			continue
+		}
+		if len(resp.Greetings) == request.Limit {
+			resp.HasMore = true
+			break
+		}
+		resp.Greetings = append(resp.Greetings, greeting)
+	}
+	return resp, nil
+}
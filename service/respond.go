@@ -10,19 +10,34 @@ import (
 
 type RespondRequest struct {
 	OriginalMessage string
+	// Locale and Style are recorded with the greeting issued.
+	Locale string
+	Style  string
 }
 
 type RespondResponse struct {
 	ResponseMessage string
+	// Greeting is the greeting recorded for the response.
+	Greeting Greeting
 }
 
 func (s *Service) Respond(ctx context.Context, request RespondRequest) (RespondResponse, error) {
 	zaphelper.Info(ctx, "starting response")
+	if s.moderator != nil {
+		var err error
+		request, err = s.moderator.Moderate(ctx, request)
+		if err != nil {
+			return RespondResponse{}, err
+		}
+	}
 	s.concurrencyRunner.Run(func() {
 		time.Sleep(1 * time.Second)
 		fmt.Print("hello")
 	})
+	greeting := s.issue(request, "Hello World")
+
 	return RespondResponse{
-		ResponseMessage: "Hello World",
+		ResponseMessage: greeting.Message,
+		Greeting:        greeting,
 	}, nil
 }
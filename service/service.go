@@ -1,15 +1,58 @@
 package service
 
+import "context"
+
 type AsynchronousRunner interface {
 	Run(f func())
 }
 
+// GreetingObserver is notified of every greeting issued by the Service.  Observe is called synchronously from Respond
+// so implementations must not block.
+type GreetingObserver interface {
+	Observe(greeting Greeting)
+}
+
+// Moderator checks the request of Respond before any greeting is issued, returning it with the name it may be greeted
+// with or an error refusing it.
+type Moderator interface {
+	Moderate(ctx context.Context, request RespondRequest) (RespondRequest, error)
+}
+
 type Service struct {
 	concurrencyRunner AsynchronousRunner
+	observers         []GreetingObserver
+	moderator         Moderator
+
+	greetings *sequence
+	history   *history
+}
+
+type Option func(s *Service)
+
+// WithGreetingObservers registers observers notified of each greeting issued.
+func WithGreetingObservers(observers ...GreetingObserver) Option {
+	return func(s *Service) {
+		s.observers = append(s.observers, observers...)
+	}
+}
+
+// WithModerator checks every request with m before responding.
+func WithModerator(m Moderator) Option {
+	return func(s *Service) {
+		s.moderator = m
+	}
 }
 
-func NewService(concurrencyRunner AsynchronousRunner) Service {
-	return Service{
+func NewService(concurrencyRunner AsynchronousRunner, opts ...Option) Service {
+	s := Service{
 		concurrencyRunner: concurrencyRunner,
+		greetings:         &sequence{},
+		history:           newHistory(defaultHistorySize),
+	}
+
+	for _, opt := range opts {
+		opt(&s)
 	}
+
+	return s
 }
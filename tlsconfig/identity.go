@@ -0,0 +1,86 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/x509"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/LewisJAllan/greeter/internal/grpcutil"
+)
+
+// Identity is who a verified client certificate was issued to.
+type Identity struct {
+	CommonName     string
+	DNSNames       []string
+	URIs           []string
+	EmailAddresses []string
+}
+
+// Name returns the most specific name of the client: its first URI SAN, such as a SPIFFE ID, then its first DNS SAN,
+// then its common name.
+func (i Identity) Name() string {
+	switch {
+	case len(i.URIs) > 0:
+		return i.URIs[0]
+	case len(i.DNSNames) > 0:
+		return i.DNSNames[0]
+	default:
+		return i.CommonName
+	}
+}
+
+type identityKey struct{}
+
+// IdentityFromContext returns the identity of the client whose request is being handled, reporting false when the
+// client did not present a verified certificate.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// UnaryServerInterceptor adds the identity of verified clients to the request context.
+func UnaryServerInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(withPeerIdentity(ctx), req)
+}
+
+// StreamServerInterceptor adds the identity of verified clients to the stream context.
+func StreamServerInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, grpcutil.WithContext(ss, withPeerIdentity(ss.Context())))
+}
+
+func withPeerIdentity(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		// only certificates verified against the client CAs identify a client
+		return ctx
+	}
+	id := newIdentity(info.State.VerifiedChains[0][0])
+	ctx = zaphelper.With(ctx, zaphelper.FromContext(ctx).With(zap.String("client", id.Name())))
+	return WithIdentity(ctx, id)
+}
+
+func newIdentity(cert *x509.Certificate) Identity {
+	id := Identity{
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+	}
+	for _, u := range cert.URIs {
+		id.URIs = append(id.URIs, u.String())
+	}
+	return id
+}
@@ -0,0 +1,190 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LewisJAllan/application-helper/zaphelper"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var (
+	reloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "greeter",
+		Subsystem: "tls",
+		Name:      "reloads_total",
+		Help:      "Certificate reloads attempted, by result.",
+	}, []string{"result"})
+
+	certificateExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "greeter",
+		Subsystem: "tls",
+		Name:      "certificate_expiry_timestamp_seconds",
+		Help:      "When the certificate in use expires, as a unix timestamp.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(reloads, certificateExpiry)
+}
+
+// expiryWarning is how close to expiry a certificate is logged as a warning when loaded.
+const expiryWarning = time.Hour * 24 * 7
+
+// Reloader is an app.Runner keeping the TLS configuration in step with the files it was loaded from.  The files are
+// polled, and once any of them changes they are read and validated again: new handshakes use the new certificates
+// only when they are valid, otherwise the previous ones stay in use until the files are fixed.
+type Reloader struct {
+	opts     Options
+	policy   policy
+	interval time.Duration
+
+	current atomic.Pointer[tls.Config]
+	// stamp records the files last loaded, or last failed to load, so each change is tried once
+	stamp string
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewReloader loads the files named by o, failing when they are not valid, and polls them every interval once started.
+func NewReloader(ctx context.Context, o Options, interval time.Duration) (*Reloader, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("tlsconfig: reload interval must be positive, got %s", interval)
+	}
+	p, err := newPolicy(o)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{
+		opts:     o,
+		policy:   p,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	r.stamp = r.fileStamp()
+	if err := r.load(ctx); err != nil {
+		reloads.WithLabelValues("failure").Inc()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Config returns a TLS configuration handing each new handshake the latest valid certificates.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.policy.minVersion,
+		NextProtos: []string{"h2"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+func (r *Reloader) Start(ctx context.Context) error {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return nil
+		case <-t.C:
+			r.check(ctx)
+		}
+	}
+}
+
+func (r *Reloader) Stop(context.Context) error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	return nil
+}
+
+func (r *Reloader) Name() string {
+	return "tls-reloader"
+}
+
+// check reloads the files when they changed since the last attempt.
+func (r *Reloader) check(ctx context.Context) {
+	stamp := r.fileStamp()
+	if stamp == r.stamp {
+		return
+	}
+	r.stamp = stamp
+
+	if err := r.load(ctx); err != nil {
+		reloads.WithLabelValues("failure").Inc()
+		zaphelper.Error(ctx, "tls reload failed, keeping the certificates in use", zap.Error(err))
+		return
+	}
+	reloads.WithLabelValues("success").Inc()
+}
+
+func (r *Reloader) load(ctx context.Context) error {
+	f, err := readFiles(r.opts)
+	if err != nil {
+		return err
+	}
+	if err := validate(f, time.Now()); err != nil {
+		return err
+	}
+
+	r.current.Store(r.policy.config(f))
+
+	leaf := f.certificate.Leaf
+	certificateExpiry.Set(float64(leaf.NotAfter.Unix()))
+
+	fields := []zap.Field{
+		zap.String("subject", leaf.Subject.String()),
+		zap.String("serial", leaf.SerialNumber.String()),
+		zap.Time("not_after", leaf.NotAfter),
+	}
+	if time.Until(leaf.NotAfter) < expiryWarning {
+		zaphelper.Warn(ctx, "tls certificate loaded, expiring soon", fields...)
+		return nil
+	}
+	zaphelper.Info(ctx, "tls certificate loaded", fields...)
+	return nil
+}
+
+// validate refuses certificates that could not be served.  tls.LoadX509KeyPair has already checked the key matches.
+func validate(f files, now time.Time) error {
+	leaf := f.certificate.Leaf
+	if leaf == nil {
+		return errors.New("tlsconfig: certificate could not be parsed")
+	}
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("tlsconfig: certificate is not valid until %s", leaf.NotBefore)
+	}
+	if now.After(leaf.NotAfter) {
+		return fmt.Errorf("tlsconfig: certificate expired at %s", leaf.NotAfter)
+	}
+	return nil
+}
+
+// fileStamp summarises the size and modification time of the files, following symlinks as mounted secrets use them.
+func (r *Reloader) fileStamp() string {
+	var b strings.Builder
+	for _, path := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s:missing;", path)
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", path, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return b.String()
+}
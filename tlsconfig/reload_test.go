@@ -0,0 +1,191 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self signed certificate with serial, valid between notBefore and notAfter, to the files
+// of o.  The modification time is moved on so each write is seen as a change.
+func writeCertificate(t *testing.T, o Options, serial int64, notBefore, notAfter time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "greeter"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(o.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(o.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().Add(time.Duration(serial) * time.Second)
+	for _, path := range []string{o.CertFile, o.KeyFile} {
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testOptions(t *testing.T) Options {
+	dir := t.TempDir()
+	return Options{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+}
+
+// serial returns the serial number of the certificate the reloader hands new handshakes.
+func serial(t *testing.T, r *Reloader) int64 {
+	t.Helper()
+	cfg, err := r.Config().GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetConfigForClient() error = %v", err)
+	}
+	return cfg.Certificates[0].Leaf.SerialNumber.Int64()
+}
+
+func TestNewReloader(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		interval  time.Duration
+		wantErr   bool
+	}{
+		{name: "valid", notBefore: now.Add(-time.Hour), notAfter: now.Add(time.Hour), interval: time.Second},
+		{name: "expired", notBefore: now.Add(-time.Hour * 2), notAfter: now.Add(-time.Hour), interval: time.Second, wantErr: true},
+		{name: "not yet valid", notBefore: now.Add(time.Hour), notAfter: now.Add(time.Hour * 2), interval: time.Second, wantErr: true},
+		{name: "no interval", notBefore: now.Add(-time.Hour), notAfter: now.Add(time.Hour), wantErr: true},
+		{name: "negative interval", notBefore: now.Add(-time.Hour), notAfter: now.Add(time.Hour), interval: -time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testOptions(t)
+			writeCertificate(t, o, 1, tt.notBefore, tt.notAfter)
+
+			_, err := NewReloader(context.Background(), o, tt.interval)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewReloader() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewReloader(context.Background(), testOptions(t), time.Second); err == nil {
+		t.Errorf("NewReloader() without files error = nil, want an error")
+	}
+}
+
+func TestReloaderCheck(t *testing.T) {
+	now := time.Now()
+	o := testOptions(t)
+	writeCertificate(t, o, 1, now.Add(-time.Hour), now.Add(time.Hour))
+
+	r, err := NewReloader(context.Background(), o, time.Hour)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	cfg := r.Config()
+	if cfg.MinVersion != tls.VersionTLS12 || len(cfg.NextProtos) != 1 || cfg.NextProtos[0] != "h2" {
+		t.Errorf("Config() = %+v, want TLS 1.2 or later over h2", cfg)
+	}
+
+	steps := []struct {
+		name       string
+		write      func()
+		wantSerial int64
+	}{
+		{name: "unchanged", wantSerial: 1},
+		{
+			name:       "renewed",
+			write:      func() { writeCertificate(t, o, 2, now.Add(-time.Hour), now.Add(time.Hour)) },
+			wantSerial: 2,
+		},
+		{
+			name:       "expired certificate kept out",
+			write:      func() { writeCertificate(t, o, 3, now.Add(-time.Hour*2), now.Add(-time.Hour)) },
+			wantSerial: 2,
+		},
+		{
+			name: "key removed",
+			write: func() {
+				if err := os.Remove(o.KeyFile); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantSerial: 2,
+		},
+		{
+			name:       "fixed",
+			write:      func() { writeCertificate(t, o, 4, now.Add(-time.Hour), now.Add(time.Hour)) },
+			wantSerial: 4,
+		},
+	}
+	for _, s := range steps {
+		if s.write != nil {
+			s.write()
+		}
+		r.check(context.Background())
+		if got := serial(t, r); got != s.wantSerial {
+			t.Errorf("%s: serial = %d, want %d", s.name, got, s.wantSerial)
+		}
+	}
+}
+
+func TestReloaderStartStop(t *testing.T) {
+	now := time.Now()
+	o := testOptions(t)
+	writeCertificate(t, o, 1, now.Add(-time.Hour), now.Add(time.Hour))
+
+	r, err := NewReloader(context.Background(), o, time.Millisecond*10)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- r.Start(context.Background()) }()
+
+	writeCertificate(t, o, 2, now.Add(-time.Hour), now.Add(time.Hour))
+	deadline := time.Now().Add(time.Second * 5)
+	for serial(t, r) != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if got := serial(t, r); got != 2 {
+		t.Errorf("serial = %d, want the renewed certificate picked up", got)
+	}
+
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	// stopping twice is harmless
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("second Stop() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+}
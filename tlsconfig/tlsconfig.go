@@ -0,0 +1,158 @@
+// Package tlsconfig builds the server TLS configuration of the gRPC listener from certificate files, and exposes the
+// identity of verified clients to the handlers.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ClientAuth values accepted by Options.ClientAuth.
+const (
+	// ClientAuthNone asks for no client certificate.
+	ClientAuthNone = "none"
+	// ClientAuthRequest verifies a client certificate when one is presented.
+	ClientAuthRequest = "request"
+	// ClientAuthRequire refuses clients without a valid certificate, mutual TLS.
+	ClientAuthRequire = "require"
+)
+
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle of the CAs client certificates are verified against.
+	ClientCAFile string
+	// ClientAuth is one of none, request or require.  Defaults to require when ClientCAFile is set.
+	ClientAuth string
+	// MinVersion is "1.2" or "1.3".  Defaults to 1.2.
+	MinVersion string
+	// CipherSuites names the TLS 1.2 cipher suites allowed, such as TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256.
+	// Defaults to the Go defaults.  TLS 1.3 suites are not configurable.
+	CipherSuites []string
+}
+
+// Enabled reports whether TLS is configured.
+func (o Options) Enabled() bool {
+	return o.CertFile != "" || o.KeyFile != ""
+}
+
+// ClientCertRequired reports whether clients must present a certificate, mutual TLS.
+func (o Options) ClientCertRequired() bool {
+	return o.ClientAuth == ClientAuthRequire || (o.ClientAuth == "" && o.ClientCAFile != "")
+}
+
+// policy is the part of the configuration that does not come from files.
+type policy struct {
+	clientAuth   tls.ClientAuthType
+	minVersion   uint16
+	cipherSuites []uint16
+}
+
+func newPolicy(o Options) (policy, error) {
+	if o.CertFile == "" || o.KeyFile == "" {
+		return policy{}, errors.New("tlsconfig: certificate and key files are both required")
+	}
+
+	var p policy
+	var errs []error
+
+	switch o.MinVersion {
+	case "", "1.2":
+		p.minVersion = tls.VersionTLS12
+	case "1.3":
+		p.minVersion = tls.VersionTLS13
+	default:
+		errs = append(errs, fmt.Errorf("unsupported minimum TLS version %q, use 1.2 or 1.3", o.MinVersion))
+	}
+
+	clientAuth := o.ClientAuth
+	if clientAuth == "" && o.ClientCAFile != "" {
+		clientAuth = ClientAuthRequire
+	}
+	switch clientAuth {
+	case "", ClientAuthNone:
+		p.clientAuth = tls.NoClientCert
+	case ClientAuthRequest:
+		p.clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		p.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		errs = append(errs, fmt.Errorf("unsupported client auth %q, use none, request or require", o.ClientAuth))
+	}
+	if p.clientAuth != tls.NoClientCert && o.ClientCAFile == "" {
+		errs = append(errs, errors.New("a client CA file is required to verify client certificates"))
+	}
+
+	for _, name := range o.CipherSuites {
+		id, err := cipherSuite(strings.TrimSpace(name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p.cipherSuites = append(p.cipherSuites, id)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return policy{}, fmt.Errorf("tlsconfig: %w", err)
+	}
+	return p, nil
+}
+
+// cipherSuite looks up a suite by name, refusing those Go considers insecure.
+func cipherSuite(name string) (uint16, error) {
+	for _, s := range tls.CipherSuites() {
+		if s.Name == name {
+			return s.ID, nil
+		}
+	}
+	for _, s := range tls.InsecureCipherSuites() {
+		if s.Name == name {
+			return 0, fmt.Errorf("cipher suite %s is insecure", name)
+		}
+	}
+	return 0, fmt.Errorf("unknown cipher suite %q", name)
+}
+
+// files is the part of the configuration read from files.
+type files struct {
+	certificate tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+func readFiles(o Options) (files, error) {
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return files{}, fmt.Errorf("tlsconfig: unable to load certificate: %w", err)
+	}
+
+	f := files{certificate: cert}
+	if o.ClientCAFile == "" {
+		return f, nil
+	}
+
+	pem, err := os.ReadFile(o.ClientCAFile)
+	if err != nil {
+		return files{}, fmt.Errorf("tlsconfig: unable to read client CA file: %w", err)
+	}
+	f.clientCAs = x509.NewCertPool()
+	if !f.clientCAs.AppendCertsFromPEM(pem) {
+		return files{}, fmt.Errorf("tlsconfig: no certificates found in %s", o.ClientCAFile)
+	}
+	return f, nil
+}
+
+func (p policy) config(f files) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{f.certificate},
+		ClientCAs:    f.clientCAs,
+		ClientAuth:   p.clientAuth,
+		MinVersion:   p.minVersion,
+		CipherSuites: p.cipherSuites,
+		// grpc requires HTTP/2
+		NextProtos: []string{"h2"},
+	}
+}
@@ -0,0 +1,114 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"slices"
+	"testing"
+)
+
+func TestNewPolicy(t *testing.T) {
+	const (
+		aes128 = "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"
+		rc4    = "TLS_ECDHE_RSA_WITH_RC4_128_SHA"
+	)
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    policy
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			opts: Options{CertFile: "cert.pem", KeyFile: "key.pem"},
+			want: policy{clientAuth: tls.NoClientCert, minVersion: tls.VersionTLS12},
+		},
+		{
+			name: "tls 1.3",
+			opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", MinVersion: "1.3"},
+			want: policy{clientAuth: tls.NoClientCert, minVersion: tls.VersionTLS13},
+		},
+		{
+			name: "client CA requires certificates",
+			opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem"},
+			want: policy{clientAuth: tls.RequireAndVerifyClientCert, minVersion: tls.VersionTLS12},
+		},
+		{
+			name: "client certificates requested",
+			opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem", ClientAuth: ClientAuthRequest},
+			want: policy{clientAuth: tls.VerifyClientCertIfGiven, minVersion: tls.VersionTLS12},
+		},
+		{
+			name: "client CA without client auth",
+			opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem", ClientAuth: ClientAuthNone},
+			want: policy{clientAuth: tls.NoClientCert, minVersion: tls.VersionTLS12},
+		},
+		{
+			name: "cipher suites",
+			opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", CipherSuites: []string{" " + aes128}},
+			want: policy{
+				clientAuth:   tls.NoClientCert,
+				minVersion:   tls.VersionTLS12,
+				cipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			},
+		},
+		{name: "missing key", opts: Options{CertFile: "cert.pem"}, wantErr: true},
+		{name: "missing certificate", opts: Options{KeyFile: "key.pem"}, wantErr: true},
+		{name: "unsupported version", opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", MinVersion: "1.1"}, wantErr: true},
+		{name: "unknown client auth", opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", ClientAuth: "always"}, wantErr: true},
+		{name: "client auth without CA", opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", ClientAuth: ClientAuthRequire}, wantErr: true},
+		{name: "insecure cipher suite", opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", CipherSuites: []string{rc4}}, wantErr: true},
+		{name: "unknown cipher suite", opts: Options{CertFile: "cert.pem", KeyFile: "key.pem", CipherSuites: []string{"TLS_NOPE"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newPolicy(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newPolicy() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got.clientAuth != tt.want.clientAuth || got.minVersion != tt.want.minVersion || !slices.Equal(got.cipherSuites, tt.want.cipherSuites) {
+				t.Errorf("newPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsClientCertRequired(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want bool
+	}{
+		{name: "no client CA", opts: Options{}, want: false},
+		{name: "client CA", opts: Options{ClientCAFile: "ca.pem"}, want: true},
+		{name: "required", opts: Options{ClientCAFile: "ca.pem", ClientAuth: ClientAuthRequire}, want: true},
+		{name: "requested", opts: Options{ClientCAFile: "ca.pem", ClientAuth: ClientAuthRequest}, want: false},
+		{name: "none", opts: Options{ClientCAFile: "ca.pem", ClientAuth: ClientAuthNone}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.ClientCertRequired(); got != tt.want {
+				t.Errorf("ClientCertRequired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentityName(t *testing.T) {
+	tests := []struct {
+		name string
+		id   Identity
+		want string
+	}{
+		{name: "uri", id: Identity{CommonName: "ops", DNSNames: []string{"ops.greeter"}, URIs: []string{"spiffe://greeter/ops"}}, want: "spiffe://greeter/ops"},
+		{name: "dns", id: Identity{CommonName: "ops", DNSNames: []string{"ops.greeter"}}, want: "ops.greeter"},
+		{name: "common name", id: Identity{CommonName: "ops", EmailAddresses: []string{"ops@greeter"}}, want: "ops"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.id.Name(); got != tt.want {
+				t.Errorf("Name() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}